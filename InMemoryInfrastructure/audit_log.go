@@ -0,0 +1,69 @@
+package inMemoryInfrastructure
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	// WriterAuditLog writes decisions to a local writer as JSON lines
+	WriterAuditLog struct {
+		mu  sync.Mutex
+		w   io.Writer
+		now func() time.Time
+	}
+
+	auditEntry struct {
+		At       string `json:"at"`
+		Allowed  bool   `json:"allowed"`
+		Action   string `json:"action"`
+		Actor    string `json:"actor"`
+		Resource string `json:"resource"`
+		Reason   string `json:"reason"`
+	}
+)
+
+func NewWriterAuditLog(w io.Writer) *WriterAuditLog {
+	return &WriterAuditLog{w: w, now: time.Now}
+}
+
+func NewStderrAuditLog() *WriterAuditLog {
+	return NewWriterAuditLog(os.Stderr)
+}
+
+// NewFileAuditLog appends decisions to the file at path
+func NewFileAuditLog(path string) (*WriterAuditLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterAuditLog(f), nil
+}
+
+func (wal *WriterAuditLog) Record(decision model.Decision) error {
+	actor := decision.Actor.UserId.V
+	if decision.Actor.IsAnonymous() {
+		actor = "anonymous"
+	}
+	line, err := json.Marshal(auditEntry{
+		At:       wal.now().UTC().Format(time.RFC3339),
+		Allowed:  decision.Allowed,
+		Action:   decision.Action.V,
+		Actor:    actor,
+		Resource: decision.Resource,
+		Reason:   decision.Reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	wal.mu.Lock()
+	defer wal.mu.Unlock()
+	_, err = wal.w.Write(append(line, '\n'))
+	return err
+}
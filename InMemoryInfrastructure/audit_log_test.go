@@ -0,0 +1,23 @@
+package inMemoryInfrastructure
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestWriterAuditLog_Record(t *testing.T) {
+	var buf bytes.Buffer
+	auditLog := NewWriterAuditLog(&buf)
+	auditLog.now = func() time.Time { return time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	assert.Nil(t, auditLog.Record(model.Decision{Action: model.ACTION_CIRCLE_VIEW, Resource: "circle:1", Reason: "authentication required"}))
+	assert.Nil(t, auditLog.Record(model.Decision{Action: model.ACTION_USER_DELETE, Actor: model.Actor{UserId: model.UserId{V: "2"}}, Resource: "user:1", Reason: "only the user themself can delete user"}))
+	assert.Equal(t,
+		`{"at":"2023-01-01T00:00:00Z","allowed":false,"action":"view circle","actor":"anonymous","resource":"circle:1","reason":"authentication required"}`+"\n"+
+			`{"at":"2023-01-01T00:00:00Z","allowed":false,"action":"delete user","actor":"2","resource":"user:1","reason":"only the user themself can delete user"}`+"\n",
+		buf.String())
+}
@@ -0,0 +1,37 @@
+package inMemoryInfrastructure
+
+import (
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	CircleFactory struct {
+		storage *TmpCircleStorage
+	}
+)
+
+func NewCircleFactory(storage *TmpCircleStorage) CircleFactory {
+	return CircleFactory{storage: storage}
+}
+
+func (cf *CircleFactory) Create(name *model.CircleName, owner *model.User) (*model.Circle, error) {
+	if name == nil {
+		return nil, model.NewValidationError("name", "is required")
+	}
+	if owner == nil {
+		return nil, model.NewValidationError("owner", "is required")
+	}
+	id, err := model.NewCircleId(cf.assignId())
+	if err != nil {
+		return nil, err
+	}
+	circle, err := model.NewCircle(&id, name, &owner.Id, []model.UserId{})
+	if err != nil {
+		return nil, err
+	}
+	return &circle, nil
+}
+
+func (cf *CircleFactory) assignId() string {
+	return cf.storage.nextId()
+}
@@ -0,0 +1,129 @@
+package inMemoryInfrastructure
+
+import (
+	"sync"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	TmpCircleStorage struct {
+		mu   sync.RWMutex
+		data []model.Circle
+		ids  idSequence
+	}
+	SliceCircleRepository struct {
+		Storage *TmpCircleStorage
+	}
+)
+
+func NewSliceCircleRepository() SliceCircleRepository {
+	id, _ := model.NewCircleId("1")
+	name, _ := model.NewCircleName("circle1")
+	owner, _ := model.NewUserId("1")
+	circle, _ := model.NewCircle(&id, &name, &owner, []model.UserId{{V: "2"}})
+	storage := TmpCircleStorage{data: []model.Circle{circle}}
+	return SliceCircleRepository{Storage: &storage}
+}
+
+// Save stores the circle as its next version. Circles read before the latest save are stale.
+func (scr *SliceCircleRepository) Save(circle *model.Circle) error {
+	if circle == nil {
+		return model.NewValidationError("circle", "is required")
+	}
+	return scr.Storage.Upsert(*circle)
+}
+
+func (scr *SliceCircleRepository) FindById(id model.CircleId) (*model.Circle, error) {
+	for _, circle := range scr.Storage.all() {
+		if circle.Id().V == id.V {
+			clone := circle.Clone()
+			return &clone, nil
+		}
+	}
+	return nil, model.NewNotFoundError("circle", id.V)
+}
+
+func (scr *SliceCircleRepository) FindByName(name *model.CircleName) (model.Circle, error) {
+	for _, circle := range scr.Storage.all() {
+		if circle.Name().V == name.V {
+			return circle.Clone(), nil
+		}
+	}
+	return model.Circle{}, nil
+}
+
+func (scr *SliceCircleRepository) FindAll() ([]model.Circle, error) {
+	circles := []model.Circle{}
+	for _, circle := range scr.Storage.all() {
+		circles = append(circles, circle.Clone())
+	}
+	return circles, nil
+}
+
+func (tcs *TmpCircleStorage) all() []model.Circle {
+	tcs.mu.RLock()
+	defer tcs.mu.RUnlock()
+	return tcs.data
+}
+
+func (tcs *TmpCircleStorage) Insert(circle model.Circle) {
+	tcs.mu.Lock()
+	defer tcs.mu.Unlock()
+	tcs.ids.see(circle.Id().V)
+	tcs.data = append(append([]model.Circle{}, tcs.data...), circle.Clone())
+}
+
+func (tcs *TmpCircleStorage) Update(circle model.Circle) {
+	tcs.mu.Lock()
+	defer tcs.mu.Unlock()
+	tcs.update(circle)
+}
+
+// Upsert checks and bumps the version of the circle like TmpUserStorage.Upsert does for users
+func (tcs *TmpCircleStorage) Upsert(circle model.Circle) error {
+	tcs.mu.Lock()
+	defer tcs.mu.Unlock()
+	version := 0
+	for _, c := range tcs.data {
+		if c.Id().V == circle.Id().V {
+			version = c.Version()
+		}
+	}
+	if circle.Version() != version {
+		return model.NewStaleError("circle", circle.Id().V, version)
+	}
+	next := circle.NextVersion()
+	if !tcs.update(next) {
+		tcs.ids.see(next.Id().V)
+		tcs.data = append(append([]model.Circle{}, tcs.data...), next)
+	}
+	return nil
+}
+
+func (tcs *TmpCircleStorage) update(circle model.Circle) bool {
+	for i, c := range tcs.data {
+		if c.Id().V == circle.Id().V {
+			data := append([]model.Circle{}, tcs.data...)
+			data[i] = circle.Clone()
+			tcs.data = data
+			return true
+		}
+	}
+	return false
+}
+
+func (tcs *TmpCircleStorage) nextId() string {
+	tcs.mu.Lock()
+	defer tcs.mu.Unlock()
+	return tcs.ids.next(len(tcs.data), func(i int) string { return tcs.data[i].Id().V })
+}
+
+func (tcs *TmpCircleStorage) Snapshot() func() {
+	data := tcs.all()
+	return func() {
+		tcs.mu.Lock()
+		defer tcs.mu.Unlock()
+		tcs.data = data
+	}
+}
@@ -0,0 +1,149 @@
+package inMemoryInfrastructure
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestSliceCircleRepository_Isolation(t *testing.T) {
+	member := model.User{Id: model.UserId{V: "3"}, Name: model.UserName{V: "user3"}, UType: model.USER_TYPE_NORMAL}
+	tests := []struct {
+		name   string
+		mutate func(scr *SliceCircleRepository)
+	}{
+		{
+			name: "circles from FindAll",
+			mutate: func(scr *SliceCircleRepository) {
+				circles, _ := scr.FindAll()
+				_ = circles[0].Remove(model.UserId{V: "2"})
+				_ = circles[0].Join(&member, 30)
+			},
+		},
+		{
+			name: "circle from FindById",
+			mutate: func(scr *SliceCircleRepository) {
+				circle, _ := scr.FindById(model.CircleId{V: "1"})
+				_ = circle.Remove(model.UserId{V: "2"})
+				_ = circle.Join(&member, 30)
+			},
+		},
+		{
+			name: "circle after Save",
+			mutate: func(scr *SliceCircleRepository) {
+				circle, _ := scr.FindById(model.CircleId{V: "1"})
+				_ = scr.Save(circle)
+				_ = circle.Remove(model.UserId{V: "2"})
+				_ = circle.Join(&member, 30)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scr := NewSliceCircleRepository()
+			tt.mutate(&scr)
+
+			circle, _ := scr.FindById(model.CircleId{V: "1"})
+			assert.Equal(t, []model.UserId{{V: "2"}}, circle.Members(), fmt.Sprintf("changing the %s changed the storage", tt.name))
+		})
+	}
+}
+
+func TestSliceCircleRepository_SaveAfterChange(t *testing.T) {
+	scr := NewSliceCircleRepository()
+	circle, _ := scr.FindById(model.CircleId{V: "1"})
+	member := model.User{Id: model.UserId{V: "3"}, Name: model.UserName{V: "user3"}, UType: model.USER_TYPE_NORMAL}
+	assert.Nil(t, circle.Join(&member, 30))
+	assert.Nil(t, scr.Save(circle))
+
+	saved, _ := scr.FindById(model.CircleId{V: "1"})
+	assert.Equal(t, []model.UserId{{V: "2"}, {V: "3"}}, saved.Members())
+}
+
+func TestSliceCircleRepository_Stale(t *testing.T) {
+	scr := NewSliceCircleRepository()
+	first, _ := scr.FindById(model.CircleId{V: "1"})
+	second, _ := scr.FindById(model.CircleId{V: "1"})
+	member := model.User{Id: model.UserId{V: "3"}, Name: model.UserName{V: "user3"}, UType: model.USER_TYPE_NORMAL}
+	assert.Nil(t, first.Join(&member, 30))
+	assert.Nil(t, scr.Save(first))
+
+	assert.ErrorIs(t, scr.Save(second), model.ErrStale)
+	saved, _ := scr.FindById(model.CircleId{V: "1"})
+	assert.Equal(t, 1, saved.Version())
+	assert.Equal(t, []model.UserId{{V: "2"}, {V: "3"}}, saved.Members())
+}
+
+func TestTmpCircleStorage_SnapshotKeepsLastId(t *testing.T) {
+	scr := NewSliceCircleRepository()
+	factory := NewCircleFactory(scr.Storage)
+	owner := model.User{Id: model.UserId{V: "1"}, Name: model.UserName{V: "user1"}, UType: model.USER_TYPE_NORMAL}
+	name := model.CircleName{V: "circle2"}
+
+	restore := scr.Storage.Snapshot()
+	created, err := factory.Create(&name, &owner)
+	assert.Nil(t, err)
+	assert.Nil(t, scr.Save(created))
+	restore()
+
+	_, err = scr.FindById(created.Id())
+	assert.ErrorIs(t, err, model.ErrNotFound, "the circle saved after the snapshot is kept")
+	again, err := factory.Create(&name, &owner)
+	assert.Nil(t, err)
+	assert.NotEqual(t, created.Id(), again.Id(), "the id handed out before the restore is reused")
+}
+
+func TestSliceCircleRepository_Concurrent(t *testing.T) {
+	const workers = 50
+	scr := NewSliceCircleRepository()
+	factory := NewCircleFactory(scr.Storage)
+	owner := model.User{Id: model.UserId{V: "1"}, Name: model.UserName{V: "user1"}, UType: model.USER_TYPE_NORMAL}
+
+	var wg sync.WaitGroup
+	ids := make(chan string, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			name := model.CircleName{V: fmt.Sprintf("circle%d", i+10)}
+			circle, err := factory.Create(&name, &owner)
+			assert.Nil(t, err)
+			assert.Nil(t, scr.Save(circle))
+			ids <- circle.Id().V
+		}(i)
+		go func() {
+			defer wg.Done()
+			circles, err := scr.FindAll()
+			assert.Nil(t, err)
+			for _, circle := range circles {
+				_ = circle.Members()
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			// only one of the writers who read the same version saves
+			circle, err := scr.FindById(model.CircleId{V: "1"})
+			assert.Nil(t, err)
+			member := model.User{Id: model.UserId{V: fmt.Sprintf("%d", i+10)}, Name: model.UserName{V: fmt.Sprintf("user%d", i+10)}, UType: model.USER_TYPE_NORMAL}
+			assert.Nil(t, circle.Join(&member, workers+2))
+			if err := scr.Save(circle); err != nil {
+				assert.ErrorIs(t, err, model.ErrStale)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[string]bool{}
+	for id := range ids {
+		assert.False(t, seen[id], fmt.Sprintf("id %s is assigned twice", id))
+		seen[id] = true
+	}
+	circles, _ := scr.FindAll()
+	assert.Equal(t, workers+1, len(circles), "every saved circle is kept")
+	circle, _ := scr.FindById(model.CircleId{V: "1"})
+	assert.Equal(t, circle.Version()+1, len(circle.Members()), "a member was joined without a new version")
+}
@@ -0,0 +1,103 @@
+package inMemoryInfrastructure
+
+import (
+	"sync"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	TmpCredentialStorage struct {
+		mu   sync.RWMutex
+		data []model.Credential
+	}
+	SliceCredentialRepository struct {
+		Storage *TmpCredentialStorage
+	}
+)
+
+func NewSliceCredentialRepository() SliceCredentialRepository {
+	return SliceCredentialRepository{Storage: &TmpCredentialStorage{data: []model.Credential{}}}
+}
+
+// a user has at most one credential
+func (scr *SliceCredentialRepository) Save(credential model.Credential) error {
+	scr.Storage.Upsert(credential)
+	return nil
+}
+
+func (scr *SliceCredentialRepository) FindByUserId(id *model.UserId) (*model.Credential, error) {
+	for _, credential := range scr.Storage.all() {
+		if credential.UserId.V == id.V {
+			return &credential, nil
+		}
+	}
+	return nil, nil
+}
+
+func (scr *SliceCredentialRepository) Delete(credential model.Credential) error {
+	if !scr.Storage.Delete(credential.UserId) {
+		return model.NewNotFoundError("credential", credential.UserId.V)
+	}
+	return nil
+}
+
+func (tcs *TmpCredentialStorage) all() []model.Credential {
+	tcs.mu.RLock()
+	defer tcs.mu.RUnlock()
+	return tcs.data
+}
+
+func (tcs *TmpCredentialStorage) Insert(credential model.Credential) {
+	tcs.mu.Lock()
+	defer tcs.mu.Unlock()
+	tcs.data = append(append([]model.Credential{}, tcs.data...), credential)
+}
+
+func (tcs *TmpCredentialStorage) Update(credential model.Credential) {
+	tcs.mu.Lock()
+	defer tcs.mu.Unlock()
+	tcs.update(credential)
+}
+
+// Upsert updates the credential, or inserts it when it is not stored yet
+func (tcs *TmpCredentialStorage) Upsert(credential model.Credential) {
+	tcs.mu.Lock()
+	defer tcs.mu.Unlock()
+	if !tcs.update(credential) {
+		tcs.data = append(append([]model.Credential{}, tcs.data...), credential)
+	}
+}
+
+func (tcs *TmpCredentialStorage) update(credential model.Credential) bool {
+	for i, c := range tcs.data {
+		if c.UserId.V == credential.UserId.V {
+			data := append([]model.Credential{}, tcs.data...)
+			data[i] = credential
+			tcs.data = data
+			return true
+		}
+	}
+	return false
+}
+
+func (tcs *TmpCredentialStorage) Delete(id model.UserId) bool {
+	tcs.mu.Lock()
+	defer tcs.mu.Unlock()
+	for i, c := range tcs.data {
+		if c.UserId.V == id.V {
+			tcs.data = append(append([]model.Credential{}, tcs.data[:i]...), tcs.data[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (tcs *TmpCredentialStorage) Snapshot() func() {
+	data := tcs.all()
+	return func() {
+		tcs.mu.Lock()
+		defer tcs.mu.Unlock()
+		tcs.data = data
+	}
+}
@@ -0,0 +1,92 @@
+package inMemoryInfrastructure
+
+import (
+	"sync"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	TmpEntitlementOverrideStorage struct {
+		mu   sync.RWMutex
+		data []model.EntitlementOverride
+	}
+	// a user has one override at most
+	SliceEntitlementOverrideRepository struct {
+		Storage *TmpEntitlementOverrideStorage
+	}
+)
+
+func NewSliceEntitlementOverrideRepository() SliceEntitlementOverrideRepository {
+	return SliceEntitlementOverrideRepository{Storage: &TmpEntitlementOverrideStorage{data: []model.EntitlementOverride{}}}
+}
+
+func (ser *SliceEntitlementOverrideRepository) Save(override model.EntitlementOverride) error {
+	ser.Storage.Upsert(override)
+	return nil
+}
+
+func (ser *SliceEntitlementOverrideRepository) FindByUserId(id *model.UserId) (*model.EntitlementOverride, error) {
+	for _, override := range ser.Storage.all() {
+		if override.UserId.V == id.V {
+			clone := override.Clone()
+			return &clone, nil
+		}
+	}
+	return nil, nil
+}
+
+func (ser *SliceEntitlementOverrideRepository) Delete(id *model.UserId) error {
+	if !ser.Storage.Delete(*id) {
+		return model.NewNotFoundError("entitlement override", id.V)
+	}
+	return nil
+}
+
+func (teos *TmpEntitlementOverrideStorage) all() []model.EntitlementOverride {
+	teos.mu.RLock()
+	defer teos.mu.RUnlock()
+	return teos.data
+}
+
+// Upsert updates the override, or inserts it when it is not stored yet
+func (teos *TmpEntitlementOverrideStorage) Upsert(override model.EntitlementOverride) {
+	teos.mu.Lock()
+	defer teos.mu.Unlock()
+	if !teos.update(override) {
+		teos.data = append(append([]model.EntitlementOverride{}, teos.data...), override.Clone())
+	}
+}
+
+func (teos *TmpEntitlementOverrideStorage) update(override model.EntitlementOverride) bool {
+	for i, o := range teos.data {
+		if o.UserId.V == override.UserId.V {
+			data := append([]model.EntitlementOverride{}, teos.data...)
+			data[i] = override.Clone()
+			teos.data = data
+			return true
+		}
+	}
+	return false
+}
+
+func (teos *TmpEntitlementOverrideStorage) Delete(id model.UserId) bool {
+	teos.mu.Lock()
+	defer teos.mu.Unlock()
+	for i, o := range teos.data {
+		if o.UserId.V == id.V {
+			teos.data = append(append([]model.EntitlementOverride{}, teos.data[:i]...), teos.data[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (teos *TmpEntitlementOverrideStorage) Snapshot() func() {
+	data := teos.all()
+	return func() {
+		teos.mu.Lock()
+		defer teos.mu.Unlock()
+		teos.data = data
+	}
+}
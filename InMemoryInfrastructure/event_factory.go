@@ -0,0 +1,39 @@
+package inMemoryInfrastructure
+
+import (
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	EventFactory struct {
+		storage *TmpEventStorage
+	}
+)
+
+func NewEventFactory(storage *TmpEventStorage) EventFactory {
+	return EventFactory{storage: storage}
+}
+
+func (ef *EventFactory) Create(circleId *model.CircleId, title *model.EventTitle, start time.Time, end time.Time, location string, capacity int) (*model.Event, error) {
+	if circleId == nil {
+		return nil, model.NewValidationError("circleId", "is required")
+	}
+	if title == nil {
+		return nil, model.NewValidationError("title", "is required")
+	}
+	id, err := model.NewEventId(ef.assignId())
+	if err != nil {
+		return nil, err
+	}
+	event, err := model.NewEvent(id, *circleId, *title, start, end, location, capacity)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (ef *EventFactory) assignId() string {
+	return ef.storage.nextId()
+}
@@ -0,0 +1,103 @@
+package inMemoryInfrastructure
+
+import (
+	"sync"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	TmpEventStorage struct {
+		mu   sync.RWMutex
+		data []model.Event
+		ids  idSequence
+	}
+	SliceEventRepository struct {
+		Storage *TmpEventStorage
+	}
+)
+
+func NewSliceEventRepository() SliceEventRepository {
+	return SliceEventRepository{Storage: &TmpEventStorage{data: []model.Event{}}}
+}
+
+func (ser *SliceEventRepository) Save(event model.Event) error {
+	ser.Storage.Upsert(event)
+	return nil
+}
+
+func (ser *SliceEventRepository) FindById(id *model.EventId) (*model.Event, error) {
+	for _, event := range ser.Storage.all() {
+		if event.Id.V == id.V {
+			clone := event.Clone()
+			return &clone, nil
+		}
+	}
+	return nil, nil
+}
+
+func (ser *SliceEventRepository) FindByCircle(circleId *model.CircleId) ([]model.Event, error) {
+	events := []model.Event{}
+	for _, event := range ser.Storage.all() {
+		if event.CircleId.V == circleId.V {
+			events = append(events, event.Clone())
+		}
+	}
+	return events, nil
+}
+
+func (tes *TmpEventStorage) all() []model.Event {
+	tes.mu.RLock()
+	defer tes.mu.RUnlock()
+	return tes.data
+}
+
+func (tes *TmpEventStorage) Insert(event model.Event) {
+	tes.mu.Lock()
+	defer tes.mu.Unlock()
+	tes.ids.see(event.Id.V)
+	tes.data = append(append([]model.Event{}, tes.data...), event.Clone())
+}
+
+func (tes *TmpEventStorage) Update(event model.Event) {
+	tes.mu.Lock()
+	defer tes.mu.Unlock()
+	tes.update(event)
+}
+
+// Upsert updates the event, or inserts it when it is not stored yet
+func (tes *TmpEventStorage) Upsert(event model.Event) {
+	tes.mu.Lock()
+	defer tes.mu.Unlock()
+	if !tes.update(event) {
+		tes.ids.see(event.Id.V)
+		tes.data = append(append([]model.Event{}, tes.data...), event.Clone())
+	}
+}
+
+func (tes *TmpEventStorage) update(event model.Event) bool {
+	for i, e := range tes.data {
+		if e.Id.V == event.Id.V {
+			data := append([]model.Event{}, tes.data...)
+			data[i] = event.Clone()
+			tes.data = data
+			return true
+		}
+	}
+	return false
+}
+
+func (tes *TmpEventStorage) nextId() string {
+	tes.mu.Lock()
+	defer tes.mu.Unlock()
+	return tes.ids.next(len(tes.data), func(i int) string { return tes.data[i].Id.V })
+}
+
+func (tes *TmpEventStorage) Snapshot() func() {
+	data := tes.all()
+	return func() {
+		tes.mu.Lock()
+		defer tes.mu.Unlock()
+		tes.data = data
+	}
+}
@@ -0,0 +1,70 @@
+package inMemoryInfrastructure
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestTmpEventStorage_SnapshotKeepsLastId(t *testing.T) {
+	ser := NewSliceEventRepository()
+	factory := NewEventFactory(ser.Storage)
+	title := model.EventTitle{V: "meetup"}
+	start := time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	restore := ser.Storage.Snapshot()
+	created, err := factory.Create(&model.CircleId{V: "1"}, &title, start, start.Add(time.Hour), "", 10)
+	assert.Nil(t, err)
+	assert.Nil(t, ser.Save(*created))
+	restore()
+
+	found, err := ser.FindById(&created.Id)
+	assert.Nil(t, err)
+	assert.Nil(t, found, "the event saved after the snapshot is kept")
+	again, err := factory.Create(&model.CircleId{V: "1"}, &title, start, start.Add(time.Hour), "", 10)
+	assert.Nil(t, err)
+	assert.NotEqual(t, created.Id, again.Id, "the id handed out before the restore is reused")
+}
+
+func TestSliceEventRepository_Concurrent(t *testing.T) {
+	const workers = 50
+	ser := NewSliceEventRepository()
+	factory := NewEventFactory(ser.Storage)
+	start := time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	ids := make(chan string, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			title := model.EventTitle{V: fmt.Sprintf("meetup%d", i)}
+			event, err := factory.Create(&model.CircleId{V: "1"}, &title, start, start.Add(time.Hour), "", 10)
+			assert.Nil(t, err)
+			assert.Nil(t, ser.Save(*event))
+			ids <- event.Id.V
+		}(i)
+		go func() {
+			defer wg.Done()
+			events, err := ser.FindByCircle(&model.CircleId{V: "1"})
+			assert.Nil(t, err)
+			for _, event := range events {
+				_ = event.Title.V
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[string]bool{}
+	for id := range ids {
+		assert.False(t, seen[id], fmt.Sprintf("id %s is assigned twice", id))
+		seen[id] = true
+	}
+	events, _ := ser.FindByCircle(&model.CircleId{V: "1"})
+	assert.Equal(t, workers, len(events), "every saved event is kept")
+}
@@ -0,0 +1,33 @@
+package inMemoryInfrastructure
+
+import "strconv"
+
+type (
+	// idSequence hands out the numeric ids of a storage, and is guarded by the lock of the storage.
+	// Snapshots do not put it back, so that the ids handed out in a unit of work which was rolled back are never reused.
+	idSequence struct {
+		started bool
+		last    int
+	}
+)
+
+// next hands out the number after the largest id stored or handed out.
+// The stored ids are read only the first time, for the data which the storage was made with,
+// as the storage shows the sequence every id which it stores afterwards.
+func (s *idSequence) next(stored int, id func(i int) string) string {
+	if !s.started {
+		for i := 0; i < stored; i++ {
+			s.see(id(i))
+		}
+		s.started = true
+	}
+	s.last++
+	return strconv.Itoa(s.last)
+}
+
+// see keeps the sequence after the stored id. Ids which are not numbers are skipped.
+func (s *idSequence) see(id string) {
+	if number, err := strconv.Atoi(id); err == nil {
+		s.last = max(s.last, number)
+	}
+}
@@ -0,0 +1,46 @@
+package inMemoryInfrastructure
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestTmpUserStorage_nextId(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored []string
+		saved  []string
+		want   []string
+	}{
+		{name: "empty", stored: []string{}, want: []string{"1", "2"}},
+		{name: "after the largest id", stored: []string{"3", "1"}, want: []string{"4", "5"}},
+		{name: "not a number first", stored: []string{"admin", "7", "2"}, want: []string{"8", "9"}},
+		{name: "only not numbers", stored: []string{"admin"}, want: []string{"1", "2"}},
+		{name: "saved with its own id", stored: []string{"1"}, saved: []string{"10"}, want: []string{"2", "11"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &TmpUserStorage{data: []model.User{}}
+			for _, id := range tt.stored {
+				storage.data = append(storage.data, model.User{Id: model.UserId{V: id}})
+			}
+			got := []string{storage.nextId()}
+			for _, id := range tt.saved {
+				storage.Insert(model.User{Id: model.UserId{V: id}})
+			}
+			got = append(got, storage.nextId())
+			assert.Equal(t, tt.want, got, fmt.Sprintf("nextId() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func TestTmpUserStorage_nextIdAfterRollback(t *testing.T) {
+	storage := &TmpUserStorage{data: []model.User{{Id: model.UserId{V: "1"}}}}
+	restore := storage.Snapshot()
+	storage.Insert(model.User{Id: model.UserId{V: storage.nextId()}})
+	restore()
+	assert.Equal(t, "3", storage.nextId(), "an id handed out before the rollback was reused")
+}
@@ -0,0 +1,39 @@
+package inMemoryInfrastructure
+
+import (
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	InvoiceFactory struct {
+		storage *TmpInvoiceStorage
+	}
+)
+
+func NewInvoiceFactory(storage *TmpInvoiceStorage) InvoiceFactory {
+	return InvoiceFactory{storage: storage}
+}
+
+func (f *InvoiceFactory) Create(userId *model.UserId, subscriptionId model.SubscriptionId, plan *model.UserType, months int, amount int, retry bool, issued time.Time) (*model.Invoice, error) {
+	if userId == nil {
+		return nil, model.NewValidationError("userId", "is required")
+	}
+	if plan == nil {
+		return nil, model.NewValidationError("plan", "is required")
+	}
+	id, err := model.NewInvoiceId(f.assignId())
+	if err != nil {
+		return nil, err
+	}
+	invoice, err := model.NewInvoice(id, *userId, subscriptionId, *plan, months, amount, retry, issued)
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (f *InvoiceFactory) assignId() string {
+	return f.storage.nextId()
+}
@@ -0,0 +1,109 @@
+package inMemoryInfrastructure
+
+import (
+	"sync"
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	TmpInvoiceStorage struct {
+		mu   sync.RWMutex
+		data []model.Invoice
+		ids  idSequence
+	}
+	SliceInvoiceRepository struct {
+		Storage *TmpInvoiceStorage
+	}
+)
+
+func NewSliceInvoiceRepository() SliceInvoiceRepository {
+	return SliceInvoiceRepository{Storage: &TmpInvoiceStorage{data: []model.Invoice{}}}
+}
+
+func (sir *SliceInvoiceRepository) Save(invoice model.Invoice) error {
+	sir.Storage.Upsert(invoice)
+	return nil
+}
+
+func (sir *SliceInvoiceRepository) FindById(id *model.InvoiceId) (*model.Invoice, error) {
+	for _, invoice := range sir.Storage.all() {
+		if invoice.Id.V == id.V {
+			return &invoice, nil
+		}
+	}
+	return nil, nil
+}
+
+func (sir *SliceInvoiceRepository) FindByUserId(id *model.UserId) ([]model.Invoice, error) {
+	invoices := []model.Invoice{}
+	for _, invoice := range sir.Storage.all() {
+		if invoice.UserId.V == id.V {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+func (sir *SliceInvoiceRepository) FindOpenBySubscriptionId(id *model.SubscriptionId) (*model.Invoice, error) {
+	for _, invoice := range sir.Storage.all() {
+		if invoice.SubscriptionId.V == id.V && invoice.IsOpen() {
+			return &invoice, nil
+		}
+	}
+	return nil, nil
+}
+
+func (sir *SliceInvoiceRepository) FindRetryDue(t time.Time) ([]model.Invoice, error) {
+	invoices := []model.Invoice{}
+	for _, invoice := range sir.Storage.all() {
+		if invoice.IsRetryDue(t) {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+func (tis *TmpInvoiceStorage) all() []model.Invoice {
+	tis.mu.RLock()
+	defer tis.mu.RUnlock()
+	return tis.data
+}
+
+// Upsert updates the invoice, or inserts it when it is not stored yet
+func (tis *TmpInvoiceStorage) Upsert(invoice model.Invoice) {
+	tis.mu.Lock()
+	defer tis.mu.Unlock()
+	if !tis.update(invoice) {
+		tis.ids.see(invoice.Id.V)
+		tis.data = append(append([]model.Invoice{}, tis.data...), invoice)
+	}
+}
+
+func (tis *TmpInvoiceStorage) update(invoice model.Invoice) bool {
+	for i, v := range tis.data {
+		if v.Id.V == invoice.Id.V {
+			data := append([]model.Invoice{}, tis.data...)
+			data[i] = invoice
+			tis.data = data
+			return true
+		}
+	}
+	return false
+}
+
+func (tis *TmpInvoiceStorage) nextId() string {
+	tis.mu.Lock()
+	defer tis.mu.Unlock()
+	return tis.ids.next(len(tis.data), func(i int) string { return tis.data[i].Id.V })
+}
+
+func (tis *TmpInvoiceStorage) Snapshot() func() {
+	data := tis.all()
+	return func() {
+		tis.mu.Lock()
+		defer tis.mu.Unlock()
+		tis.data = data
+	}
+}
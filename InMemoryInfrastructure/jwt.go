@@ -0,0 +1,109 @@
+package inMemoryInfrastructure
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	// HS256JWT signs and verifies JWTs with HMAC-SHA256.
+	// The subject is the id of the user and the expiry is required.
+	HS256JWT struct {
+		Secret []byte
+		// checked only when it is not empty
+		Issuer string
+		// tolerated clock skew
+		Leeway time.Duration
+	}
+
+	jwtHeader struct {
+		Alg string `json:"alg"`
+		Typ string `json:"typ,omitempty"`
+	}
+
+	jwtClaims struct {
+		Sub string `json:"sub"`
+		Iss string `json:"iss,omitempty"`
+		Iat int64  `json:"iat,omitempty"`
+		Nbf int64  `json:"nbf,omitempty"`
+		Exp int64  `json:"exp"`
+	}
+)
+
+func NewHS256JWT(secret []byte, issuer string) HS256JWT {
+	return HS256JWT{Secret: secret, Issuer: issuer, Leeway: 30 * time.Second}
+}
+
+func (j HS256JWT) Sign(userId model.UserId, issuedAt time.Time, expiresAt time.Time) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(jwtClaims{Sub: userId.V, Iss: j.Issuer, Iat: issuedAt.Unix(), Exp: expiresAt.Unix()})
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(j.sign(signingInput)), nil
+}
+
+func (j HS256JWT) Verify(token string, now time.Time) (model.UserId, error) {
+	invalid := model.NewAuthenticationError("invalid token")
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return model.UserId{}, invalid
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return model.UserId{}, invalid
+	}
+	// the algorithm is fixed so that "none" or asymmetric algorithms can not be forced
+	if header.Alg != "HS256" {
+		return model.UserId{}, invalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, j.sign(parts[0]+"."+parts[1])) {
+		return model.UserId{}, invalid
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return model.UserId{}, invalid
+	}
+	if claims.Exp == 0 || !now.Before(time.Unix(claims.Exp, 0).Add(j.Leeway)) {
+		return model.UserId{}, model.NewAuthenticationError("token has expired")
+	}
+	if claims.Nbf != 0 && now.Add(j.Leeway).Before(time.Unix(claims.Nbf, 0)) {
+		return model.UserId{}, invalid
+	}
+	if len(j.Issuer) != 0 && claims.Iss != j.Issuer {
+		return model.UserId{}, invalid
+	}
+
+	userId, err := model.NewUserId(claims.Sub)
+	if err != nil {
+		return model.UserId{}, invalid
+	}
+	return userId, nil
+}
+
+func (j HS256JWT) sign(signingInput string) []byte {
+	mac := hmac.New(sha256.New, j.Secret)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,48 @@
+package inMemoryInfrastructure
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestHS256JWT_Verify(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	codec := NewHS256JWT([]byte("secret"), "issuer")
+	valid, _ := codec.Sign(model.UserId{V: "1"}, now, now.Add(time.Hour))
+	expired, _ := codec.Sign(model.UserId{V: "1"}, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	otherSecret, _ := NewHS256JWT([]byte("other"), "issuer").Sign(model.UserId{V: "1"}, now, now.Add(time.Hour))
+	otherIssuer, _ := NewHS256JWT([]byte("secret"), "other").Sign(model.UserId{V: "1"}, now, now.Add(time.Hour))
+	parts := strings.Split(valid, ".")
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."
+
+	tests := []struct {
+		name  string
+		token string
+		want  model.UserId
+		err   error
+	}{
+		{name: "valid", token: valid, want: model.UserId{V: "1"}},
+		{name: "expired", token: expired, err: model.ErrUnauthenticated},
+		{name: "signed with another secret", token: otherSecret, err: model.ErrUnauthenticated},
+		{name: "another issuer", token: otherIssuer, err: model.ErrUnauthenticated},
+		{name: "alg none", token: none, err: model.ErrUnauthenticated},
+		{name: "tampered claims", token: parts[0] + "." + parts[1] + "x." + parts[2], err: model.ErrUnauthenticated},
+		{name: "malformed", token: "a.b", err: model.ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := codec.Verify(tt.token, now)
+			assert.Equal(t, tt.want, got,
+				fmt.Sprintf("Verify() got = %v, want %v", got, tt.want))
+			assert.Equal(t, true, errors.Is(err, tt.err),
+				fmt.Sprintf("Verify() error = %v, want %v", err, tt.err))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package inMemoryInfrastructure
+
+import (
+	"sync"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	TmpLedgerStorage struct {
+		mu   sync.RWMutex
+		data []model.LedgerEntry
+	}
+	// ledger entries are only appended
+	SliceLedgerRepository struct {
+		Storage *TmpLedgerStorage
+	}
+)
+
+func NewSliceLedgerRepository() SliceLedgerRepository {
+	return SliceLedgerRepository{Storage: &TmpLedgerStorage{data: []model.LedgerEntry{}}}
+}
+
+func (slr *SliceLedgerRepository) Save(entry model.LedgerEntry) error {
+	slr.Storage.Append(entry)
+	return nil
+}
+
+func (slr *SliceLedgerRepository) FindByUserId(id *model.UserId) (*model.Ledger, error) {
+	ledger := model.Ledger{UserId: *id, Entries: []model.LedgerEntry{}}
+	for _, entry := range slr.Storage.all() {
+		if entry.UserId.V == id.V {
+			ledger.Entries = append(ledger.Entries, entry)
+		}
+	}
+	return &ledger, nil
+}
+
+func (tls *TmpLedgerStorage) all() []model.LedgerEntry {
+	tls.mu.RLock()
+	defer tls.mu.RUnlock()
+	return tls.data
+}
+
+func (tls *TmpLedgerStorage) Append(entry model.LedgerEntry) {
+	tls.mu.Lock()
+	defer tls.mu.Unlock()
+	tls.data = append(append([]model.LedgerEntry{}, tls.data...), entry)
+}
+
+func (tls *TmpLedgerStorage) Snapshot() func() {
+	data := tls.all()
+	return func() {
+		tls.mu.Lock()
+		defer tls.mu.Unlock()
+		tls.data = data
+	}
+}
@@ -0,0 +1,42 @@
+package inMemoryInfrastructure
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"uyutaka.com/ddd-bottom-up/application"
+)
+
+type (
+	// WriterMailer writes mails to a local writer instead of delivering them
+	WriterMailer struct {
+		mu sync.Mutex
+		w  io.Writer
+	}
+)
+
+func NewWriterMailer(w io.Writer) *WriterMailer {
+	return &WriterMailer{w: w}
+}
+
+func NewStdoutMailer() *WriterMailer {
+	return NewWriterMailer(os.Stdout)
+}
+
+// NewFileMailer appends mails to the file at path
+func NewFileMailer(path string) (*WriterMailer, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterMailer(f), nil
+}
+
+func (wm *WriterMailer) Send(mail application.Mail) error {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	_, err := fmt.Fprintf(wm.w, "To: %s\r\nSubject: %s\r\n\r\n%s\r\n.\r\n", mail.To, mail.Subject, mail.Body)
+	return err
+}
@@ -0,0 +1,43 @@
+package inMemoryInfrastructure
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestArgon2idHasher(t *testing.T) {
+	// small parameters keep the test fast
+	hasher := Argon2idHasher{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	hash, err := hasher.Hash(model.Password{V: "Correct-horse7"})
+	assert.Nil(t, err)
+	assert.Equal(t, true, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), hash)
+
+	other, _ := hasher.Hash(model.Password{V: "Correct-horse7"})
+	assert.NotEqual(t, hash, other, "hashes must be salted")
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		want     bool
+		wantErr  bool
+	}{
+		{name: "match", password: "Correct-horse7", hash: hash, want: true},
+		{name: "mismatch", password: "Correct-horse8", hash: hash, want: false},
+		{name: "other parameters", password: "Correct-horse7", hash: other, want: true},
+		{name: "malformed", password: "Correct-horse7", hash: "$2a$10$xxxx", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewArgon2idHasher().Verify(model.Password{V: tt.password}, tt.hash)
+			assert.Equal(t, tt.want, got,
+				fmt.Sprintf("Verify() got = %v, want %v", got, tt.want))
+			assert.Equal(t, tt.wantErr, err != nil,
+				fmt.Sprintf("Verify() error = %v, wantErr %v", err, tt.wantErr))
+		})
+	}
+}
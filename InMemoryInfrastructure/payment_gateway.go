@@ -0,0 +1,66 @@
+package inMemoryInfrastructure
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"uyutaka.com/ddd-bottom-up/application"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	// FakePaymentGateway takes every payment in process, except the ones it was told to decline.
+	// Payment ids are numbered in the order of the payments, so that runs can be repeated.
+	FakePaymentGateway struct {
+		mu       sync.Mutex
+		declines map[string]int
+		payments int
+		refunds  map[string]bool
+	}
+)
+
+func NewFakePaymentGateway() *FakePaymentGateway {
+	return &FakePaymentGateway{declines: map[string]int{}, refunds: map[string]bool{}}
+}
+
+// Decline refuses the next times payments of the user. A negative times refuses all of them.
+func (g *FakePaymentGateway) Decline(userId model.UserId, times int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.declines[userId.V] = times
+}
+
+func (g *FakePaymentGateway) Charge(request application.PaymentRequest) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if times, ok := g.declines[request.UserId.V]; ok && times != 0 {
+		if times > 0 {
+			g.declines[request.UserId.V] = times - 1
+		}
+		return "", model.NewPaymentDeclinedError("card declined")
+	}
+	g.payments++
+	return "pay_" + strconv.Itoa(g.payments), nil
+}
+
+// Refund gives back the payment once. Payments which were not taken can not be refunded.
+func (g *FakePaymentGateway) Refund(paymentId string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	number, err := strconv.Atoi(strings.TrimPrefix(paymentId, "pay_"))
+	if err != nil || number < 1 || number > g.payments {
+		return model.NewNotFoundError("payment", paymentId)
+	}
+	if g.refunds[paymentId] {
+		return model.NewConflictError("payment", paymentId+" is already refunded")
+	}
+	g.refunds[paymentId] = true
+	return nil
+}
+
+func (g *FakePaymentGateway) Refunded(paymentId string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.refunds[paymentId]
+}
@@ -0,0 +1,66 @@
+package inMemoryInfrastructure
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/application"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestFakePaymentGateway_Charge(t *testing.T) {
+	tests := []struct {
+		name     string
+		declines int
+		want     []string
+	}{
+		{name: "never declined", declines: 0, want: []string{"pay_1", "pay_2", "pay_3"}},
+		{name: "declined once", declines: 1, want: []string{"", "pay_1", "pay_2"}},
+		{name: "always declined", declines: -1, want: []string{"", "", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := NewFakePaymentGateway()
+			gateway.Decline(model.UserId{V: "1"}, tt.declines)
+			got := []string{}
+			for i := 0; i < 3; i++ {
+				paymentId, err := gateway.Charge(application.PaymentRequest{UserId: model.UserId{V: "1"}, InvoiceId: model.InvoiceId{V: "1"}, Amount: 500})
+				assert.Equal(t, paymentId == "", errors.Is(err, model.ErrPaymentDeclined), fmt.Sprintf("Charge() error = %v", err))
+				got = append(got, paymentId)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFakePaymentGateway_Refund(t *testing.T) {
+	tests := []struct {
+		name      string
+		paymentId string
+		wantErr   error
+	}{
+		{name: "taken", paymentId: "pay_1"},
+		{name: "refunded already", paymentId: "pay_2", wantErr: model.ErrConflict},
+		{name: "not taken", paymentId: "pay_3", wantErr: model.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := NewFakePaymentGateway()
+			for i := 0; i < 2; i++ {
+				_, err := gateway.Charge(application.PaymentRequest{UserId: model.UserId{V: "1"}, InvoiceId: model.InvoiceId{V: "1"}, Amount: 500})
+				assert.Nil(t, err)
+			}
+			assert.Nil(t, gateway.Refund("pay_2"))
+
+			err := gateway.Refund(tt.paymentId)
+			if tt.wantErr == nil {
+				assert.Nil(t, err, fmt.Sprintf("Refund(%s) error = %v", tt.paymentId, err))
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr, fmt.Sprintf("Refund(%s) error = %v", tt.paymentId, err))
+			}
+			assert.Equal(t, tt.wantErr != model.ErrNotFound, gateway.Refunded(tt.paymentId))
+		})
+	}
+}
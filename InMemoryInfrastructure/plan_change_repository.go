@@ -0,0 +1,58 @@
+package inMemoryInfrastructure
+
+import (
+	"sync"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	TmpPlanChangeStorage struct {
+		mu   sync.RWMutex
+		data []model.PlanChange
+	}
+	// plan changes are only appended
+	SlicePlanChangeRepository struct {
+		Storage *TmpPlanChangeStorage
+	}
+)
+
+func NewSlicePlanChangeRepository() SlicePlanChangeRepository {
+	return SlicePlanChangeRepository{Storage: &TmpPlanChangeStorage{data: []model.PlanChange{}}}
+}
+
+func (spr *SlicePlanChangeRepository) Save(change model.PlanChange) error {
+	spr.Storage.Append(change)
+	return nil
+}
+
+func (spr *SlicePlanChangeRepository) FindByUserId(id *model.UserId) ([]model.PlanChange, error) {
+	changes := []model.PlanChange{}
+	for _, change := range spr.Storage.all() {
+		if change.UserId.V == id.V {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (tpcs *TmpPlanChangeStorage) all() []model.PlanChange {
+	tpcs.mu.RLock()
+	defer tpcs.mu.RUnlock()
+	return tpcs.data
+}
+
+func (tpcs *TmpPlanChangeStorage) Append(change model.PlanChange) {
+	tpcs.mu.Lock()
+	defer tpcs.mu.Unlock()
+	tpcs.data = append(append([]model.PlanChange{}, tpcs.data...), change)
+}
+
+func (tpcs *TmpPlanChangeStorage) Snapshot() func() {
+	data := tpcs.all()
+	return func() {
+		tpcs.mu.Lock()
+		defer tpcs.mu.Unlock()
+		tpcs.data = data
+	}
+}
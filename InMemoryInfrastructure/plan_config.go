@@ -0,0 +1,74 @@
+package inMemoryInfrastructure
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	// planConfig is a plan in a configuration file, for example
+	// {"plans": [{"type": "team", "premium": true, "monthlyPrice": 2000, "maxCircles": 20, "circleCapacity": 100, "features": ["events"]}]}
+	planConfig struct {
+		Type           string   `json:"type"`
+		Premium        bool     `json:"premium"`
+		MonthlyPrice   int      `json:"monthlyPrice"`
+		MaxCircles     int      `json:"maxCircles"`
+		CircleCapacity int      `json:"circleCapacity"`
+		Features       []string `json:"features"`
+	}
+
+	plansConfig struct {
+		Plans []planConfig `json:"plans"`
+	}
+)
+
+// ReadPlans reads plans from JSON. Unknown fields are rejected so that typos do not go unnoticed.
+func ReadPlans(r io.Reader) ([]model.Plan, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	var config plansConfig
+	if err := decoder.Decode(&config); err != nil {
+		return nil, err
+	}
+
+	plans := []model.Plan{}
+	for _, c := range config.Plans {
+		features := []model.Feature{}
+		for _, v := range c.Features {
+			feature, err := model.NewFeature(v)
+			if err != nil {
+				return nil, err
+			}
+			features = append(features, feature)
+		}
+		entitlements, err := model.NewEntitlements(c.MaxCircles, c.CircleCapacity, features)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, model.Plan{Type: model.UserType{V: c.Type}, Premium: c.Premium, MonthlyPrice: c.MonthlyPrice, Entitlements: entitlements})
+	}
+	return plans, nil
+}
+
+// LoadPlans reads the plans in the file at path and registers them, replacing the plans of the same types
+func LoadPlans(path string, registry *model.PlanRegistry) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	plans, err := ReadPlans(f)
+	if err != nil {
+		return err
+	}
+	for _, plan := range plans {
+		if err := registry.Register(plan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package inMemoryInfrastructure
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestReadPlans(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    []model.Plan
+		wantErr bool
+	}{
+		{
+			name:   "plans",
+			config: `{"plans": [{"type": "team", "premium": true, "monthlyPrice": 2000, "maxCircles": 20, "circleCapacity": 100, "features": ["events", "private_circles"]}]}`,
+			want: []model.Plan{{Type: model.UserType{V: "team"}, Premium: true, MonthlyPrice: 2000, Entitlements: model.Entitlements{
+				MaxCircles: 20, CircleCapacity: 100, Features: []model.Feature{model.FEATURE_EVENTS, model.FEATURE_PRIVATE_CIRCLES}}}},
+		},
+		{name: "unknown field", config: `{"plans": [{"type": "team", "capacity": 100}]}`, wantErr: true},
+		{name: "no capacity", config: `{"plans": [{"type": "team"}]}`, wantErr: true},
+		{name: "invalid feature", config: `{"plans": [{"type": "team", "circleCapacity": 100, "features": ["Private Circles"]}]}`, wantErr: true},
+		{name: "not json", config: `plans: team`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadPlans(strings.NewReader(tt.config))
+			assert.Equal(t, tt.wantErr, err != nil, fmt.Sprintf("ReadPlans() error = %v", err))
+			if !tt.wantErr {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadPlans(t *testing.T) {
+	registry := model.NewPlanRegistry(model.DefaultPlanRegistry.Plans()...)
+	assert.Equal(t, true, LoadPlans(t.TempDir()+"/missing.json", registry) != nil)
+
+	path := t.TempDir() + "/plans.json"
+	assert.Nil(t, os.WriteFile(path, []byte(`{"plans": [{"type": "enterprise", "premium": true, "circleCapacity": 500}, {"type": "normal", "maxCircles": 1, "circleCapacity": 10}]}`), 0o600))
+	assert.Nil(t, LoadPlans(path, registry))
+	assert.Equal(t, true, registry.IsPremium(model.UserType{V: "enterprise"}))
+	normal, _ := registry.Find(model.USER_TYPE_NORMAL)
+	assert.Equal(t, 10, normal.Entitlements.CircleCapacity)
+
+	assert.Nil(t, os.WriteFile(path, []byte(`{"plans": [{"type": "Bad Type", "circleCapacity": 10}]}`), 0o600))
+	assert.Equal(t, true, errors.Is(LoadPlans(path, registry), model.ErrValidation))
+}
@@ -0,0 +1,42 @@
+package inMemoryInfrastructure
+
+import (
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	PostFactory struct {
+		storage *TmpPostStorage
+	}
+)
+
+func NewPostFactory(storage *TmpPostStorage) PostFactory {
+	return PostFactory{storage: storage}
+}
+
+func (pf *PostFactory) Create(circleId *model.CircleId, author *model.UserId, body *model.PostBody, created time.Time) (*model.Post, error) {
+	if circleId == nil {
+		return nil, model.NewValidationError("circleId", "is required")
+	}
+	if author == nil {
+		return nil, model.NewValidationError("author", "is required")
+	}
+	if body == nil {
+		return nil, model.NewValidationError("body", "is required")
+	}
+	id, err := model.NewPostId(pf.assignId())
+	if err != nil {
+		return nil, err
+	}
+	post, err := model.NewPost(id, *circleId, *author, *body, created)
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+func (pf *PostFactory) assignId() string {
+	return pf.storage.nextId()
+}
@@ -0,0 +1,122 @@
+package inMemoryInfrastructure
+
+import (
+	"sync"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	TmpPostStorage struct {
+		mu   sync.RWMutex
+		data []model.Post
+		ids  idSequence
+	}
+	SlicePostRepository struct {
+		Storage *TmpPostStorage
+	}
+)
+
+func NewSlicePostRepository() SlicePostRepository {
+	return SlicePostRepository{Storage: &TmpPostStorage{data: []model.Post{}}}
+}
+
+func (spr *SlicePostRepository) Save(post model.Post) error {
+	spr.Storage.Upsert(post)
+	return nil
+}
+
+func (spr *SlicePostRepository) FindById(id *model.PostId) (*model.Post, error) {
+	for _, post := range spr.Storage.all() {
+		if post.Id.V == id.V {
+			return &post, nil
+		}
+	}
+	return nil, nil
+}
+
+// returns posts of the circle in the order they were created
+func (spr *SlicePostRepository) FindByCircle(circleId *model.CircleId) ([]model.Post, error) {
+	posts := []model.Post{}
+	for _, post := range spr.Storage.all() {
+		if post.CircleId.V == circleId.V {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+func (spr *SlicePostRepository) Delete(post model.Post) error {
+	if !spr.Storage.Delete(post.Id) {
+		return model.NewNotFoundError("post", post.Id.V)
+	}
+	return nil
+}
+
+func (tps *TmpPostStorage) all() []model.Post {
+	tps.mu.RLock()
+	defer tps.mu.RUnlock()
+	return tps.data
+}
+
+func (tps *TmpPostStorage) Insert(post model.Post) {
+	tps.mu.Lock()
+	defer tps.mu.Unlock()
+	tps.ids.see(post.Id.V)
+	tps.data = append(append([]model.Post{}, tps.data...), post)
+}
+
+func (tps *TmpPostStorage) Update(post model.Post) {
+	tps.mu.Lock()
+	defer tps.mu.Unlock()
+	tps.update(post)
+}
+
+// Upsert updates the post, or inserts it when it is not stored yet
+func (tps *TmpPostStorage) Upsert(post model.Post) {
+	tps.mu.Lock()
+	defer tps.mu.Unlock()
+	if !tps.update(post) {
+		tps.ids.see(post.Id.V)
+		tps.data = append(append([]model.Post{}, tps.data...), post)
+	}
+}
+
+func (tps *TmpPostStorage) update(post model.Post) bool {
+	for i, p := range tps.data {
+		if p.Id.V == post.Id.V {
+			data := append([]model.Post{}, tps.data...)
+			data[i] = post
+			tps.data = data
+			return true
+		}
+	}
+	return false
+}
+
+func (tps *TmpPostStorage) Delete(id model.PostId) bool {
+	tps.mu.Lock()
+	defer tps.mu.Unlock()
+	for i, p := range tps.data {
+		if p.Id.V == id.V {
+			tps.data = append(append([]model.Post{}, tps.data[:i]...), tps.data[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (tps *TmpPostStorage) nextId() string {
+	tps.mu.Lock()
+	defer tps.mu.Unlock()
+	return tps.ids.next(len(tps.data), func(i int) string { return tps.data[i].Id.V })
+}
+
+func (tps *TmpPostStorage) Snapshot() func() {
+	data := tps.all()
+	return func() {
+		tps.mu.Lock()
+		defer tps.mu.Unlock()
+		tps.data = data
+	}
+}
@@ -0,0 +1,90 @@
+package inMemoryInfrastructure
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestSlicePostRepository_FindByCircle(t *testing.T) {
+	storage := &TmpPostStorage{data: []model.Post{
+		{Id: model.PostId{V: "1"}, CircleId: model.CircleId{V: "1"}, Author: model.UserId{V: "1"}, Body: model.PostBody{V: "first"}},
+		{Id: model.PostId{V: "2"}, CircleId: model.CircleId{V: "2"}, Author: model.UserId{V: "1"}, Body: model.PostBody{V: "other"}},
+		{Id: model.PostId{V: "3"}, CircleId: model.CircleId{V: "1"}, Author: model.UserId{V: "2"}, Body: model.PostBody{V: "second"}},
+	}}
+	tests := []struct {
+		name     string
+		circleId model.CircleId
+		want     []model.Post
+	}{
+		{
+			name:     "posts of the circle",
+			circleId: model.CircleId{V: "1"},
+			want: []model.Post{
+				{Id: model.PostId{V: "1"}, CircleId: model.CircleId{V: "1"}, Author: model.UserId{V: "1"}, Body: model.PostBody{V: "first"}},
+				{Id: model.PostId{V: "3"}, CircleId: model.CircleId{V: "1"}, Author: model.UserId{V: "2"}, Body: model.PostBody{V: "second"}},
+			},
+		},
+		{
+			name:     "no posts",
+			circleId: model.CircleId{V: "3"},
+			want:     []model.Post{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spr := &SlicePostRepository{Storage: storage}
+			got, err := spr.FindByCircle(&tt.circleId)
+			assert.Nil(t, err)
+			assert.Equal(t, true, reflect.DeepEqual(got, tt.want),
+				fmt.Sprintf("SlicePostRepository.FindByCircle() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func TestSlicePostRepository_Delete(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []model.Post
+		post     model.Post
+		wantErr  bool
+		expected []model.Post
+	}{
+		{
+			name: "deleted",
+			data: []model.Post{
+				{Id: model.PostId{V: "1"}, Body: model.PostBody{V: "first"}},
+				{Id: model.PostId{V: "2"}, Body: model.PostBody{V: "second"}},
+			},
+			post:    model.Post{Id: model.PostId{V: "1"}},
+			wantErr: false,
+			expected: []model.Post{
+				{Id: model.PostId{V: "2"}, Body: model.PostBody{V: "second"}},
+			},
+		},
+		{
+			name: "not found",
+			data: []model.Post{
+				{Id: model.PostId{V: "1"}, Body: model.PostBody{V: "first"}},
+			},
+			post:    model.Post{Id: model.PostId{V: "3"}},
+			wantErr: true,
+			expected: []model.Post{
+				{Id: model.PostId{V: "1"}, Body: model.PostBody{V: "first"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spr := &SlicePostRepository{Storage: &TmpPostStorage{data: tt.data}}
+			err := spr.Delete(tt.post)
+			assert.Equal(t, tt.wantErr, err != nil,
+				fmt.Sprintf("SlicePostRepository.Delete() error = %v, wantErr %v", err, tt.wantErr))
+			assert.Equal(t, true, reflect.DeepEqual(spr.Storage.data, tt.expected),
+				fmt.Sprintf("spr.Storage.data = %v, expected = %v", spr.Storage.data, tt.expected))
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package inMemoryInfrastructure
+
+import (
+	"sync"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	TmpPromoCodeStorage struct {
+		mu   sync.RWMutex
+		data []model.PromoCode
+	}
+	SlicePromoCodeRepository struct {
+		Storage *TmpPromoCodeStorage
+	}
+)
+
+func NewSlicePromoCodeRepository() SlicePromoCodeRepository {
+	return SlicePromoCodeRepository{Storage: &TmpPromoCodeStorage{data: []model.PromoCode{}}}
+}
+
+func (spr *SlicePromoCodeRepository) Save(promoCode model.PromoCode) error {
+	spr.Storage.Upsert(promoCode)
+	return nil
+}
+
+func (spr *SlicePromoCodeRepository) FindByCode(code *model.PromoCodeValue) (*model.PromoCode, error) {
+	for _, promoCode := range spr.Storage.all() {
+		if promoCode.Code.V == code.V {
+			clone := promoCode.Clone()
+			return &clone, nil
+		}
+	}
+	return nil, nil
+}
+
+func (tpcs *TmpPromoCodeStorage) all() []model.PromoCode {
+	tpcs.mu.RLock()
+	defer tpcs.mu.RUnlock()
+	return tpcs.data
+}
+
+// Upsert updates the promo code, or inserts it when it is not stored yet
+func (tpcs *TmpPromoCodeStorage) Upsert(promoCode model.PromoCode) {
+	tpcs.mu.Lock()
+	defer tpcs.mu.Unlock()
+	if !tpcs.update(promoCode) {
+		tpcs.data = append(append([]model.PromoCode{}, tpcs.data...), promoCode.Clone())
+	}
+}
+
+func (tpcs *TmpPromoCodeStorage) update(promoCode model.PromoCode) bool {
+	for i, p := range tpcs.data {
+		if p.Code.V == promoCode.Code.V {
+			data := append([]model.PromoCode{}, tpcs.data...)
+			data[i] = promoCode.Clone()
+			tpcs.data = data
+			return true
+		}
+	}
+	return false
+}
+
+func (tpcs *TmpPromoCodeStorage) Snapshot() func() {
+	data := tpcs.all()
+	return func() {
+		tpcs.mu.Lock()
+		defer tpcs.mu.Unlock()
+		tpcs.data = data
+	}
+}
@@ -0,0 +1,93 @@
+package inMemoryInfrastructure
+
+import (
+	"sync"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	TmpSessionStorage struct {
+		mu   sync.RWMutex
+		data []model.Session
+	}
+	SliceSessionRepository struct {
+		Storage *TmpSessionStorage
+	}
+)
+
+func NewSliceSessionRepository() SliceSessionRepository {
+	return SliceSessionRepository{Storage: &TmpSessionStorage{data: []model.Session{}}}
+}
+
+func (ssr *SliceSessionRepository) Save(session model.Session) error {
+	ssr.Storage.Upsert(session)
+	return nil
+}
+
+func (ssr *SliceSessionRepository) FindByToken(tokenHash string) (*model.Session, error) {
+	for _, session := range ssr.Storage.all() {
+		if session.Token == tokenHash {
+			return &session, nil
+		}
+	}
+	return nil, nil
+}
+
+func (ssr *SliceSessionRepository) Delete(session model.Session) error {
+	deleted := ssr.Storage.DeleteWhere(func(s model.Session) bool { return s.Token == session.Token })
+	if deleted == 0 {
+		return model.NewNotFoundError("session", "")
+	}
+	return nil
+}
+
+func (ssr *SliceSessionRepository) DeleteByUserId(id *model.UserId) error {
+	ssr.Storage.DeleteWhere(func(s model.Session) bool { return s.UserId.V == id.V })
+	return nil
+}
+
+func (tss *TmpSessionStorage) all() []model.Session {
+	tss.mu.RLock()
+	defer tss.mu.RUnlock()
+	return tss.data
+}
+
+// Upsert updates the session, or inserts it when it is not stored yet
+func (tss *TmpSessionStorage) Upsert(session model.Session) {
+	tss.mu.Lock()
+	defer tss.mu.Unlock()
+	data := append([]model.Session{}, tss.data...)
+	for i, s := range data {
+		if s.Token == session.Token {
+			data[i] = session
+			tss.data = data
+			return
+		}
+	}
+	tss.data = append(data, session)
+}
+
+// DeleteWhere deletes the sessions which match and returns how many were deleted
+func (tss *TmpSessionStorage) DeleteWhere(match func(session model.Session) bool) int {
+	tss.mu.Lock()
+	defer tss.mu.Unlock()
+	sessions := []model.Session{}
+	for _, s := range tss.data {
+		if !match(s) {
+			sessions = append(sessions, s)
+		}
+	}
+	deleted := len(tss.data) - len(sessions)
+	tss.data = sessions
+	return deleted
+}
+
+func (tss *TmpSessionStorage) Snapshot() func() {
+	data := tss.all()
+	return func() {
+		tss.mu.Lock()
+		defer tss.mu.Unlock()
+		tss.data = data
+	}
+}
@@ -0,0 +1,57 @@
+package inMemoryInfrastructure
+
+import (
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	SubscriptionFactory struct {
+		storage *TmpSubscriptionStorage
+	}
+)
+
+func NewSubscriptionFactory(storage *TmpSubscriptionStorage) SubscriptionFactory {
+	return SubscriptionFactory{storage: storage}
+}
+
+func (sf *SubscriptionFactory) Create(userId *model.UserId, plan *model.UserType, months int, start time.Time, autoRenew bool) (*model.Subscription, error) {
+	if userId == nil {
+		return nil, model.NewValidationError("userId", "is required")
+	}
+	if plan == nil {
+		return nil, model.NewValidationError("plan", "is required")
+	}
+	id, err := model.NewSubscriptionId(sf.assignId())
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := model.NewSubscription(id, *userId, *plan, months, start, autoRenew)
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (sf *SubscriptionFactory) CreateTrial(userId *model.UserId, promoCode *model.PromoCode, start time.Time) (*model.Subscription, error) {
+	if userId == nil {
+		return nil, model.NewValidationError("userId", "is required")
+	}
+	if promoCode == nil {
+		return nil, model.NewValidationError("promoCode", "is required")
+	}
+	id, err := model.NewSubscriptionId(sf.assignId())
+	if err != nil {
+		return nil, err
+	}
+	trial, err := model.NewTrial(id, *userId, *promoCode, start)
+	if err != nil {
+		return nil, err
+	}
+	return &trial, nil
+}
+
+func (sf *SubscriptionFactory) assignId() string {
+	return sf.storage.nextId()
+}
@@ -0,0 +1,101 @@
+package inMemoryInfrastructure
+
+import (
+	"sync"
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	TmpSubscriptionStorage struct {
+		mu   sync.RWMutex
+		data []model.Subscription
+		ids  idSequence
+	}
+	SliceSubscriptionRepository struct {
+		Storage *TmpSubscriptionStorage
+	}
+)
+
+func NewSliceSubscriptionRepository() SliceSubscriptionRepository {
+	return SliceSubscriptionRepository{Storage: &TmpSubscriptionStorage{data: []model.Subscription{}}}
+}
+
+func (ssr *SliceSubscriptionRepository) Save(subscription model.Subscription) error {
+	ssr.Storage.Upsert(subscription)
+	return nil
+}
+
+func (ssr *SliceSubscriptionRepository) FindById(id *model.SubscriptionId) (*model.Subscription, error) {
+	for _, subscription := range ssr.Storage.all() {
+		if subscription.Id.V == id.V {
+			return &subscription, nil
+		}
+	}
+	return nil, nil
+}
+
+// subscriptions are stored in the order they started, so the last one is the latest
+func (ssr *SliceSubscriptionRepository) FindByUserId(id *model.UserId) (*model.Subscription, error) {
+	data := ssr.Storage.all()
+	for i := len(data) - 1; i >= 0; i-- {
+		if subscription := data[i]; subscription.UserId.V == id.V {
+			return &subscription, nil
+		}
+	}
+	return nil, nil
+}
+
+func (ssr *SliceSubscriptionRepository) FindDue(t time.Time) ([]model.Subscription, error) {
+	subscriptions := []model.Subscription{}
+	for _, subscription := range ssr.Storage.all() {
+		if subscription.IsDue(t) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (tss *TmpSubscriptionStorage) all() []model.Subscription {
+	tss.mu.RLock()
+	defer tss.mu.RUnlock()
+	return tss.data
+}
+
+// Upsert updates the subscription, or inserts it when it is not stored yet
+func (tss *TmpSubscriptionStorage) Upsert(subscription model.Subscription) {
+	tss.mu.Lock()
+	defer tss.mu.Unlock()
+	if !tss.update(subscription) {
+		tss.ids.see(subscription.Id.V)
+		tss.data = append(append([]model.Subscription{}, tss.data...), subscription)
+	}
+}
+
+func (tss *TmpSubscriptionStorage) update(subscription model.Subscription) bool {
+	for i, s := range tss.data {
+		if s.Id.V == subscription.Id.V {
+			data := append([]model.Subscription{}, tss.data...)
+			data[i] = subscription
+			tss.data = data
+			return true
+		}
+	}
+	return false
+}
+
+func (tss *TmpSubscriptionStorage) nextId() string {
+	tss.mu.Lock()
+	defer tss.mu.Unlock()
+	return tss.ids.next(len(tss.data), func(i int) string { return tss.data[i].Id.V })
+}
+
+func (tss *TmpSubscriptionStorage) Snapshot() func() {
+	data := tss.all()
+	return func() {
+		tss.mu.Lock()
+		defer tss.mu.Unlock()
+		tss.data = data
+	}
+}
@@ -0,0 +1,55 @@
+package inMemoryInfrastructure
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestSliceSubscriptionRepository_FindDue(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	ended := model.Subscription{Id: model.SubscriptionId{V: "1"}, UserId: model.UserId{V: "1"}, Months: 1, Start: now.AddDate(0, -1, 0), End: now}
+	running := model.Subscription{Id: model.SubscriptionId{V: "2"}, UserId: model.UserId{V: "2"}, Months: 1, Start: now, End: now.AddDate(0, 1, 0)}
+	expired := model.Subscription{Id: model.SubscriptionId{V: "3"}, UserId: model.UserId{V: "3"}, Months: 1, Start: now.AddDate(0, -2, 0), End: now.AddDate(0, -1, 0), Expired: now.AddDate(0, -1, 0)}
+	tests := []struct {
+		name string
+		t    time.Time
+		want []model.Subscription
+	}{
+		{name: "ended now", t: now, want: []model.Subscription{ended}},
+		{name: "before the end", t: now.Add(-time.Second), want: []model.Subscription{}},
+		{name: "next month", t: now.AddDate(0, 1, 0), want: []model.Subscription{ended, running}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ssr := &SliceSubscriptionRepository{Storage: &TmpSubscriptionStorage{data: []model.Subscription{ended, running, expired}}}
+			got, err := ssr.FindDue(tt.t)
+			assert.Nil(t, err)
+			assert.Equal(t, true, reflect.DeepEqual(got, tt.want),
+				fmt.Sprintf("SliceSubscriptionRepository.FindDue() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func TestSliceSubscriptionRepository_FindByUserId(t *testing.T) {
+	ssr := NewSliceSubscriptionRepository()
+	factory := NewSubscriptionFactory(ssr.Storage)
+	userId := model.UserId{V: "1"}
+	plan := model.USER_TYPE_PREMIUM
+	for i := 0; i < 2; i++ {
+		subscription, err := factory.Create(&userId, &plan, 1, time.Now(), false)
+		assert.Nil(t, err)
+		assert.Nil(t, ssr.Save(*subscription))
+	}
+
+	got, err := ssr.FindByUserId(&userId)
+	assert.Nil(t, err)
+	assert.Equal(t, "2", got.Id.V, "the latest subscription is returned")
+	got, err = ssr.FindByUserId(&model.UserId{V: "2"})
+	assert.Nil(t, err)
+	assert.Nil(t, got)
+}
@@ -0,0 +1,66 @@
+package inMemoryInfrastructure
+
+import (
+	"sync"
+)
+
+type (
+	// storages which can be put back as they were, such as TmpUserStorage.
+	// Snapshot returns a function which puts the data back as it is now.
+	// The storages keep their data copy-on-write, so that a snapshot only holds on to the current slice.
+	ISnapshotStorage interface {
+		Snapshot() func()
+	}
+
+	// InMemoryUnitOfWork runs units of work one at a time, so that what a unit reads
+	// is not changed by another unit before it saves, and a unit never saves a stale version.
+	// Versions catch the clients which read in an earlier request instead, through If-Match.
+	// Units of work can not be nested.
+	InMemoryUnitOfWork struct {
+		mu       sync.Mutex
+		storages []ISnapshotStorage
+	}
+)
+
+func NewInMemoryUnitOfWork(storages ...ISnapshotStorage) *InMemoryUnitOfWork {
+	return &InMemoryUnitOfWork{storages: storages}
+}
+
+// Register adds storages to the units of work which start after it
+func (uow *InMemoryUnitOfWork) Register(storages ...ISnapshotStorage) {
+	uow.mu.Lock()
+	defer uow.mu.Unlock()
+	uow.storages = append(uow.storages, storages...)
+}
+
+// Do saves straight to the storages, which are put back as they were
+// when work returns an error or panics
+func (uow *InMemoryUnitOfWork) Do(work func() error) error {
+	uow.mu.Lock()
+	defer uow.mu.Unlock()
+
+	rollback := uow.begin()
+	committed := false
+	defer func() {
+		if !committed {
+			rollback()
+		}
+	}()
+	if err := work(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+func (uow *InMemoryUnitOfWork) begin() func() {
+	restores := []func(){}
+	for _, storage := range uow.storages {
+		restores = append(restores, storage.Snapshot())
+	}
+	return func() {
+		for _, restore := range restores {
+			restore()
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package inMemoryInfrastructure
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestInMemoryUnitOfWork_Do(t *testing.T) {
+	failure := errors.New("failed")
+	tests := []struct {
+		name      string
+		fail      error
+		panics    bool
+		wantErr   error
+		wantUsers []string
+		wantPosts int
+	}{
+		{name: "commit", wantUsers: []string{"user1", "user2"}, wantPosts: 1},
+		{name: "rollback on error", fail: failure, wantErr: failure, wantUsers: []string{"user1"}, wantPosts: 0},
+		{name: "rollback on panic", panics: true, wantUsers: []string{"user1"}, wantPosts: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepository := SliceUserRepository{Storage: &TmpUserStorage{data: []model.User{
+				{Id: model.UserId{V: "1"}, Name: model.UserName{V: "user1"}, UType: model.USER_TYPE_NORMAL},
+			}}}
+			postRepository := NewSlicePostRepository()
+			uow := NewInMemoryUnitOfWork(userRepository.Storage)
+			uow.Register(postRepository.Storage)
+
+			work := func() error {
+				if err := userRepository.Save(model.User{Id: model.UserId{V: "2"}, Name: model.UserName{V: "user2"}, UType: model.USER_TYPE_NORMAL}); err != nil {
+					return err
+				}
+				if err := postRepository.Save(model.Post{Id: model.PostId{V: "1"}, CircleId: model.CircleId{V: "1"}, Author: model.UserId{V: "2"}}); err != nil {
+					return err
+				}
+				if tt.panics {
+					panic("work panicked")
+				}
+				return tt.fail
+			}
+			if tt.panics {
+				assert.Panics(t, func() { _ = uow.Do(work) })
+			} else {
+				assert.Equal(t, tt.wantErr, uow.Do(work))
+			}
+
+			names := []string{}
+			for _, user := range userRepository.Storage.data {
+				names = append(names, user.Name.V)
+			}
+			assert.Equal(t, tt.wantUsers, names, fmt.Sprintf("users after %s", tt.name))
+			assert.Equal(t, tt.wantPosts, len(postRepository.Storage.data), fmt.Sprintf("posts after %s", tt.name))
+		})
+	}
+}
@@ -1,37 +1,39 @@
 package inMemoryInfrastructure
 
 import (
-	"strconv"
-
 	"uyutaka.com/ddd-bottom-up/model"
 )
 
 type (
 	UserFactory struct {
-		storage *TmpUserStorage
+		storage    *TmpUserStorage
+		namePolicy model.UserNamePolicy
 	}
 )
 
-func NewUserFactory(storage *TmpUserStorage) UserFactory {
-	return UserFactory{storage: storage}
+func NewUserFactory(storage *TmpUserStorage, namePolicy model.UserNamePolicy) UserFactory {
+	return UserFactory{storage: storage, namePolicy: namePolicy}
 }
 
+// Create makes a user only of a name which follows the policy of the factory
 func (uf *UserFactory) Create(name *model.UserName) (*model.User, error) {
-	id := uf.assignId()
-	userId, _ := model.NewUserId(id)
-	return &model.User{Name: *name, Id: userId, UType: model.USER_TYPE_NORMAL}, nil
+	if name == nil {
+		return nil, model.NewValidationError("name", "is required")
+	}
+	if _, err := uf.namePolicy.NewUserName(name.V); err != nil {
+		return nil, err
+	}
+	userId, err := model.NewUserId(uf.assignId())
+	if err != nil {
+		return nil, err
+	}
+	user, err := model.NewUser(userId, *name, model.USER_TYPE_NORMAL)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
 func (uf *UserFactory) assignId() string {
-	max := 0
-	for _, user := range uf.storage.data {
-		intId, err := strconv.Atoi(user.Id.V)
-		if err != nil {
-			break
-		}
-		if max < intId {
-			max = intId
-		}
-	}
-	return strconv.Itoa(max + 1)
+	return uf.storage.nextId()
 }
@@ -36,7 +36,7 @@ func TestUserFactory_Create(t *testing.T) {
 			},
 			args: args{name: &model.UserName{V: "test_user3"}},
 			wants: wants{
-				user:  model.User{Id: model.UserId{V: "3"}, Name: model.UserName{V: "test_user3"}, UType: model.USER_TYPE_NORMAL},
+				user:  model.User{Id: model.UserId{V: "3"}, Name: model.UserName{V: "test_user3"}, UType: model.USER_TYPE_NORMAL, Role: model.USER_ROLE_MEMBER, Status: model.UserStatusRecord{Status: model.USER_STATUS_ACTIVE}},
 				error: nil,
 			},
 		},
@@ -44,7 +44,8 @@ func TestUserFactory_Create(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			uf := &UserFactory{
-				storage: tt.fields.storage,
+				storage:    tt.fields.storage,
+				namePolicy: model.NewDefaultUserNamePolicy(),
 			}
 			user, err := uf.Create(tt.args.name)
 			assert.Equal(t, tt.wants.error, err,
@@ -56,6 +57,17 @@ func TestUserFactory_Create(t *testing.T) {
 	}
 }
 
+func TestUserFactory_CreateFollowsNamePolicy(t *testing.T) {
+	policy := model.NewDefaultUserNamePolicy()
+	policy.Reserved = []string{"guest"}
+	uf := NewUserFactory(&TmpUserStorage{data: []model.User{}}, policy)
+
+	_, err := uf.Create(&model.UserName{V: "guest"})
+	assert.ErrorIs(t, err, model.ErrValidation, "a reserved name of the policy was accepted")
+	_, err = uf.Create(&model.UserName{V: "admin"})
+	assert.Nil(t, err, "a reserved name of the default policy was rejected")
+}
+
 func TestUserFactory_assignId(t *testing.T) {
 	type fields struct {
 		storage *TmpUserStorage
@@ -1,14 +1,21 @@
 package inMemoryInfrastructure
 
 import (
-	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"uyutaka.com/ddd-bottom-up/model"
 )
 
 type (
+	// TmpUserStorage is safe for concurrent use. Writes replace data with a changed copy
+	// instead of changing it in place, so readers iterate what they got without holding the lock.
 	TmpUserStorage struct {
+		mu   sync.RWMutex
 		data []model.User
+		ids  idSequence
 	}
 	SliceUserRepository struct {
 		Storage *TmpUserStorage
@@ -17,23 +24,19 @@ type (
 
 func NewSliceUserRepository() SliceUserRepository {
 	storage := TmpUserStorage{data: []model.User{
-		{Id: model.UserId{V: "1"}, Name: model.UserName{V: "user1"}, UType: model.USER_TYPE_NORMAL},
-		{Id: model.UserId{V: "2"}, Name: model.UserName{V: "user2"}, UType: model.USER_TYPE_PREMIUM},
+		{Id: model.UserId{V: "1"}, Name: model.UserName{V: "user1"}, UType: model.USER_TYPE_NORMAL, Role: model.USER_ROLE_MEMBER, Status: model.UserStatusRecord{Status: model.USER_STATUS_ACTIVE}},
+		{Id: model.UserId{V: "2"}, Name: model.UserName{V: "user2"}, UType: model.USER_TYPE_PREMIUM, Role: model.USER_ROLE_MEMBER, Status: model.UserStatusRecord{Status: model.USER_STATUS_ACTIVE}},
 	}}
 	return SliceUserRepository{Storage: &storage}
 }
 
+// Save stores the user as their next version. Users read before the latest save are stale.
 func (sur *SliceUserRepository) Save(user model.User) error {
-	if sur.Exists(user) {
-		sur.Storage.Update(user)
-	} else {
-		sur.Storage.Insert(user)
-	}
-	return nil
+	return sur.Storage.Upsert(user)
 }
 
 func (sur *SliceUserRepository) FindById(id *model.UserId) (*model.User, error) {
-	for _, user := range sur.Storage.data {
+	for _, user := range sur.Storage.all() {
 		if user.Id.V == id.V {
 			return &user, nil
 		}
@@ -42,7 +45,7 @@ func (sur *SliceUserRepository) FindById(id *model.UserId) (*model.User, error)
 }
 
 func (sur *SliceUserRepository) FindByName(name *model.UserName) (*model.User, error) {
-	for _, user := range sur.Storage.data {
+	for _, user := range sur.Storage.all() {
 		if user.Name.V == name.V {
 			return &user, nil
 		}
@@ -50,12 +53,23 @@ func (sur *SliceUserRepository) FindByName(name *model.UserName) (*model.User, e
 	return nil, nil
 }
 
+func (sur *SliceUserRepository) FindByEmail(email *model.Email) (*model.User, error) {
+	for _, user := range sur.Storage.all() {
+		if user.Email.Equals(*email) {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindAll returns a copy, so changing the users does not change the storage until they are saved
 func (sur *SliceUserRepository) FindAll() (*[]model.User, error) {
-	return &sur.Storage.data, nil
+	users := append([]model.User{}, sur.Storage.all()...)
+	return &users, nil
 }
 
 func (sur *SliceUserRepository) Exists(user model.User) bool {
-	for _, u := range sur.Storage.data {
+	for _, u := range sur.Storage.all() {
 		if u.Id.V == user.Id.V {
 			return true
 		}
@@ -64,24 +78,141 @@ func (sur *SliceUserRepository) Exists(user model.User) bool {
 }
 
 func (sur *SliceUserRepository) Delete(user model.User) error {
-	for i, u := range sur.Storage.data {
-		if u.Id.V == user.Id.V {
-			sur.Storage.data = append(sur.Storage.data[:i], sur.Storage.data[i+1:]...)
-			return nil
-		}
-	}
-	return errors.New("user not found")
+	return sur.Storage.Delete(user)
+}
+
+func (tus *TmpUserStorage) all() []model.User {
+	tus.mu.RLock()
+	defer tus.mu.RUnlock()
+	return tus.data
 }
 
 func (tus *TmpUserStorage) Insert(user model.User) {
-	tus.data = append(tus.data, user)
+	tus.mu.Lock()
+	defer tus.mu.Unlock()
+	tus.ids.see(user.Id.V)
+	tus.data = append(append([]model.User{}, tus.data...), user)
 }
 
 func (tus *TmpUserStorage) Update(user model.User) {
+	tus.mu.Lock()
+	defer tus.mu.Unlock()
+	tus.update(user)
+}
+
+// Upsert stores the user as their next version, inserting them when they are not stored yet.
+// It fails when the user is not at the stored version, as someone else saved them after they were read.
+func (tus *TmpUserStorage) Upsert(user model.User) error {
+	tus.mu.Lock()
+	defer tus.mu.Unlock()
+	stored := tus.find(user.Id)
+	if stored == nil && user.Version != 0 {
+		return model.NewNotFoundError("user", user.Id.V)
+	}
+	if stored != nil && stored.Version != user.Version {
+		return model.NewStaleError("user", user.Id.V, stored.Version)
+	}
+	user.Version++
+	if !tus.update(user) {
+		tus.ids.see(user.Id.V)
+		tus.data = append(append([]model.User{}, tus.data...), user)
+	}
+	return nil
+}
+
+func (tus *TmpUserStorage) find(id model.UserId) *model.User {
+	for _, u := range tus.data {
+		if u.Id.V == id.V {
+			return &u
+		}
+	}
+	return nil
+}
+
+func (tus *TmpUserStorage) update(user model.User) bool {
+	for i, u := range tus.data {
+		if u.Id.V == user.Id.V {
+			data := append([]model.User{}, tus.data...)
+			data[i] = user
+			tus.data = data
+			return true
+		}
+	}
+	return false
+}
+
+// Delete fails like Upsert when the user is not at the stored version
+func (tus *TmpUserStorage) Delete(user model.User) error {
+	tus.mu.Lock()
+	defer tus.mu.Unlock()
 	for i, u := range tus.data {
 		if u.Id.V == user.Id.V {
-			tus.data[i] = user
-			return
+			if u.Version != user.Version {
+				return model.NewStaleError("user", user.Id.V, u.Version)
+			}
+			tus.data = append(append([]model.User{}, tus.data[:i]...), tus.data[i+1:]...)
+			return nil
+		}
+	}
+	return model.NewNotFoundError("user", user.Id.V)
+}
+
+func (tus *TmpUserStorage) nextId() string {
+	tus.mu.Lock()
+	defer tus.mu.Unlock()
+	return tus.ids.next(len(tus.data), func(i int) string { return tus.data[i].Id.V })
+}
+
+func (sur *SliceUserRepository) FindByQuery(query model.UserQuery) (*model.UserPage, error) {
+	users := []model.User{}
+	for _, user := range sur.Storage.all() {
+		if query.UType != nil && user.UType.V != query.UType.V {
+			continue
 		}
+		if query.Status != nil && user.Status.Status != *query.Status {
+			continue
+		}
+		if !strings.HasPrefix(user.Name.V, query.NamePrefix) {
+			continue
+		}
+		users = append(users, user)
+	}
+
+	sort.SliceStable(users, func(i, j int) bool {
+		if query.Descending {
+			return lessUser(users[j], users[i], query.SortKey)
+		}
+		return lessUser(users[i], users[j], query.SortKey)
+	})
+
+	total := len(users)
+	if query.Offset > 0 {
+		users = users[min(query.Offset, total):]
+	}
+	if query.Limit > 0 && len(users) > query.Limit {
+		users = users[:query.Limit]
+	}
+	return &model.UserPage{Users: users, Total: total}, nil
+}
+
+// ids are compared as numbers because UserFactory assigns sequential numbers
+func lessUser(a model.User, b model.User, key model.UserSortKey) bool {
+	if key == model.USER_SORT_KEY_NAME && a.Name.V != b.Name.V {
+		return a.Name.V < b.Name.V
+	}
+	aId, aErr := strconv.Atoi(a.Id.V)
+	bId, bErr := strconv.Atoi(b.Id.V)
+	if aErr == nil && bErr == nil {
+		return aId < bId
+	}
+	return a.Id.V < b.Id.V
+}
+
+func (tus *TmpUserStorage) Snapshot() func() {
+	data := tus.all()
+	return func() {
+		tus.mu.Lock()
+		defer tus.mu.Unlock()
+		tus.data = data
 	}
 }
@@ -3,6 +3,7 @@ package inMemoryInfrastructure
 import (
 	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -170,3 +171,203 @@ func TestTmpUserStorage_Insert(t *testing.T) {
 }
 
 // TODO FindById()
+
+func TestSliceUserRepository_FindByQuery(t *testing.T) {
+	storage := &TmpUserStorage{data: []model.User{
+		{Id: model.UserId{V: "10"}, Name: model.UserName{V: "bob"}, UType: model.USER_TYPE_NORMAL},
+		{Id: model.UserId{V: "2"}, Name: model.UserName{V: "alice"}, UType: model.USER_TYPE_PREMIUM},
+		{Id: model.UserId{V: "3"}, Name: model.UserName{V: "albert"}, UType: model.USER_TYPE_NORMAL},
+	}}
+	premium := model.USER_TYPE_PREMIUM
+	tests := []struct {
+		name      string
+		query     model.UserQuery
+		wantIds   []string
+		wantTotal int
+	}{
+		{
+			name:      "sorted by id numerically",
+			query:     model.UserQuery{SortKey: model.USER_SORT_KEY_ID},
+			wantIds:   []string{"2", "3", "10"},
+			wantTotal: 3,
+		},
+		{
+			name:      "sorted by name descending",
+			query:     model.UserQuery{SortKey: model.USER_SORT_KEY_NAME, Descending: true},
+			wantIds:   []string{"10", "2", "3"},
+			wantTotal: 3,
+		},
+		{
+			name:      "limit and offset",
+			query:     model.UserQuery{SortKey: model.USER_SORT_KEY_ID, Limit: 1, Offset: 1},
+			wantIds:   []string{"3"},
+			wantTotal: 3,
+		},
+		{
+			name:      "offset beyond the end",
+			query:     model.UserQuery{SortKey: model.USER_SORT_KEY_ID, Offset: 5},
+			wantIds:   []string{},
+			wantTotal: 3,
+		},
+		{
+			name:      "filtered by type",
+			query:     model.UserQuery{SortKey: model.USER_SORT_KEY_ID, UType: &premium},
+			wantIds:   []string{"2"},
+			wantTotal: 1,
+		},
+		{
+			name:      "filtered by name prefix",
+			query:     model.UserQuery{SortKey: model.USER_SORT_KEY_NAME, NamePrefix: "al"},
+			wantIds:   []string{"3", "2"},
+			wantTotal: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sur := &SliceUserRepository{Storage: storage}
+			page, err := sur.FindByQuery(tt.query)
+			assert.Nil(t, err)
+
+			ids := []string{}
+			for _, user := range page.Users {
+				ids = append(ids, user.Id.V)
+			}
+			assert.Equal(t, tt.wantIds, ids,
+				fmt.Sprintf("SliceUserRepository.FindByQuery() = %v, want %v", ids, tt.wantIds))
+			assert.Equal(t, tt.wantTotal, page.Total)
+		})
+	}
+}
+
+// run with -race
+func TestSliceUserRepository_Concurrent(t *testing.T) {
+	const workers = 50
+	repo := NewSliceUserRepository()
+	factory := NewUserFactory(repo.Storage, model.NewDefaultUserNamePolicy())
+
+	var wg sync.WaitGroup
+	ids := make(chan string, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			name := model.UserName{V: fmt.Sprintf("user%d", i+10)}
+			user, err := factory.Create(&name)
+			assert.Nil(t, err)
+			assert.Nil(t, repo.Save(*user))
+			ids <- user.Id.V
+		}(i)
+		go func() {
+			defer wg.Done()
+			users, err := repo.FindAll()
+			assert.Nil(t, err)
+			for _, user := range *users {
+				_ = user.Name.V
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			_ = repo.Delete(model.User{Id: model.UserId{V: "1"}})
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[string]bool{}
+	for id := range ids {
+		assert.False(t, seen[id], fmt.Sprintf("id %s is assigned twice", id))
+		seen[id] = true
+	}
+	users, _ := repo.FindAll()
+	assert.Equal(t, workers+1, len(*users), "every saved user is kept and user 1 is deleted once")
+}
+
+func TestSliceUserRepository_Isolation(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(sur *SliceUserRepository)
+	}{
+		{
+			name: "users from FindAll",
+			mutate: func(sur *SliceUserRepository) {
+				users, _ := sur.FindAll()
+				(*users)[0].Name = model.UserName{V: "changed"}
+			},
+		},
+		{
+			name: "user from FindById",
+			mutate: func(sur *SliceUserRepository) {
+				user, _ := sur.FindById(&model.UserId{V: "1"})
+				user.Name = model.UserName{V: "changed"}
+			},
+		},
+		{
+			name: "user after Save",
+			mutate: func(sur *SliceUserRepository) {
+				user := model.User{Id: model.UserId{V: "1"}, Name: model.UserName{V: "user1"}, UType: model.USER_TYPE_NORMAL}
+				_ = sur.Save(user)
+				user.Name = model.UserName{V: "changed"}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sur := &SliceUserRepository{Storage: &TmpUserStorage{data: []model.User{
+				{Id: model.UserId{V: "1"}, Name: model.UserName{V: "user1"}, UType: model.USER_TYPE_NORMAL},
+			}}}
+			tt.mutate(sur)
+
+			user, _ := sur.FindById(&model.UserId{V: "1"})
+			assert.Equal(t, "user1", user.Name.V, fmt.Sprintf("changing the %s changed the storage", tt.name))
+		})
+	}
+}
+
+func TestSliceUserRepository_Stale(t *testing.T) {
+	tests := []struct {
+		name    string
+		write   func(sur *SliceUserRepository, user model.User) error
+		version int
+		wantErr error
+	}{
+		{name: "save the current version", write: saveUser, version: 1},
+		{name: "save a stale version", write: saveUser, version: 0, wantErr: model.ErrStale},
+		{name: "delete the current version", write: deleteUser, version: 1},
+		{name: "delete a stale version", write: deleteUser, version: 0, wantErr: model.ErrStale},
+		{name: "save a deleted user", write: saveUser, version: 5, wantErr: model.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sur := &SliceUserRepository{Storage: &TmpUserStorage{data: []model.User{
+				{Id: model.UserId{V: "1"}, Name: model.UserName{V: "user1"}, UType: model.USER_TYPE_NORMAL, Version: 1},
+			}}}
+			if tt.wantErr == model.ErrNotFound {
+				user, _ := sur.FindById(&model.UserId{V: "1"})
+				assert.Nil(t, sur.Delete(*user))
+			}
+
+			user := model.User{Id: model.UserId{V: "1"}, Name: model.UserName{V: "user1"}, UType: model.USER_TYPE_NORMAL, Version: tt.version}
+			err := tt.write(sur, user)
+			if tt.wantErr == nil {
+				assert.Nil(t, err, fmt.Sprintf("%s failed", tt.name))
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr, fmt.Sprintf("%s did not fail", tt.name))
+			}
+		})
+	}
+}
+
+func saveUser(sur *SliceUserRepository, user model.User) error {
+	if err := sur.Save(user); err != nil {
+		return err
+	}
+	saved, _ := sur.FindById(&user.Id)
+	if saved.Version != user.Version+1 {
+		return fmt.Errorf("saved version %d", saved.Version)
+	}
+	return nil
+}
+
+func deleteUser(sur *SliceUserRepository, user model.User) error {
+	return sur.Delete(user)
+}
@@ -0,0 +1,172 @@
+package application
+
+import (
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	// AdminApplicationService holds the use cases for admins
+	AdminApplicationService struct {
+		UserRepository      model.IUserRepository
+		PromoCodeRepository model.IPromoCodeRepository
+		Policy              model.AuthorizationPolicy
+		AuditLog            model.IAuditLog
+		UnitOfWork          model.IUnitOfWork
+	}
+)
+
+func NewAdminApplicationService(userRepository model.IUserRepository, promoCodeRepository model.IPromoCodeRepository, unitOfWork model.IUnitOfWork, auditLog model.IAuditLog) AdminApplicationService {
+	return AdminApplicationService{UserRepository: userRepository, PromoCodeRepository: promoCodeRepository, Policy: model.NewAuthorizationPolicy(), AuditLog: auditLog, UnitOfWork: unitOfWork}
+}
+
+func (aas *AdminApplicationService) Suspend(command UserSuspendCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+
+	return aas.UnitOfWork.Do(func() error {
+		user, err := aas.findUser(command.Id)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(aas.Policy.CanAdministerUser(command.Actor, model.ACTION_USER_SUSPEND, user), aas.AuditLog); err != nil {
+			return err
+		}
+
+		err = user.Suspend(command.Reason, time.Now())
+		if err != nil {
+			return err
+		}
+		return aas.UserRepository.Save(*user)
+	})
+}
+
+func (aas *AdminApplicationService) Reinstate(command UserReinstateCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+
+	return aas.UnitOfWork.Do(func() error {
+		user, err := aas.findUser(command.Id)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(aas.Policy.CanAdministerUser(command.Actor, model.ACTION_USER_REINSTATE, user), aas.AuditLog); err != nil {
+			return err
+		}
+
+		err = user.Reinstate(command.Reason, time.Now())
+		if err != nil {
+			return err
+		}
+		return aas.UserRepository.Save(*user)
+	})
+}
+
+func (aas *AdminApplicationService) Deactivate(command UserDeactivateCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+
+	return aas.UnitOfWork.Do(func() error {
+		user, err := aas.findUser(command.Id)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(aas.Policy.CanAdministerUser(command.Actor, model.ACTION_USER_DEACTIVATE, user), aas.AuditLog); err != nil {
+			return err
+		}
+
+		err = user.Deactivate(command.Reason, time.Now())
+		if err != nil {
+			return err
+		}
+		return aas.UserRepository.Save(*user)
+	})
+}
+
+// ListSuspended pages suspended users in the order of their ids
+func (aas *AdminApplicationService) ListSuspended(command UserListSuspendedCommand) (*UserGetAllResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+	if err := model.Enforce(aas.Policy.CanListUsersByStatus(command.Actor), aas.AuditLog); err != nil {
+		return nil, err
+	}
+
+	offset, err := decodeCursor(command.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit := command.Limit
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+	status := model.USER_STATUS_SUSPENDED
+	query := model.UserQuery{Limit: limit, Offset: offset, SortKey: model.USER_SORT_KEY_ID, Status: &status}
+	page, err := aas.UserRepository.FindByQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := UserGetAllResult{Users: page.Users, Total: page.Total}
+	if next := query.Offset + len(page.Users); next < page.Total {
+		result.NextCursor = encodeCursor(next)
+	}
+	return &result, nil
+}
+
+func (aas *AdminApplicationService) CreatePromoCode(command PromoCodeCreateCommand) (*PromoCodeCreateResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+	if err := model.Enforce(aas.Policy.CanCreatePromoCode(command.Actor), aas.AuditLog); err != nil {
+		return nil, err
+	}
+
+	var promoCode model.PromoCode
+	err := aas.UnitOfWork.Do(func() error {
+		code, err := model.NewPromoCodeValue(command.Code)
+		if err != nil {
+			return err
+		}
+		plan, err := model.NewUserType(command.Plan)
+		if err != nil {
+			return err
+		}
+		promoCode, err = model.NewPromoCode(code, plan, command.TrialDays, command.MaxRedemptions, command.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		duplicated, err := aas.PromoCodeRepository.FindByCode(&code)
+		if err != nil {
+			return err
+		}
+		if duplicated != nil {
+			return model.NewConflictError("promo code", "already exists")
+		}
+		return aas.PromoCodeRepository.Save(promoCode)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PromoCodeCreateResult{PromoCode: promoCode}, nil
+}
+
+func (aas *AdminApplicationService) findUser(v string) (*model.User, error) {
+	id, err := model.NewUserId(v)
+	if err != nil {
+		return nil, err
+	}
+	user, err := aas.UserRepository.FindById(&id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, model.NewNotFoundError("user", id.V)
+	}
+	return user, nil
+}
@@ -0,0 +1,358 @@
+package application
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	AuthApplicationService struct {
+		UserRepository       model.IUserRepository
+		CredentialRepository model.ICredentialRepository
+		SessionRepository    model.ISessionRepository
+		PasswordHasher       model.IPasswordHasher
+		AccessTokenVerifier  IAccessTokenVerifier
+		Policy               model.AuthorizationPolicy
+		AuditLog             model.IAuditLog
+		LockoutPolicy        model.LockoutPolicy
+		UserNamePolicy       model.UserNamePolicy
+		SessionTTL           time.Duration
+		UnitOfWork           model.IUnitOfWork
+	}
+
+	// port to verify self-contained access tokens such as JWTs.
+	// They are not accepted when AuthApplicationService has no verifier.
+	IAccessTokenVerifier interface {
+		Verify(token string, now time.Time) (model.UserId, error)
+	}
+)
+
+const defaultSessionTTL = 24 * time.Hour
+
+// the same message is used whichever of the name or the password is wrong
+const invalidLoginReason = "invalid name or password"
+
+func NewAuthApplicationService(userRepository model.IUserRepository, credentialRepository model.ICredentialRepository, sessionRepository model.ISessionRepository, passwordHasher model.IPasswordHasher, accessTokenVerifier IAccessTokenVerifier, unitOfWork model.IUnitOfWork, auditLog model.IAuditLog) AuthApplicationService {
+	return AuthApplicationService{
+		UserRepository:       userRepository,
+		CredentialRepository: credentialRepository,
+		SessionRepository:    sessionRepository,
+		PasswordHasher:       passwordHasher,
+		AccessTokenVerifier:  accessTokenVerifier,
+		Policy:               model.NewAuthorizationPolicy(),
+		AuditLog:             auditLog,
+		LockoutPolicy:        model.DefaultLockoutPolicy,
+		UserNamePolicy:       model.NewDefaultUserNamePolicy(),
+		SessionTTL:           defaultSessionTTL,
+		UnitOfWork:           unitOfWork,
+	}
+}
+
+// SetPassword sets the first password of a user or changes it.
+// Changing the password ends every session of the user.
+func (aas *AuthApplicationService) SetPassword(command UserSetPasswordCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+
+	id, err := model.NewUserId(command.Id)
+	if err != nil {
+		return err
+	}
+	user, err := aas.UserRepository.FindById(&id)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return model.NewNotFoundError("user", id.V)
+	}
+	if err := model.Enforce(aas.Policy.CanManageUser(command.Actor, model.ACTION_USER_SET_PASSWORD, user), aas.AuditLog); err != nil {
+		return err
+	}
+
+	// hashing is slow, so the passwords are hashed and verified before the unit of work
+	// and the credential is checked again in it
+	password, err := model.NewPassword(command.Password, user.Name)
+	if err != nil {
+		return err
+	}
+	hash, err := aas.PasswordHasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	verified, err := aas.CredentialRepository.FindByUserId(&user.Id)
+	if err != nil {
+		return err
+	}
+	ok := false
+	if verified != nil {
+		if len(command.CurrentPassword) == 0 {
+			return model.NewValidationError("currentPassword", "is required")
+		}
+		ok, err = aas.verify(verified, command.CurrentPassword, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	var refusal error
+	err = aas.UnitOfWork.Do(func() error {
+		user, err := aas.UserRepository.FindById(&id)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return model.NewNotFoundError("user", id.V)
+		}
+
+		var credential *model.Credential
+		if verified == nil {
+			found, err := aas.CredentialRepository.FindByUserId(&user.Id)
+			if err != nil {
+				return err
+			}
+			if found != nil {
+				return model.NewConflictError("credential", "the password was set by another request")
+			}
+			created, err := model.NewCredential(user.Id, hash, now)
+			if err != nil {
+				return err
+			}
+			credential = &created
+		} else {
+			credential, err = aas.recordAttempt(verified, ok, now)
+			if err != nil {
+				if isRefusal(err) {
+					refusal = err
+					return nil
+				}
+				return err
+			}
+			if err := credential.ChangePassword(hash, now); err != nil {
+				return err
+			}
+		}
+
+		err = aas.CredentialRepository.Save(*credential)
+		if err != nil {
+			return err
+		}
+		return aas.SessionRepository.DeleteByUserId(&user.Id)
+	})
+	if err != nil {
+		return err
+	}
+	return refusal
+}
+
+// Login checks the password and starts a session.
+// The returned token is shown only once; sessions keep its hash.
+func (aas *AuthApplicationService) Login(command UserLoginCommand) (*UserLoginResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	// the password is verified before the unit of work, as hashing is slow
+	_, verified, err := aas.findCredentialByName(command.Name)
+	if err != nil {
+		return nil, err
+	}
+	if verified == nil {
+		// hash anyway so that unknown names take as long as wrong passwords
+		if _, err := aas.PasswordHasher.Hash(model.Password{V: command.Password}); err != nil {
+			return nil, err
+		}
+		return nil, model.NewAuthenticationError(invalidLoginReason)
+	}
+	now := time.Now()
+	ok, err := aas.verify(verified, command.Password, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var token string
+	var session model.Session
+	var refusal error
+	err = aas.UnitOfWork.Do(func() error {
+		credential, err := aas.recordAttempt(verified, ok, now)
+		if err != nil {
+			if isRefusal(err) {
+				refusal = err
+				return nil
+			}
+			return err
+		}
+		user, err := aas.UserRepository.FindById(&credential.UserId)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return model.NewAuthenticationError(invalidLoginReason)
+		}
+		// checked after the password so that the status is not told to strangers
+		if err := model.Enforce(aas.Policy.CanLogIn(model.ANONYMOUS_ACTOR, user), aas.AuditLog); err != nil {
+			return err
+		}
+		credential.RecordSuccess()
+		err = aas.CredentialRepository.Save(*credential)
+		if err != nil {
+			return err
+		}
+
+		token, err = newToken()
+		if err != nil {
+			return err
+		}
+		session, err = model.NewSession(model.HashToken(token), credential.UserId, now, now.Add(aas.SessionTTL))
+		if err != nil {
+			return err
+		}
+		return aas.SessionRepository.Save(session)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if refusal != nil {
+		return nil, refusal
+	}
+
+	return &UserLoginResult{Token: token, UserId: session.UserId.V, ExpiresAt: session.ExpiresAt}, nil
+}
+
+func (aas *AuthApplicationService) Logout(command UserLogoutCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+
+	return aas.UnitOfWork.Do(func() error {
+		session, err := aas.SessionRepository.FindByToken(model.HashToken(command.Token))
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return model.NewAuthenticationError("session not found")
+		}
+		return aas.SessionRepository.Delete(*session)
+	})
+}
+
+// Authenticate resolves a bearer token to the actor of a request.
+// Tokens shaped like a JWT are checked by the access token verifier,
+// and others are looked up as session tokens.
+func (aas *AuthApplicationService) Authenticate(command UserAuthenticateCommand) (*UserAuthenticateResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var userId model.UserId
+	if aas.AccessTokenVerifier != nil && strings.Count(command.Token, ".") == 2 {
+		id, err := aas.AccessTokenVerifier.Verify(command.Token, now)
+		if err != nil {
+			return nil, err
+		}
+		userId = id
+	} else {
+		session, err := aas.SessionRepository.FindByToken(model.HashToken(command.Token))
+		if err != nil {
+			return nil, err
+		}
+		if session == nil {
+			return nil, model.NewAuthenticationError("invalid token")
+		}
+		if session.IsExpired(now) {
+			err := aas.UnitOfWork.Do(func() error {
+				return aas.SessionRepository.Delete(*session)
+			})
+			if err != nil {
+				return nil, err
+			}
+			return nil, model.NewAuthenticationError("token has expired")
+		}
+		userId = session.UserId
+	}
+
+	// tokens of deleted users are no longer valid
+	user, err := aas.UserRepository.FindById(&userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, model.NewAuthenticationError("invalid token")
+	}
+	if err := model.Enforce(aas.Policy.CanLogIn(model.ANONYMOUS_ACTOR, user), aas.AuditLog); err != nil {
+		return nil, err
+	}
+	actor, err := model.NewActor(user.Id, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &UserAuthenticateResult{Actor: actor}, nil
+}
+
+// verify checks the password against the credential. It is called outside of units of work,
+// and recordAttempt records its result.
+func (aas *AuthApplicationService) verify(credential *model.Credential, password string, now time.Time) (bool, error) {
+	if credential.IsLocked(now) {
+		return false, model.NewLockedError("account", credential.LockedUntil)
+	}
+	return aas.PasswordHasher.Verify(model.Password{V: password}, credential.PasswordHash)
+}
+
+// recordAttempt finds the credential which was verified again and records a failure,
+// locking it according to the lockout policy. A password changed since it was verified does not match.
+func (aas *AuthApplicationService) recordAttempt(verified *model.Credential, ok bool, now time.Time) (*model.Credential, error) {
+	credential, err := aas.CredentialRepository.FindByUserId(&verified.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil || credential.PasswordHash != verified.PasswordHash {
+		return nil, model.NewAuthenticationError(invalidLoginReason)
+	}
+	if credential.IsLocked(now) {
+		return nil, model.NewLockedError("account", credential.LockedUntil)
+	}
+	if ok {
+		return credential, nil
+	}
+
+	credential.RecordFailure(now, aas.LockoutPolicy)
+	err = aas.CredentialRepository.Save(*credential)
+	if err != nil {
+		return nil, err
+	}
+	if credential.IsLocked(now) {
+		return nil, model.NewLockedError("account", credential.LockedUntil)
+	}
+	return nil, model.NewAuthenticationError(invalidLoginReason)
+}
+
+// isRefusal tells the errors of recordAttempt which are committed with the unit of work,
+// as the failed attempts they record count towards lockouts
+func isRefusal(err error) bool {
+	return errors.Is(err, model.ErrUnauthenticated) || errors.Is(err, model.ErrLocked)
+}
+
+func (aas *AuthApplicationService) findCredentialByName(v string) (*model.User, *model.Credential, error) {
+	name, err := aas.UserNamePolicy.NewUserName(v)
+	if err != nil {
+		// names which can not be registered do not exist
+		return nil, nil, nil
+	}
+	user, err := aas.UserRepository.FindByName(&name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, nil
+	}
+	credential, err := aas.CredentialRepository.FindByUserId(&user.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, credential, nil
+}
@@ -0,0 +1,137 @@
+package application
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	// billing issues invoices for subscriptions and collects them through the payment gateway.
+	// Every invoice is charged to the ledger of the user, and settled by its payment or written off.
+	billing struct {
+		invoiceRepository model.IInvoiceRepository
+		invoiceFactory    model.IInvoiceFactory
+		ledgerRepository  model.ILedgerRepository
+		paymentGateway    IPaymentGateway
+	}
+
+	// payment is what the payment gateway answered for an invoice.
+	// id is empty when nothing was taken, and declined is set when the gateway refused the payment.
+	payment struct {
+		invoice  *model.Invoice
+		id       string
+		declined error
+	}
+)
+
+// issue bills the months of the plan. The invoice is saved by record, together with the result of its payment.
+// subscriptionId is empty when the payment starts a new subscription.
+func (b billing) issue(userId model.UserId, subscriptionId model.SubscriptionId, uType model.UserType, months int, retry bool, at time.Time) (*model.Invoice, error) {
+	plan, ok := model.DefaultPlanRegistry.Find(uType)
+	if !ok {
+		return nil, model.NewNotFoundError("plan", uType.V)
+	}
+	return b.invoiceFactory.Create(&userId, subscriptionId, &plan.Type, months, plan.Price(months), retry, at)
+}
+
+// pay takes the payment of the open invoice through the payment gateway.
+// It is called outside of units of work, which would otherwise be held up by the gateway,
+// and the payment is recorded in one afterwards. A declined payment is not an error.
+func (b billing) pay(invoice *model.Invoice) (payment, error) {
+	if invoice.Amount == 0 {
+		return payment{invoice: invoice}, nil
+	}
+	paymentId, err := b.paymentGateway.Charge(PaymentRequest{UserId: invoice.UserId, InvoiceId: invoice.Id, Amount: invoice.Amount})
+	if errors.Is(err, model.ErrPaymentDeclined) {
+		return payment{invoice: invoice, declined: err}, nil
+	}
+	if err != nil {
+		return payment{}, err
+	}
+	return payment{invoice: invoice, id: paymentId}, nil
+}
+
+// record saves the issued invoice, charges it to the ledger and settles it by its payment
+func (b billing) record(paid payment, at time.Time) error {
+	err := b.invoiceRepository.Save(*paid.invoice)
+	if err != nil {
+		return err
+	}
+	description := strconv.Itoa(paid.invoice.Months) + " months of " + paid.invoice.Plan.V
+	err = b.ledgerRepository.Save(model.NewLedgerEntry(*paid.invoice, model.LEDGER_ENTRY_KIND_CHARGE, description, at))
+	if err != nil {
+		return err
+	}
+	return b.settle(paid, at)
+}
+
+// settle records the payment of the open invoice.
+// Invoices which can be retried stay open when their payment is declined.
+func (b billing) settle(paid payment, at time.Time) error {
+	if paid.declined != nil {
+		return b.fail(paid.invoice, paid.declined, at)
+	}
+	err := paid.invoice.Pay(paid.id, at)
+	if err != nil {
+		return err
+	}
+	err = b.invoiceRepository.Save(*paid.invoice)
+	if err != nil {
+		return err
+	}
+	return b.ledgerRepository.Save(model.NewLedgerEntry(*paid.invoice, model.LEDGER_ENTRY_KIND_PAYMENT, "payment "+paid.id, at))
+}
+
+// link records the subscription which the paid invoice started
+func (b billing) link(invoice *model.Invoice, subscription *model.Subscription) error {
+	err := invoice.Link(subscription.Id)
+	if err != nil {
+		return err
+	}
+	return b.invoiceRepository.Save(*invoice)
+}
+
+// refund gives back the payments, whose unit of work failed to record them
+func (b billing) refund(payments []payment) error {
+	var first error
+	for _, paid := range payments {
+		if len(paid.id) == 0 {
+			continue
+		}
+		if err := b.paymentGateway.Refund(paid.id); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (b billing) fail(invoice *model.Invoice, declined error, at time.Time) error {
+	err := invoice.Fail(declined.Error(), at)
+	if err != nil {
+		return err
+	}
+	err = b.invoiceRepository.Save(*invoice)
+	if err != nil {
+		return err
+	}
+	if invoice.IsOpen() {
+		return nil
+	}
+	return b.ledgerRepository.Save(model.NewLedgerEntry(*invoice, model.LEDGER_ENTRY_KIND_WRITE_OFF, "payment failed", at))
+}
+
+// void closes the invoice without a payment and writes it off
+func (b billing) void(invoice *model.Invoice, at time.Time) error {
+	err := invoice.Void(at)
+	if err != nil {
+		return err
+	}
+	err = b.invoiceRepository.Save(*invoice)
+	if err != nil {
+		return err
+	}
+	return b.ledgerRepository.Save(model.NewLedgerEntry(*invoice, model.LEDGER_ENTRY_KIND_WRITE_OFF, "invoice voided", at))
+}
@@ -0,0 +1,81 @@
+package application
+
+import (
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	// BillingApplicationService shows users what they were billed and paid
+	BillingApplicationService struct {
+		UserRepository    model.IUserRepository
+		InvoiceRepository model.IInvoiceRepository
+		LedgerRepository  model.ILedgerRepository
+		Policy            model.AuthorizationPolicy
+		AuditLog          model.IAuditLog
+	}
+)
+
+func NewBillingApplicationService(userRepository model.IUserRepository, invoiceRepository model.IInvoiceRepository, ledgerRepository model.ILedgerRepository, auditLog model.IAuditLog) BillingApplicationService {
+	return BillingApplicationService{
+		UserRepository:    userRepository,
+		InvoiceRepository: invoiceRepository,
+		LedgerRepository:  ledgerRepository,
+		Policy:            model.NewAuthorizationPolicy(),
+		AuditLog:          auditLog,
+	}
+}
+
+// Invoices returns the invoices of the user, oldest first
+func (bas *BillingApplicationService) Invoices(command BillingGetCommand) (*InvoiceListResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	user, err := bas.findUser(command.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if err := model.Enforce(bas.Policy.CanViewPlan(command.Actor, model.ACTION_INVOICES_VIEW, user), bas.AuditLog); err != nil {
+		return nil, err
+	}
+	invoices, err := bas.InvoiceRepository.FindByUserId(&user.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &InvoiceListResult{Invoices: invoices}, nil
+}
+
+// Ledger returns the ledger of the user with its balance
+func (bas *BillingApplicationService) Ledger(command BillingGetCommand) (*LedgerResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	user, err := bas.findUser(command.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if err := model.Enforce(bas.Policy.CanViewPlan(command.Actor, model.ACTION_LEDGER_VIEW, user), bas.AuditLog); err != nil {
+		return nil, err
+	}
+	ledger, err := bas.LedgerRepository.FindByUserId(&user.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &LedgerResult{Ledger: *ledger}, nil
+}
+
+func (bas *BillingApplicationService) findUser(v string) (*model.User, error) {
+	id, err := model.NewUserId(v)
+	if err != nil {
+		return nil, err
+	}
+	user, err := bas.UserRepository.FindById(&id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, model.NewNotFoundError("user", id.V)
+	}
+	return user, nil
+}
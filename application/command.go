@@ -1,10 +1,16 @@
 package application
 
-import "uyutaka.com/ddd-bottom-up/model"
+import (
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
 
 type (
+	// Password is optional. It is set by AuthApplicationService once the user is registered
 	UserRegisterCommand struct {
-		Name string
+		Name     string
+		Password string
 	}
 
 	UserRegisterResult struct {
@@ -19,16 +25,331 @@ type (
 		User model.User
 	}
 
+	// Sort is "id" or "name", prefixed with "-" for descending order
+	UserGetAllCommand struct {
+		Limit      int
+		Cursor     string
+		Sort       string
+		Type       string
+		NamePrefix string
+	}
+
 	UserGetAllResult struct {
-		Users []model.User
+		Users      []model.User
+		Total      int
+		NextCursor string
 	}
 
+	// Version is the version of the user the client read. Nil changes any version.
 	UserUpdateCommand struct {
-		Id   string
-		Name string
+		Actor   model.Actor
+		Id      string
+		Name    string
+		Version *int
 	}
 
 	UserDeleteCommand struct {
+		Actor   model.Actor
+		Id      string
+		Version *int
+	}
+
+	UserChangeEmailCommand struct {
+		Actor model.Actor
+		Id    string
+		Email string
+	}
+
+	UserIssueEmailVerificationCommand struct {
+		Actor model.Actor
+		Id    string
+	}
+
+	UserVerifyEmailCommand struct {
+		Actor model.Actor
+		Id    string
+		Token string
+	}
+
+	// CurrentPassword is required once a password has been set
+	UserSetPasswordCommand struct {
+		Actor           model.Actor
+		Id              string
+		CurrentPassword string
+		Password        string
+	}
+
+	UserLoginCommand struct {
+		Name     string
+		Password string
+	}
+
+	UserLoginResult struct {
+		Token     string
+		UserId    string
+		ExpiresAt time.Time
+	}
+
+	UserLogoutCommand struct {
+		Token string
+	}
+
+	UserSuspendCommand struct {
+		Actor  model.Actor
+		Id     string
+		Reason string
+	}
+
+	UserReinstateCommand struct {
+		Actor  model.Actor
+		Id     string
+		Reason string
+	}
+
+	UserDeactivateCommand struct {
+		Actor  model.Actor
+		Id     string
+		Reason string
+	}
+
+	// Plan defaults to premium, and Reason defaults to who changed the plan
+	UserUpgradeCommand struct {
+		Actor  model.Actor
+		Id     string
+		Plan   string
+		Reason string
+	}
+
+	UserDowngradeCommand struct {
+		Actor  model.Actor
+		Id     string
+		Reason string
+	}
+
+	UserPlanChangedResult struct {
+		Change model.PlanChange
+	}
+
+	// the actor redeems the code for themself
+	UserRedeemPromoCodeCommand struct {
+		Actor model.Actor
+		Id    string
+		Code  string
+	}
+
+	UserRedeemPromoCodeResult struct {
+		Change model.PlanChange
+		Trial  model.Subscription
+	}
+
+	PromoCodeCreateCommand struct {
+		Actor          model.Actor
+		Code           string
+		Plan           string
+		TrialDays      int
+		MaxRedemptions int
+		ExpiresAt      time.Time
+	}
+
+	PromoCodeCreateResult struct {
+		PromoCode model.PromoCode
+	}
+
+	UserListSuspendedCommand struct {
+		Actor  model.Actor
+		Limit  int
+		Cursor string
+	}
+
+	// Token is a bearer token, either a JWT or a session token
+	UserAuthenticateCommand struct {
+		Token string
+	}
+
+	UserAuthenticateResult struct {
+		Actor model.Actor
+	}
+)
+
+type (
+	// the actor is the author
+	PostCreateCommand struct {
+		Actor    model.Actor
+		CircleId string
+		Body     string
+	}
+
+	PostCreateResult struct {
+		Id string
+	}
+
+	// the post has to belong to the circle
+	PostEditCommand struct {
+		Actor    model.Actor
+		CircleId string
+		PostId   string
+		Body     string
+	}
+
+	// the post has to belong to the circle
+	PostDeleteCommand struct {
+		Actor    model.Actor
+		CircleId string
+		PostId   string
+	}
+
+	// posts of private circles are listed to their members only
+	PostListCommand struct {
+		Actor    model.Actor
+		CircleId string
+	}
+
+	PostListResult struct {
+		Posts []model.Post
+	}
+)
+
+type (
+	// the actor is the organizer
+	EventScheduleCommand struct {
+		Actor    model.Actor
+		CircleId string
+		Title    string
+		Start    time.Time
+		End      time.Time
+		Location string
+		Capacity int
+	}
+
+	EventScheduleResult struct {
 		Id string
 	}
+
+	// the actor answers for themself
+	EventRsvpCommand struct {
+		Actor    model.Actor
+		CircleId string
+		EventId  string
+		Answer   string
+	}
+
+	EventRsvpResult struct {
+		Waitlisted bool
+	}
+
+	EventListCommand struct {
+		Actor    model.Actor
+		CircleId string
+	}
+
+	EventListResult struct {
+		Circle model.Circle
+		Events []model.Event
+	}
+)
+
+type (
+	// the actor subscribes for themself. Plan defaults to premium.
+	SubscriptionStartCommand struct {
+		Actor     model.Actor
+		UserId    string
+		Plan      string
+		Months    int
+		AutoRenew bool
+	}
+
+	SubscriptionRenewCommand struct {
+		Actor  model.Actor
+		UserId string
+		Months int
+	}
+
+	SubscriptionCancelCommand struct {
+		Actor  model.Actor
+		UserId string
+	}
+
+	SubscriptionGetCommand struct {
+		Actor  model.Actor
+		UserId string
+	}
+
+	SubscriptionResult struct {
+		Subscription model.Subscription
+	}
+
+	// Now is the time the job runs at
+	SubscriptionExpireCommand struct {
+		Now time.Time
+	}
+
+	SubscriptionExpireResult struct {
+		Renewed    []model.Subscription
+		Downgraded []model.PlanChange
+	}
+
+	// Now is the time the job runs at
+	SubscriptionRetryPaymentsCommand struct {
+		Now time.Time
+	}
+
+	// Failed invoices will not be retried again
+	SubscriptionRetryPaymentsResult struct {
+		Paid       []model.Invoice
+		Failed     []model.Invoice
+		Downgraded []model.PlanChange
+	}
+
+	PlanHistoryCommand struct {
+		Actor  model.Actor
+		UserId string
+	}
+
+	PlanHistoryResult struct {
+		Changes []model.PlanChange
+	}
+)
+
+type (
+	BillingGetCommand struct {
+		Actor  model.Actor
+		UserId string
+	}
+
+	InvoiceListResult struct {
+		Invoices []model.Invoice
+	}
+
+	LedgerResult struct {
+		Ledger model.Ledger
+	}
+)
+
+type (
+	EntitlementGetCommand struct {
+		Actor  model.Actor
+		UserId string
+	}
+
+	EntitlementGetResult struct {
+		User         model.User
+		Entitlements model.Entitlements
+		Overridden   bool
+	}
+
+	// nil limits are left as the plan gives them
+	EntitlementOverrideCommand struct {
+		Actor          model.Actor
+		UserId         string
+		MaxCircles     *int
+		CircleCapacity *int
+		Granted        []string
+		Revoked        []string
+		Reason         string
+	}
+
+	EntitlementClearOverrideCommand struct {
+		Actor  model.Actor
+		UserId string
+	}
 )
@@ -0,0 +1,128 @@
+package application
+
+import (
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	// EntitlementApplicationService shows users what their plan allows,
+	// and lets admins override it for a user
+	EntitlementApplicationService struct {
+		UserRepository     model.IUserRepository
+		OverrideRepository model.IEntitlementOverrideRepository
+		Entitlements       model.EntitlementService
+		Policy             model.AuthorizationPolicy
+		AuditLog           model.IAuditLog
+		UnitOfWork         model.IUnitOfWork
+	}
+)
+
+func NewEntitlementApplicationService(userRepository model.IUserRepository, overrideRepository model.IEntitlementOverrideRepository, entitlements model.EntitlementService, unitOfWork model.IUnitOfWork, auditLog model.IAuditLog) EntitlementApplicationService {
+	return EntitlementApplicationService{
+		UserRepository:     userRepository,
+		OverrideRepository: overrideRepository,
+		Entitlements:       entitlements,
+		Policy:             model.NewAuthorizationPolicy(),
+		AuditLog:           auditLog,
+		UnitOfWork:         unitOfWork,
+	}
+}
+
+// Get returns the effective entitlements of the user
+func (eas *EntitlementApplicationService) Get(command EntitlementGetCommand) (*EntitlementGetResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	user, err := eas.findUser(command.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if err := model.Enforce(eas.Policy.CanViewPlan(command.Actor, model.ACTION_ENTITLEMENTS_VIEW, user), eas.AuditLog); err != nil {
+		return nil, err
+	}
+	entitlements, err := eas.Entitlements.Of(user)
+	if err != nil {
+		return nil, err
+	}
+	override, err := eas.OverrideRepository.FindByUserId(&user.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &EntitlementGetResult{User: *user, Entitlements: entitlements, Overridden: override != nil}, nil
+}
+
+// Override replaces the override of the user
+func (eas *EntitlementApplicationService) Override(command EntitlementOverrideCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+
+	return eas.UnitOfWork.Do(func() error {
+		user, err := eas.findUser(command.UserId)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(eas.Policy.CanAdministerUser(command.Actor, model.ACTION_ENTITLEMENTS_OVERRIDE, user), eas.AuditLog); err != nil {
+			return err
+		}
+		granted, err := newFeatures(command.Granted)
+		if err != nil {
+			return err
+		}
+		revoked, err := newFeatures(command.Revoked)
+		if err != nil {
+			return err
+		}
+		override, err := model.NewEntitlementOverride(user.Id, command.MaxCircles, command.CircleCapacity, granted, revoked, command.Reason)
+		if err != nil {
+			return err
+		}
+		return eas.OverrideRepository.Save(override)
+	})
+}
+
+// ClearOverride gives the user the entitlements of their plan again
+func (eas *EntitlementApplicationService) ClearOverride(command EntitlementClearOverrideCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+
+	return eas.UnitOfWork.Do(func() error {
+		user, err := eas.findUser(command.UserId)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(eas.Policy.CanAdministerUser(command.Actor, model.ACTION_ENTITLEMENTS_OVERRIDE, user), eas.AuditLog); err != nil {
+			return err
+		}
+		return eas.OverrideRepository.Delete(&user.Id)
+	})
+}
+
+func (eas *EntitlementApplicationService) findUser(v string) (*model.User, error) {
+	id, err := model.NewUserId(v)
+	if err != nil {
+		return nil, err
+	}
+	user, err := eas.UserRepository.FindById(&id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, model.NewNotFoundError("user", id.V)
+	}
+	return user, nil
+}
+
+func newFeatures(vs []string) ([]model.Feature, error) {
+	features := []model.Feature{}
+	for _, v := range vs {
+		feature, err := model.NewFeature(v)
+		if err != nil {
+			return nil, err
+		}
+		features = append(features, feature)
+	}
+	return features, nil
+}
@@ -0,0 +1,186 @@
+package application
+
+import (
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	EventApplicationService struct {
+		EventFactory     model.IEventFactory
+		EventRepository  model.IEventRepository
+		CircleRepository model.ICircleRepository
+		UserRepository   model.IUserRepository
+		Entitlements     model.EntitlementService
+		Policy           model.AuthorizationPolicy
+		AuditLog         model.IAuditLog
+		UnitOfWork       model.IUnitOfWork
+	}
+)
+
+func NewEventApplicationService(eventFactory model.IEventFactory, eventRepository model.IEventRepository, circleRepository model.ICircleRepository, userRepository model.IUserRepository, entitlements model.EntitlementService, unitOfWork model.IUnitOfWork, auditLog model.IAuditLog) EventApplicationService {
+	return EventApplicationService{
+		EventFactory:     eventFactory,
+		EventRepository:  eventRepository,
+		CircleRepository: circleRepository,
+		UserRepository:   userRepository,
+		Entitlements:     entitlements,
+		Policy:           model.NewAuthorizationPolicy(),
+		AuditLog:         auditLog,
+		UnitOfWork:       unitOfWork,
+	}
+}
+
+func (eas *EventApplicationService) Schedule(command EventScheduleCommand) (*EventScheduleResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+	if err := command.Actor.Authenticated(); err != nil {
+		return nil, err
+	}
+
+	var event *model.Event
+	err := eas.UnitOfWork.Do(func() error {
+		circle, err := eas.findCircle(command.CircleId)
+		if err != nil {
+			return err
+		}
+
+		organizer, err := eas.findUser(command.Actor.UserId)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(eas.Policy.CanScheduleEvent(command.Actor, circle, organizer), eas.AuditLog); err != nil {
+			return err
+		}
+		// events are a feature of the plan of the owner of the circle
+		ownerId := circle.Owner()
+		owner, err := eas.UserRepository.FindById(&ownerId)
+		if err != nil {
+			return err
+		}
+		if owner != nil {
+			if err := eas.Entitlements.RequireFeature(owner, model.FEATURE_EVENTS); err != nil {
+				return err
+			}
+		}
+
+		title, err := model.NewEventTitle(command.Title)
+		if err != nil {
+			return err
+		}
+
+		circleId := circle.Id()
+		event, err = eas.EventFactory.Create(&circleId, &title, command.Start, command.End, command.Location, command.Capacity)
+		if err != nil {
+			return err
+		}
+		return eas.EventRepository.Save(*event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventScheduleResult{Id: event.Id.V}, nil
+}
+
+func (eas *EventApplicationService) Rsvp(command EventRsvpCommand) (*EventRsvpResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+	if err := command.Actor.Authenticated(); err != nil {
+		return nil, err
+	}
+
+	var event *model.Event
+	memberId := command.Actor.UserId
+	err := eas.UnitOfWork.Do(func() error {
+		eventId, err := model.NewEventId(command.EventId)
+		if err != nil {
+			return err
+		}
+		event, err = eas.EventRepository.FindById(&eventId)
+		if err != nil {
+			return err
+		}
+		if event == nil || event.CircleId.V != command.CircleId {
+			return model.NewNotFoundError("event", eventId.V)
+		}
+
+		circle, err := eas.findCircle(event.CircleId.V)
+		if err != nil {
+			return err
+		}
+
+		member, err := eas.findUser(memberId)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(eas.Policy.CanRespondToEvent(command.Actor, circle, event, member), eas.AuditLog); err != nil {
+			return err
+		}
+
+		answer, err := model.NewRsvpAnswer(command.Answer)
+		if err != nil {
+			return err
+		}
+		err = event.Respond(memberId, answer, time.Now())
+		if err != nil {
+			return err
+		}
+		return eas.EventRepository.Save(*event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	waitlisted := false
+	for _, id := range event.Waitlist() {
+		if id.V == memberId.V {
+			waitlisted = true
+		}
+	}
+	return &EventRsvpResult{Waitlisted: waitlisted}, nil
+}
+
+func (eas *EventApplicationService) List(command EventListCommand) (*EventListResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	circle, err := eas.findCircle(command.CircleId)
+	if err != nil {
+		return nil, err
+	}
+	// events of private circles are shown to their members only
+	if err := model.Enforce(eas.Policy.CanViewCircle(command.Actor, circle), eas.AuditLog); err != nil {
+		return nil, err
+	}
+
+	circleId := circle.Id()
+	events, err := eas.EventRepository.FindByCircle(&circleId)
+	if err != nil {
+		return nil, err
+	}
+	return &EventListResult{Circle: *circle, Events: events}, nil
+}
+
+func (eas *EventApplicationService) findCircle(v string) (*model.Circle, error) {
+	circleId, err := model.NewCircleId(v)
+	if err != nil {
+		return nil, err
+	}
+	return eas.CircleRepository.FindById(circleId)
+}
+
+func (eas *EventApplicationService) findUser(id model.UserId) (*model.User, error) {
+	user, err := eas.UserRepository.FindById(&id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, model.NewNotFoundError("user", id.V)
+	}
+	return user, nil
+}
@@ -0,0 +1,14 @@
+package application
+
+type (
+	Mail struct {
+		To      string
+		Subject string
+		Body    string
+	}
+
+	// port to send mails to users
+	IMailer interface {
+		Send(mail Mail) error
+	}
+)
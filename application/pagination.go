@@ -0,0 +1,79 @@
+package application
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// validateLimit accepts 0 as well, which asks for the default page size
+func validateLimit(limit int) error {
+	if limit < 0 || limit > maxPageLimit {
+		return model.NewValidationError("limit", "must be between 0 and "+strconv.Itoa(maxPageLimit)+", 0 for the default")
+	}
+	return nil
+}
+
+// cursors are opaque to clients so that the paging strategy can change later
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	if len(cursor) == 0 {
+		return 0, nil
+	}
+	invalid := model.NewValidationError("cursor", "is invalid")
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, invalid
+	}
+	v, ok := strings.CutPrefix(string(decoded), "offset:")
+	if !ok {
+		return 0, invalid
+	}
+	offset, err := strconv.Atoi(v)
+	if err != nil || offset < 0 {
+		return 0, invalid
+	}
+	return offset, nil
+}
+
+func (c UserGetAllCommand) query() (model.UserQuery, error) {
+	query := model.UserQuery{Limit: c.Limit, SortKey: model.USER_SORT_KEY_ID, NamePrefix: c.NamePrefix}
+	if query.Limit == 0 {
+		query.Limit = defaultPageLimit
+	}
+
+	offset, err := decodeCursor(c.Cursor)
+	if err != nil {
+		return model.UserQuery{}, err
+	}
+	query.Offset = offset
+
+	if len(c.Sort) != 0 {
+		key, descending := strings.CutPrefix(c.Sort, "-")
+		sortKey, err := model.NewUserSortKey(key)
+		if err != nil {
+			return model.UserQuery{}, err
+		}
+		query.SortKey = sortKey
+		query.Descending = descending
+	}
+
+	if len(c.Type) != 0 {
+		uType, err := model.NewUserType(c.Type)
+		if err != nil {
+			return model.UserQuery{}, err
+		}
+		query.UType = &uType
+	}
+	return query, nil
+}
@@ -0,0 +1,34 @@
+package application
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateLimit(t *testing.T) {
+	tests := []struct {
+		limit   int
+		wantErr string
+	}{
+		{limit: 0},
+		{limit: 1},
+		{limit: 100},
+		{limit: -1, wantErr: "invalid limit: must be between 0 and 100, 0 for the default"},
+		{limit: 101, wantErr: "invalid limit: must be between 0 and 100, 0 for the default"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.limit), func(t *testing.T) {
+			err := validateLimit(tt.limit)
+			if tt.wantErr == "" {
+				assert.Nil(t, err, fmt.Sprintf("limit %d was rejected", tt.limit))
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+	for _, command := range []interface{ Validate() error }{UserGetAllCommand{Limit: 101}, UserListSuspendedCommand{Limit: 101}} {
+		assert.ErrorContains(t, command.Validate(), "must be between 0 and 100", fmt.Sprintf("%T", command))
+	}
+}
@@ -0,0 +1,22 @@
+package application
+
+import (
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	// PaymentRequest asks for the amount of the invoice in yen
+	PaymentRequest struct {
+		UserId    model.UserId
+		InvoiceId model.InvoiceId
+		Amount    int
+	}
+
+	// port to take payments from users.
+	// Charge returns the id of the payment, or a PaymentDeclinedError when the payment was refused.
+	// Payments are not part of units of work, so Refund gives back the ones whose unit of work failed.
+	IPaymentGateway interface {
+		Charge(request PaymentRequest) (string, error)
+		Refund(paymentId string) error
+	}
+)
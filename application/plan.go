@@ -0,0 +1,43 @@
+package application
+
+import (
+	"log"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	// port for the features which react to changes of plans.
+	// Handlers are told after the unit of work of the change is committed, so they can not undo it.
+	// Their errors are logged, as the change has happened for the caller of the use case.
+	IPlanChangedHandler interface {
+		PlanChanged(change model.PlanChange) error
+	}
+
+	PlanChangedHandlerFunc func(change model.PlanChange) error
+)
+
+func (f PlanChangedHandlerFunc) PlanChanged(change model.PlanChange) error {
+	return f(change)
+}
+
+// recordPlanChange saves the user whose plan changed together with the change
+func recordPlanChange(userRepository model.IUserRepository, planChangeRepository model.IPlanChangeRepository, user *model.User, change *model.PlanChange) error {
+	err := userRepository.Save(*user)
+	if err != nil {
+		return err
+	}
+	return planChangeRepository.Save(*change)
+}
+
+// notifyPlanChanged tells every handler of the committed changes.
+// A failing handler is logged and does not keep the others from being told.
+func notifyPlanChanged(handlers []IPlanChangedHandler, changes ...model.PlanChange) {
+	for _, change := range changes {
+		for _, handler := range handlers {
+			if err := handler.PlanChanged(change); err != nil {
+				log.Printf("plan change of user %s to %s was not handled: %v", change.UserId.V, change.To.V, err)
+			}
+		}
+	}
+}
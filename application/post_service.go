@@ -0,0 +1,191 @@
+package application
+
+import (
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	PostApplicationService struct {
+		PostFactory      model.IPostFactory
+		PostRepository   model.IPostRepository
+		CircleRepository model.ICircleRepository
+		UserRepository   model.IUserRepository
+		Policy           model.AuthorizationPolicy
+		AuditLog         model.IAuditLog
+		UnitOfWork       model.IUnitOfWork
+	}
+)
+
+func NewPostApplicationService(postFactory model.IPostFactory, postRepository model.IPostRepository, circleRepository model.ICircleRepository, userRepository model.IUserRepository, unitOfWork model.IUnitOfWork, auditLog model.IAuditLog) PostApplicationService {
+	return PostApplicationService{PostFactory: postFactory, PostRepository: postRepository, CircleRepository: circleRepository, UserRepository: userRepository, Policy: model.NewAuthorizationPolicy(), AuditLog: auditLog, UnitOfWork: unitOfWork}
+}
+
+func (pas *PostApplicationService) Create(command PostCreateCommand) (*PostCreateResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+	if err := command.Actor.Authenticated(); err != nil {
+		return nil, err
+	}
+
+	var post *model.Post
+	err := pas.UnitOfWork.Do(func() error {
+		circle, err := pas.findCircle(command.CircleId)
+		if err != nil {
+			return err
+		}
+
+		author, err := pas.findUser(command.Actor.UserId)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(pas.Policy.CanCreatePost(command.Actor, circle, author), pas.AuditLog); err != nil {
+			return err
+		}
+
+		body, err := model.NewPostBody(command.Body)
+		if err != nil {
+			return err
+		}
+
+		circleId := circle.Id()
+		post, err = pas.PostFactory.Create(&circleId, &author.Id, &body, time.Now())
+		if err != nil {
+			return err
+		}
+		return pas.PostRepository.Save(*post)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostCreateResult{Id: post.Id.V}, nil
+}
+
+func (pas *PostApplicationService) Edit(command PostEditCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+	if err := command.Actor.Authenticated(); err != nil {
+		return err
+	}
+
+	return pas.UnitOfWork.Do(func() error {
+		post, err := pas.findPost(command.CircleId, command.PostId)
+		if err != nil {
+			return err
+		}
+
+		circle, err := pas.findCircle(post.CircleId.V)
+		if err != nil {
+			return err
+		}
+
+		editor, err := pas.findUser(command.Actor.UserId)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(pas.Policy.CanEditPost(command.Actor, circle, post, editor), pas.AuditLog); err != nil {
+			return err
+		}
+
+		body, err := model.NewPostBody(command.Body)
+		if err != nil {
+			return err
+		}
+		err = post.Edit(editor.Id, &body, time.Now())
+		if err != nil {
+			return err
+		}
+		return pas.PostRepository.Save(*post)
+	})
+}
+
+func (pas *PostApplicationService) Delete(command PostDeleteCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+	if err := command.Actor.Authenticated(); err != nil {
+		return err
+	}
+
+	return pas.UnitOfWork.Do(func() error {
+		post, err := pas.findPost(command.CircleId, command.PostId)
+		if err != nil {
+			return err
+		}
+
+		circle, err := pas.findCircle(post.CircleId.V)
+		if err != nil {
+			return err
+		}
+
+		deleter, err := pas.findUser(command.Actor.UserId)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(pas.Policy.CanDeletePost(command.Actor, circle, post, deleter), pas.AuditLog); err != nil {
+			return err
+		}
+
+		return pas.PostRepository.Delete(*post)
+	})
+}
+
+func (pas *PostApplicationService) List(command PostListCommand) (*PostListResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	circle, err := pas.findCircle(command.CircleId)
+	if err != nil {
+		return nil, err
+	}
+	if err := model.Enforce(pas.Policy.CanViewCircle(command.Actor, circle), pas.AuditLog); err != nil {
+		return nil, err
+	}
+
+	circleId := circle.Id()
+	posts, err := pas.PostRepository.FindByCircle(&circleId)
+	if err != nil {
+		return nil, err
+	}
+	return &PostListResult{Posts: posts}, nil
+}
+
+func (pas *PostApplicationService) findCircle(v string) (*model.Circle, error) {
+	circleId, err := model.NewCircleId(v)
+	if err != nil {
+		return nil, err
+	}
+	return pas.CircleRepository.FindById(circleId)
+}
+
+func (pas *PostApplicationService) findUser(id model.UserId) (*model.User, error) {
+	user, err := pas.UserRepository.FindById(&id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, model.NewNotFoundError("user", id.V)
+	}
+	return user, nil
+}
+
+// findPost finds the post in the circle, so that posts of other circles are not found
+func (pas *PostApplicationService) findPost(circleId string, v string) (*model.Post, error) {
+	postId, err := model.NewPostId(v)
+	if err != nil {
+		return nil, err
+	}
+	post, err := pas.PostRepository.FindById(&postId)
+	if err != nil {
+		return nil, err
+	}
+	if post == nil || post.CircleId.V != circleId {
+		return nil, model.NewNotFoundError("post", postId.V)
+	}
+	return post, nil
+}
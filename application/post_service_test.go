@@ -0,0 +1,125 @@
+package application_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	inMemoryInfrastructure "uyutaka.com/ddd-bottom-up/InMemoryInfrastructure"
+	"uyutaka.com/ddd-bottom-up/application"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+// circle1 is owned by user1, user2 is its member and user3 is not
+func setUpPostApplicationService(t *testing.T) (application.PostApplicationService, model.CircleApplicationService) {
+	userRepository := inMemoryInfrastructure.NewSliceUserRepository()
+	assert.Nil(t, userRepository.Save(model.User{Id: model.UserId{V: "3"}, Name: model.UserName{V: "user3"}, UType: model.USER_TYPE_NORMAL, Role: model.USER_ROLE_MEMBER, Status: model.UserStatusRecord{Status: model.USER_STATUS_ACTIVE}}))
+	circleRepository := inMemoryInfrastructure.NewSliceCircleRepository()
+	circleFactory := inMemoryInfrastructure.NewCircleFactory(circleRepository.Storage)
+	overrideRepository := inMemoryInfrastructure.NewSliceEntitlementOverrideRepository()
+	postRepository := inMemoryInfrastructure.NewSlicePostRepository()
+	postFactory := inMemoryInfrastructure.NewPostFactory(postRepository.Storage)
+	unitOfWork := inMemoryInfrastructure.NewInMemoryUnitOfWork(userRepository.Storage, circleRepository.Storage, overrideRepository.Storage, postRepository.Storage)
+	auditLog := inMemoryInfrastructure.NewWriterAuditLog(io.Discard)
+
+	entitlements := model.NewEntitlementService(model.DefaultPlanRegistry, &overrideRepository)
+	circleApplicationService := model.NewCircleApplicationService(&circleFactory, &circleRepository, model.NewCircleService(&circleRepository), &userRepository, entitlements, unitOfWork, auditLog, time.Now())
+	postApplicationService := application.NewPostApplicationService(&postFactory, &postRepository, &circleRepository, &userRepository, unitOfWork, auditLog)
+	return postApplicationService, circleApplicationService
+}
+
+func actorOf(id string) model.Actor {
+	actor, _ := model.NewActor(model.UserId{V: id}, model.USER_ROLE_MEMBER)
+	return actor
+}
+
+func TestPostApplicationService_Create(t *testing.T) {
+	tests := []struct {
+		name    string
+		actor   model.Actor
+		wantErr error
+	}{
+		{name: "owner", actor: actorOf("1")},
+		{name: "member", actor: actorOf("2")},
+		{name: "not a member", actor: actorOf("3"), wantErr: model.ErrPermission},
+		{name: "anonymous", actor: model.ANONYMOUS_ACTOR, wantErr: model.ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pas, _ := setUpPostApplicationService(t)
+
+			_, err := pas.Create(application.PostCreateCommand{Actor: tt.actor, CircleId: "1", Body: "hello"})
+			if tt.wantErr == nil {
+				assert.Nil(t, err, fmt.Sprintf("%s could not post", tt.name))
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr, fmt.Sprintf("%s could post", tt.name))
+			}
+
+			result, err := pas.List(application.PostListCommand{Actor: actorOf("1"), CircleId: "1"})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantErr == nil, len(result.Posts) == 1)
+		})
+	}
+}
+
+func TestPostApplicationService_Delete(t *testing.T) {
+	tests := []struct {
+		name      string
+		moderator string
+		actor     model.Actor
+		wantErr   error
+	}{
+		{name: "author", actor: actorOf("2")},
+		{name: "owner", actor: actorOf("1")},
+		{name: "moderator", moderator: "3", actor: actorOf("3")},
+		{name: "member", actor: actorOf("3"), wantErr: model.ErrPermission},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pas, cas := setUpPostApplicationService(t)
+			assert.Nil(t, cas.Join(model.NewCircleJoinCommand(actorOf("3"), "1", nil)))
+			if tt.moderator != "" {
+				assert.Nil(t, cas.AppointModerator(model.NewCircleAppointModeratorCommand(actorOf("1"), "1", tt.moderator)))
+			}
+			post, err := pas.Create(application.PostCreateCommand{Actor: actorOf("2"), CircleId: "1", Body: "hello"})
+			assert.Nil(t, err)
+
+			err = pas.Delete(application.PostDeleteCommand{Actor: tt.actor, CircleId: "1", PostId: post.Id})
+			if tt.wantErr == nil {
+				assert.Nil(t, err, fmt.Sprintf("%s could not delete the post", tt.name))
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr, fmt.Sprintf("%s could delete the post", tt.name))
+			}
+
+			result, err := pas.List(application.PostListCommand{Actor: actorOf("1"), CircleId: "1"})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantErr != nil, len(result.Posts) == 1)
+		})
+	}
+}
+
+func TestPostApplicationService_DeleteInAnotherCircle(t *testing.T) {
+	pas, _ := setUpPostApplicationService(t)
+	post, err := pas.Create(application.PostCreateCommand{Actor: actorOf("2"), CircleId: "1", Body: "hello"})
+	assert.Nil(t, err)
+
+	err = pas.Delete(application.PostDeleteCommand{Actor: actorOf("2"), CircleId: "2", PostId: post.Id})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestPostApplicationService_DenialsAreAudited(t *testing.T) {
+	pas, _ := setUpPostApplicationService(t)
+	var audit bytes.Buffer
+	pas.AuditLog = inMemoryInfrastructure.NewWriterAuditLog(&audit)
+	member, _ := pas.UserRepository.FindById(&model.UserId{V: "2"})
+	assert.Nil(t, member.Suspend("spam", time.Now()))
+	assert.Nil(t, pas.UserRepository.Save(*member))
+
+	_, err := pas.Create(application.PostCreateCommand{Actor: actorOf("2"), CircleId: "1", Body: "hello"})
+	assert.ErrorIs(t, err, model.ErrPermission, "a suspended member could post")
+	assert.Contains(t, audit.String(), "create post")
+	assert.Contains(t, audit.String(), "user is suspended")
+}
@@ -0,0 +1,45 @@
+package application
+
+import (
+	"errors"
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+// DefaultRetryPolicy runs a use case at most 3 times
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Wait: 10 * time.Millisecond}
+
+type (
+	// RetryPolicy decides how many times a use case runs when its writes are stale,
+	// and how long it waits before the next attempt. The wait doubles after every attempt.
+	RetryPolicy struct {
+		Attempts int
+		Wait     time.Duration
+	}
+
+	// RetryingUnitOfWork runs the work again in a new unit of work when it failed with a stale error,
+	// so that it reads what the other writer saved and decides again. It waits between the units,
+	// so the wait never holds up the units of work of others.
+	// Work which takes effect outside the unit of work, such as payments, must not be retried.
+	RetryingUnitOfWork struct {
+		unitOfWork model.IUnitOfWork
+		policy     RetryPolicy
+	}
+)
+
+func NewRetryingUnitOfWork(unitOfWork model.IUnitOfWork, policy RetryPolicy) RetryingUnitOfWork {
+	return RetryingUnitOfWork{unitOfWork: unitOfWork, policy: policy}
+}
+
+func (ruow RetryingUnitOfWork) Do(work func() error) error {
+	wait := ruow.policy.Wait
+	for attempt := 1; ; attempt++ {
+		err := ruow.unitOfWork.Do(work)
+		if !errors.Is(err, model.ErrStale) || attempt >= ruow.policy.Attempts {
+			return err
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
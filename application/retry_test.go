@@ -0,0 +1,101 @@
+package application_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	inMemoryInfrastructure "uyutaka.com/ddd-bottom-up/InMemoryInfrastructure"
+	"uyutaka.com/ddd-bottom-up/application"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+// lockingUnitOfWork runs one unit at a time without rolling back, so that the writes of another writer stay
+type lockingUnitOfWork struct {
+	mu sync.Mutex
+}
+
+func (u *lockingUnitOfWork) Do(work func() error) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return work()
+}
+
+func TestRetryingUnitOfWork_Do(t *testing.T) {
+	tests := []struct {
+		name      string
+		attempts  int
+		missing   bool
+		wantErr   error
+		wantRuns  int
+		wantNames string
+	}{
+		{name: "give up", attempts: 1, wantErr: model.ErrStale, wantRuns: 1, wantNames: "other"},
+		{name: "retry", attempts: 3, wantRuns: 2, wantNames: "other renamed"},
+		{name: "not stale", attempts: 3, missing: true, wantErr: model.ErrNotFound, wantRuns: 1, wantNames: "user1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepository := inMemoryInfrastructure.NewSliceUserRepository()
+			uow := application.NewRetryingUnitOfWork(&lockingUnitOfWork{}, application.RetryPolicy{Attempts: tt.attempts})
+
+			runs := 0
+			err := uow.Do(func() error {
+				runs++
+				if tt.missing {
+					return model.NewNotFoundError("user", "3")
+				}
+				user, _ := userRepository.FindById(&model.UserId{V: "1"})
+				if runs == 1 {
+					// another writer saves the user after it was read
+					other := *user
+					other.Name = model.UserName{V: "other"}
+					if err := userRepository.Save(other); err != nil {
+						return err
+					}
+				}
+				user.Name = model.UserName{V: user.Name.V + " renamed"}
+				return userRepository.Save(*user)
+			})
+
+			if tt.wantErr == nil {
+				assert.Nil(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			assert.Equal(t, tt.wantRuns, runs, fmt.Sprintf("runs to %s", tt.name))
+			user, _ := userRepository.FindById(&model.UserId{V: "1"})
+			assert.Equal(t, tt.wantNames, user.Name.V)
+		})
+	}
+}
+
+func TestRetryingUnitOfWork_WaitsOutsideUnitOfWork(t *testing.T) {
+	inner := inMemoryInfrastructure.NewInMemoryUnitOfWork()
+	uow := application.NewRetryingUnitOfWork(inner, application.RetryPolicy{Attempts: 2, Wait: 100 * time.Millisecond})
+
+	other := make(chan struct{})
+	runs := 0
+	err := uow.Do(func() error {
+		runs++
+		if runs == 1 {
+			go func() {
+				_ = inner.Do(func() error {
+					close(other)
+					return nil
+				})
+			}()
+			return model.NewStaleError("user", "1", 2)
+		}
+		select {
+		case <-other:
+			return nil
+		default:
+			return fmt.Errorf("the other unit of work waited for the retry")
+		}
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, runs)
+}
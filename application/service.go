@@ -1,90 +1,485 @@
 package application
 
 import (
-	"errors"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
 
 	"uyutaka.com/ddd-bottom-up/model"
 )
 
 type (
 	UserApplicationService struct {
-		UserService    model.UserService
-		UserFactory    model.IUserFactory
-		UserRepository model.IUserRepository
+		UserService          model.UserService
+		UserFactory          model.IUserFactory
+		UserRepository       model.IUserRepository
+		Mailer               IMailer
+		Policy               model.AuthorizationPolicy
+		AuditLog             model.IAuditLog
+		PlanChangeRepository model.IPlanChangeRepository
+		PlanChangedHandlers  []IPlanChangedHandler
+		// trials of promo codes are kept as subscriptions
+		PromoCodeRepository    model.IPromoCodeRepository
+		SubscriptionRepository model.ISubscriptionRepository
+		SubscriptionFactory    model.ISubscriptionFactory
+		// passwords given on registration are kept as credentials.
+		// Registrations with a password are refused while they are nil.
+		CredentialRepository model.ICredentialRepository
+		PasswordHasher       model.IPasswordHasher
+		UnitOfWork           model.IUnitOfWork
 	}
 )
 
-func NewUserApplicationService(userService model.UserService, userFactory model.IUserFactory, userRepository model.IUserRepository) UserApplicationService {
-	return UserApplicationService{UserService: userService, UserFactory: userFactory, UserRepository: userRepository}
+const emailVerificationTTL = 24 * time.Hour
+
+func NewUserApplicationService(userService model.UserService, userFactory model.IUserFactory, userRepository model.IUserRepository, planChangeRepository model.IPlanChangeRepository, promoCodeRepository model.IPromoCodeRepository, subscriptionRepository model.ISubscriptionRepository, subscriptionFactory model.ISubscriptionFactory, mailer IMailer, unitOfWork model.IUnitOfWork, auditLog model.IAuditLog) UserApplicationService {
+	return UserApplicationService{
+		UserService:            userService,
+		UserFactory:            userFactory,
+		UserRepository:         userRepository,
+		Mailer:                 mailer,
+		Policy:                 model.NewAuthorizationPolicy(),
+		AuditLog:               auditLog,
+		PlanChangeRepository:   planChangeRepository,
+		PromoCodeRepository:    promoCodeRepository,
+		SubscriptionRepository: subscriptionRepository,
+		SubscriptionFactory:    subscriptionFactory,
+		UnitOfWork:             unitOfWork,
+	}
 }
 
 func (uas *UserApplicationService) Get(command UserGetCommand) (*UserGetResult, error) {
-	id, _ := model.NewUserId(command.UserId)
-	user, _ := uas.UserRepository.FindById(&id)
-	if user == nil {
-		return nil, errors.New("user not found")
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	user, err := uas.findUser(command.UserId)
+	if err != nil {
+		return nil, err
 	}
 	result := UserGetResult{User: *user}
 	return &result, nil
 }
 
-func (uas *UserApplicationService) GetAll() (*UserGetAllResult, error) {
-	users, errors := uas.UserRepository.FindAll()
-	if errors != nil {
-		return nil, errors
+func (uas *UserApplicationService) GetAll(command UserGetAllCommand) (*UserGetAllResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	query, err := command.query()
+	if err != nil {
+		return nil, err
+	}
+	page, err := uas.UserRepository.FindByQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := UserGetAllResult{Users: page.Users, Total: page.Total}
+	if next := query.Offset + len(page.Users); next < page.Total {
+		result.NextCursor = encodeCursor(next)
 	}
-	result := UserGetAllResult{Users: *users}
 	return &result, nil
 }
 
 func (uas *UserApplicationService) Register(command UserRegisterCommand) (*UserRegisterResult, error) {
+	errs := model.NewValidationErrors()
+	userName, err := uas.UserService.NewUserName(command.Name)
+	errs.Add("name", err)
+	errs.Add("", command.Validate())
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
 
-	// starts tx
-	userName, _ := model.NewUserName(command.Name)
-	user, _ := uas.UserFactory.Create(&userName)
-	if uas.UserRepository.Exists(*user) {
-		return nil, errors.New("user already exists")
+	// the password is hashed before the unit of work, as hashing is slow on purpose
+	var hash string
+	if len(command.Password) != 0 {
+		if uas.CredentialRepository == nil || uas.PasswordHasher == nil {
+			return nil, model.NewValidationError("password", "can not be set on registration")
+		}
+		password, err := model.NewPassword(command.Password, userName)
+		if err != nil {
+			return nil, err
+		}
+		hash, err = uas.PasswordHasher.Hash(password)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	uas.UserRepository.Save(*user)
-	// ends tx
+	var user *model.User
+	err = uas.UnitOfWork.Do(func() (err error) {
+		user, err = uas.UserFactory.Create(&userName)
+		if err != nil {
+			return err
+		}
+		exists, err := uas.UserService.Exists(user)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return model.NewDuplicateUserNameError(user.Name.V)
+		}
+
+		if err := uas.UserRepository.Save(*user); err != nil {
+			return err
+		}
+		if len(hash) == 0 {
+			return nil
+		}
+		// the user and their credential are saved together, so that no user is left without the password they asked for
+		credential, err := model.NewCredential(user.Id, hash, time.Now())
+		if err != nil {
+			return err
+		}
+		return uas.CredentialRepository.Save(credential)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	return &UserRegisterResult{Id: user.Id.V}, nil
 }
 
 func (uas *UserApplicationService) Update(command UserUpdateCommand) error {
-	// starts tx
-	id, _ := model.NewUserId(command.Id)
-	user, _ := uas.UserRepository.FindById(&id)
-	if user == nil {
-		return errors.New("user not found")
+	errs := model.NewValidationErrors()
+	errs.Add("", command.Validate())
+	name, err := uas.UserService.NewUserName(command.Name)
+	errs.Add("name", err)
+	if err := errs.Err(); err != nil {
+		return err
 	}
 
-	if len(command.Name) != 0 {
-		name, _ := model.NewUserName(command.Name)
-		user.ChangeName(&name)
-	} else {
-		return errors.New("name is empty")
-	}
-	uas.UserRepository.Save(*user)
+	return uas.UnitOfWork.Do(func() error {
+		user, err := uas.findUser(command.Id)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(uas.Policy.CanManageUser(command.Actor, model.ACTION_USER_UPDATE, user), uas.AuditLog); err != nil {
+			return err
+		}
+		if err := user.RequireVersion(command.Version); err != nil {
+			return err
+		}
 
-	// ends tx
-	return nil
+		err = user.ChangeName(&name)
+		if err != nil {
+			return err
+		}
+		exists, err := uas.UserService.Exists(user)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return model.NewDuplicateUserNameError(user.Name.V)
+		}
+		return uas.UserRepository.Save(*user)
+	})
 }
 
 func (uas *UserApplicationService) Delete(command UserDeleteCommand) error {
-	// starts tx
-	id, _ := model.NewUserId(command.Id)
-	user, _ := uas.UserRepository.FindById(&id)
-	if user == nil {
-		return errors.New("user not found")
+	if err := command.Validate(); err != nil {
+		return err
 	}
 
-	err := uas.UserRepository.Delete(*user)
+	return uas.UnitOfWork.Do(func() error {
+		user, err := uas.findUser(command.Id)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(uas.Policy.CanManageUser(command.Actor, model.ACTION_USER_DELETE, user), uas.AuditLog); err != nil {
+			return err
+		}
+		if err := user.RequireVersion(command.Version); err != nil {
+			return err
+		}
+
+		return uas.UserRepository.Delete(*user)
+	})
+}
+
+func (uas *UserApplicationService) ChangeEmail(command UserChangeEmailCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+
+	return uas.UnitOfWork.Do(func() error {
+		user, err := uas.findUser(command.Id)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(uas.Policy.CanManageUser(command.Actor, model.ACTION_USER_CHANGE_EMAIL, user), uas.AuditLog); err != nil {
+			return err
+		}
+
+		email, err := model.NewEmail(command.Email)
+		if err != nil {
+			return err
+		}
+		err = user.ChangeEmail(&email)
+		if err != nil {
+			return err
+		}
+		exists, err := uas.UserService.EmailExists(user)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return model.NewConflictError("email", "is already registered")
+		}
+		return uas.UserRepository.Save(*user)
+	})
+}
+
+// IssueEmailVerification mails a token which proves the ownership of the address
+func (uas *UserApplicationService) IssueEmailVerification(command UserIssueEmailVerificationCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+
+	var user *model.User
+	var token string
+	err := uas.UnitOfWork.Do(func() (err error) {
+		user, err = uas.findUser(command.Id)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(uas.Policy.CanManageUser(command.Actor, model.ACTION_USER_VERIFY_EMAIL, user), uas.AuditLog); err != nil {
+			return err
+		}
+
+		token, err = newToken()
+		if err != nil {
+			return err
+		}
+		err = user.IssueEmailVerification(model.HashToken(token), time.Now().Add(emailVerificationTTL))
+		if err != nil {
+			return err
+		}
+		return uas.UserRepository.Save(*user)
+	})
 	if err != nil {
-		return errors.New("could not delete user")
+		return err
 	}
-	// ends tx
 
-	return nil
+	return uas.Mailer.Send(Mail{
+		To:      user.Email.V,
+		Subject: "Verify your email address",
+		Body:    "Hello " + user.Name.V + ",\r\n\r\nYour verification token is " + token + "\r\nIt expires in 24 hours.",
+	})
+}
+
+func (uas *UserApplicationService) VerifyEmail(command UserVerifyEmailCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+
+	return uas.UnitOfWork.Do(func() error {
+		user, err := uas.findUser(command.Id)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(uas.Policy.CanManageUser(command.Actor, model.ACTION_USER_VERIFY_EMAIL, user), uas.AuditLog); err != nil {
+			return err
+		}
+
+		err = user.VerifyEmail(command.Token, time.Now())
+		if err != nil {
+			return err
+		}
+		return uas.UserRepository.Save(*user)
+	})
+}
+
+// Upgrade puts the user on a premium plan by hand, outside of subscriptions
+func (uas *UserApplicationService) Upgrade(command UserUpgradeCommand) (*UserPlanChangedResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	var change *model.PlanChange
+	err := uas.UnitOfWork.Do(func() error {
+		user, err := uas.findUser(command.Id)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(uas.Policy.CanAdministerUser(command.Actor, model.ACTION_USER_UPGRADE, user), uas.AuditLog); err != nil {
+			return err
+		}
+		plan := model.USER_TYPE_PREMIUM
+		if len(command.Plan) != 0 {
+			plan, err = model.NewUserType(command.Plan)
+			if err != nil {
+				return err
+			}
+		}
+		change, err = user.Upgrade(plan, planChangeReason(command.Reason, "upgraded by user "+command.Actor.UserId.V), time.Now())
+		if err != nil {
+			return err
+		}
+		return recordPlanChange(uas.UserRepository, uas.PlanChangeRepository, user, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+	notifyPlanChanged(uas.PlanChangedHandlers, *change)
+
+	return &UserPlanChangedResult{Change: *change}, nil
+}
+
+// Downgrade makes the user normal by hand. Their subscription stops renewing,
+// so that they are not charged and upgraded again at its end.
+func (uas *UserApplicationService) Downgrade(command UserDowngradeCommand) (*UserPlanChangedResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	var change *model.PlanChange
+	err := uas.UnitOfWork.Do(func() error {
+		user, err := uas.findUser(command.Id)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(uas.Policy.CanAdministerUser(command.Actor, model.ACTION_USER_DOWNGRADE, user), uas.AuditLog); err != nil {
+			return err
+		}
+		now := time.Now()
+		change, err = user.DownGrade(planChangeReason(command.Reason, "downgraded by user "+command.Actor.UserId.V), now)
+		if err != nil {
+			return err
+		}
+		if err := uas.stopRenewing(user, now); err != nil {
+			return err
+		}
+		return recordPlanChange(uas.UserRepository, uas.PlanChangeRepository, user, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+	notifyPlanChanged(uas.PlanChangedHandlers, *change)
+
+	return &UserPlanChangedResult{Change: *change}, nil
+}
+
+// stopRenewing cancels the auto-renewing subscription of the user,
+// or only turns off its auto-renew when its period is over and it waits for the scheduler
+func (uas *UserApplicationService) stopRenewing(user *model.User, now time.Time) error {
+	subscription, err := uas.SubscriptionRepository.FindByUserId(&user.Id)
+	if err != nil {
+		return err
+	}
+	if subscription == nil || !subscription.AutoRenew || !subscription.Expired.IsZero() {
+		return nil
+	}
+	if subscription.IsActive(now) {
+		if err := subscription.Cancel(now); err != nil {
+			return err
+		}
+	} else {
+		subscription.StopRenewing()
+	}
+	return uas.SubscriptionRepository.Save(*subscription)
+}
+
+// RedeemPromoCode upgrades the user to the plan of the code for its trial.
+// The trial expires like subscriptions do, which downgrades the user at its end.
+func (uas *UserApplicationService) RedeemPromoCode(command UserRedeemPromoCodeCommand) (*UserRedeemPromoCodeResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	var change *model.PlanChange
+	var trial *model.Subscription
+	err := uas.UnitOfWork.Do(func() error {
+		user, err := uas.findUser(command.Id)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(uas.Policy.CanManageUser(command.Actor, model.ACTION_PROMO_CODE_REDEEM, user), uas.AuditLog); err != nil {
+			return err
+		}
+		code, err := model.NewPromoCodeValue(command.Code)
+		if err != nil {
+			return err
+		}
+		promoCode, err := uas.PromoCodeRepository.FindByCode(&code)
+		if err != nil {
+			return err
+		}
+		if promoCode == nil {
+			return model.NewNotFoundError("promo code", code.V)
+		}
+
+		now := time.Now()
+		current, err := uas.SubscriptionRepository.FindByUserId(&user.Id)
+		if err != nil {
+			return err
+		}
+		if current != nil && current.IsActive(now) {
+			return model.NewConflictError("subscription", "is already active")
+		}
+		err = promoCode.Redeem(user.Id, now)
+		if err != nil {
+			return err
+		}
+		change, err = user.Upgrade(promoCode.Plan, "promo code "+promoCode.Code.V+" redeemed", now)
+		if err != nil {
+			return err
+		}
+		trial, err = uas.SubscriptionFactory.CreateTrial(&user.Id, promoCode, now)
+		if err != nil {
+			return err
+		}
+
+		err = uas.PromoCodeRepository.Save(*promoCode)
+		if err != nil {
+			return err
+		}
+		err = uas.SubscriptionRepository.Save(*trial)
+		if err != nil {
+			return err
+		}
+		return recordPlanChange(uas.UserRepository, uas.PlanChangeRepository, user, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+	notifyPlanChanged(uas.PlanChangedHandlers, *change)
+
+	return &UserRedeemPromoCodeResult{Change: *change, Trial: *trial}, nil
+}
+
+func (uas *UserApplicationService) OnPlanChanged(handler IPlanChangedHandler) {
+	uas.PlanChangedHandlers = append(uas.PlanChangedHandlers, handler)
+}
+
+func planChangeReason(reason string, fallback string) string {
+	if reason = strings.TrimSpace(reason); len(reason) != 0 {
+		return reason
+	}
+	return fallback
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (uas *UserApplicationService) findUser(v string) (*model.User, error) {
+	id, err := model.NewUserId(v)
+	if err != nil {
+		return nil, err
+	}
+	user, err := uas.UserRepository.FindById(&id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, model.NewNotFoundError("user", id.V)
+	}
+	return user, nil
 }
@@ -0,0 +1,613 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+type (
+	// SubscriptionApplicationService holds the use cases of premium subscriptions.
+	// Every change of the plan is kept in the plan change repository,
+	// and every period is paid through the payment gateway before the user gets it.
+	SubscriptionApplicationService struct {
+		UserRepository         model.IUserRepository
+		SubscriptionRepository model.ISubscriptionRepository
+		SubscriptionFactory    model.ISubscriptionFactory
+		PlanChangeRepository   model.IPlanChangeRepository
+		InvoiceRepository      model.IInvoiceRepository
+		InvoiceFactory         model.IInvoiceFactory
+		LedgerRepository       model.ILedgerRepository
+		PaymentGateway         IPaymentGateway
+		PlanChangedHandlers    []IPlanChangedHandler
+		Policy                 model.AuthorizationPolicy
+		AuditLog               model.IAuditLog
+		UnitOfWork             model.IUnitOfWork
+	}
+)
+
+func NewSubscriptionApplicationService(userRepository model.IUserRepository, subscriptionRepository model.ISubscriptionRepository, subscriptionFactory model.ISubscriptionFactory, planChangeRepository model.IPlanChangeRepository, invoiceRepository model.IInvoiceRepository, invoiceFactory model.IInvoiceFactory, ledgerRepository model.ILedgerRepository, paymentGateway IPaymentGateway, unitOfWork model.IUnitOfWork, auditLog model.IAuditLog) SubscriptionApplicationService {
+	return SubscriptionApplicationService{
+		UserRepository:         userRepository,
+		SubscriptionRepository: subscriptionRepository,
+		SubscriptionFactory:    subscriptionFactory,
+		PlanChangeRepository:   planChangeRepository,
+		InvoiceRepository:      invoiceRepository,
+		InvoiceFactory:         invoiceFactory,
+		LedgerRepository:       ledgerRepository,
+		PaymentGateway:         paymentGateway,
+		Policy:                 model.NewAuthorizationPolicy(),
+		AuditLog:               auditLog,
+		UnitOfWork:             unitOfWork,
+	}
+}
+
+// Start subscribes the user to the premium plan of the command for the given months.
+// The user is upgraded only after the first period is paid.
+func (sas *SubscriptionApplicationService) Start(command SubscriptionStartCommand) (*SubscriptionResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	// every change is checked before the payment, so that nothing is charged for a subscription which can not start
+	now := time.Now()
+	plan := model.USER_TYPE_PREMIUM
+	if len(command.Plan) != 0 {
+		plan = model.UserType{V: command.Plan}
+	}
+	user, _, err := sas.startable(command, now)
+	if err != nil {
+		return nil, err
+	}
+	started, err := sas.SubscriptionFactory.Create(&user.Id, &plan, command.Months, now, command.AutoRenew)
+	if err != nil {
+		return nil, err
+	}
+	// upgrades a copy, as the user is read again when the payment is recorded
+	checked := *user
+	if _, err := upgradeBy(&checked, started, now); err != nil {
+		return nil, err
+	}
+	invoice, err := sas.billing().issue(user.Id, model.SubscriptionId{}, plan, command.Months, false, now)
+	if err != nil {
+		return nil, err
+	}
+	paid, err := sas.billing().pay(invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	// the checks are made again, as the user may have changed while the payment was taken.
+	// A declined payment is committed with the failed invoice, which stays in the history of the user.
+	var change *model.PlanChange
+	err = sas.UnitOfWork.Do(func() error {
+		user, trial, err := sas.startable(command, now)
+		if err != nil {
+			return err
+		}
+		upgrade, err := upgradeBy(user, started, now)
+		if err != nil {
+			return err
+		}
+		if err := sas.billing().record(paid, now); err != nil {
+			return err
+		}
+		if paid.declined != nil {
+			return nil
+		}
+
+		if trial != nil {
+			if err := sas.SubscriptionRepository.Save(*trial); err != nil {
+				return err
+			}
+		}
+		if err := sas.SubscriptionRepository.Save(*started); err != nil {
+			return err
+		}
+		if err := sas.billing().link(invoice, started); err != nil {
+			return err
+		}
+		if upgrade != nil {
+			if err := sas.savePlanChange(user, upgrade); err != nil {
+				return err
+			}
+		}
+		change = upgrade
+		return nil
+	})
+	if err != nil {
+		return nil, sas.refundIfFailed(err, []payment{paid})
+	}
+	if paid.declined != nil {
+		return nil, paid.declined
+	}
+	if change != nil {
+		notifyPlanChanged(sas.PlanChangedHandlers, *change)
+	}
+
+	return &SubscriptionResult{Subscription: *started}, nil
+}
+
+// Renew extends the active subscription of the user
+func (sas *SubscriptionApplicationService) Renew(command SubscriptionRenewCommand) (*SubscriptionResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	renewed, err := sas.renewable(command, now)
+	if err != nil {
+		return nil, err
+	}
+	invoice, err := sas.billing().issue(renewed.UserId, renewed.Id, renewed.Plan, command.Months, false, now)
+	if err != nil {
+		return nil, err
+	}
+	paid, err := sas.billing().pay(invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sas.UnitOfWork.Do(func() error {
+		renewed, err = sas.renewable(command, now)
+		if err != nil {
+			return err
+		}
+		if err := sas.billing().record(paid, now); err != nil {
+			return err
+		}
+		if paid.declined != nil {
+			return nil
+		}
+		return sas.SubscriptionRepository.Save(*renewed)
+	})
+	if err != nil {
+		return nil, sas.refundIfFailed(err, []payment{paid})
+	}
+	if paid.declined != nil {
+		return nil, paid.declined
+	}
+
+	return &SubscriptionResult{Subscription: *renewed}, nil
+}
+
+// Cancel stops the renewals. The user stays premium until the end of the period.
+func (sas *SubscriptionApplicationService) Cancel(command SubscriptionCancelCommand) (*SubscriptionResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	var subscription *model.Subscription
+	err := sas.UnitOfWork.Do(func() error {
+		user, err := sas.findUser(command.UserId)
+		if err != nil {
+			return err
+		}
+		if err := model.Enforce(sas.Policy.CanManageUser(command.Actor, model.ACTION_SUBSCRIPTION_CANCEL, user), sas.AuditLog); err != nil {
+			return err
+		}
+		now := time.Now()
+		subscription, err = sas.findActiveSubscription(user, now)
+		if err != nil {
+			return err
+		}
+		err = subscription.Cancel(now)
+		if err != nil {
+			return err
+		}
+		return sas.SubscriptionRepository.Save(*subscription)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SubscriptionResult{Subscription: *subscription}, nil
+}
+
+// Get returns the latest subscription of the user, which may have expired
+func (sas *SubscriptionApplicationService) Get(command SubscriptionGetCommand) (*SubscriptionResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	user, err := sas.findUser(command.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if err := model.Enforce(sas.Policy.CanViewPlan(command.Actor, model.ACTION_SUBSCRIPTION_VIEW, user), sas.AuditLog); err != nil {
+		return nil, err
+	}
+	subscription, err := sas.SubscriptionRepository.FindByUserId(&user.Id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, model.NewNotFoundError("subscription", user.Id.V)
+	}
+	return &SubscriptionResult{Subscription: *subscription}, nil
+}
+
+// ExpireDue is run by the scheduler. Subscriptions which renew automatically are charged
+// and extended by their own months, and the others expire and their users are downgraded.
+// A declined renewal keeps the subscription due until RetryPayments settles its invoice.
+// The subscriptions which fail are returned as one error together with the result of the others,
+// and are tried again on the next run.
+func (sas *SubscriptionApplicationService) ExpireDue(command SubscriptionExpireCommand) (*SubscriptionExpireResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := SubscriptionExpireResult{Renewed: []model.Subscription{}, Downgraded: []model.PlanChange{}}
+	due, err := sas.SubscriptionRepository.FindDue(command.Now)
+	if err != nil {
+		return nil, err
+	}
+	// every subscription is settled in its own unit of work, so that one failing does not undo the others
+	var failures []error
+	for _, subscription := range due {
+		subscription := subscription
+		if subscription.AutoRenew && !subscription.IsCancelled() {
+			renewed, err := sas.renewDue(subscription, command.Now)
+			if err != nil {
+				failures = append(failures, fmt.Errorf("subscription %s: %w", subscription.Id.V, err))
+				continue
+			}
+			if renewed != nil {
+				result.Renewed = append(result.Renewed, *renewed)
+			}
+			continue
+		}
+
+		var change *model.PlanChange
+		err := sas.UnitOfWork.Do(func() (err error) {
+			change, err = sas.expire(&subscription, "subscription "+subscription.Id.V+" expired", command.Now)
+			return err
+		})
+		if err != nil {
+			failures = append(failures, fmt.Errorf("subscription %s: %w", subscription.Id.V, err))
+			continue
+		}
+		if change != nil {
+			result.Downgraded = append(result.Downgraded, *change)
+			notifyPlanChanged(sas.PlanChangedHandlers, *change)
+		}
+	}
+	return &result, errors.Join(failures...)
+}
+
+// RetryPayments is run by the scheduler. It collects the invoices of declined renewals again,
+// and expires the subscription when the last retry is declined as well.
+// Like ExpireDue, the invoices which fail are returned as one error together with the result of the others.
+func (sas *SubscriptionApplicationService) RetryPayments(command SubscriptionRetryPaymentsCommand) (*SubscriptionRetryPaymentsResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := SubscriptionRetryPaymentsResult{Paid: []model.Invoice{}, Failed: []model.Invoice{}, Downgraded: []model.PlanChange{}}
+	due, err := sas.InvoiceRepository.FindRetryDue(command.Now)
+	if err != nil {
+		return nil, err
+	}
+	var failures []error
+	for _, invoice := range due {
+		invoice := invoice
+		paid, failed, change, err := sas.retryPayment(&invoice, command.Now)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("invoice %s: %w", invoice.Id.V, err))
+			continue
+		}
+		if paid {
+			result.Paid = append(result.Paid, invoice)
+		}
+		if failed {
+			result.Failed = append(result.Failed, invoice)
+		}
+		if change != nil {
+			result.Downgraded = append(result.Downgraded, *change)
+			notifyPlanChanged(sas.PlanChangedHandlers, *change)
+		}
+	}
+	return &result, errors.Join(failures...)
+}
+
+// History returns the plan changes of the user, oldest first
+func (sas *SubscriptionApplicationService) History(command PlanHistoryCommand) (*PlanHistoryResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	user, err := sas.findUser(command.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if err := model.Enforce(sas.Policy.CanViewPlan(command.Actor, model.ACTION_PLAN_HISTORY_VIEW, user), sas.AuditLog); err != nil {
+		return nil, err
+	}
+	changes, err := sas.PlanChangeRepository.FindByUserId(&user.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &PlanHistoryResult{Changes: changes}, nil
+}
+
+func (sas *SubscriptionApplicationService) OnPlanChanged(handler IPlanChangedHandler) {
+	sas.PlanChangedHandlers = append(sas.PlanChangedHandlers, handler)
+}
+
+func (sas *SubscriptionApplicationService) savePlanChange(user *model.User, change *model.PlanChange) error {
+	return recordPlanChange(sas.UserRepository, sas.PlanChangeRepository, user, change)
+}
+
+// expire ends the due subscription and downgrades its user, who may have been deleted or downgraded already
+func (sas *SubscriptionApplicationService) expire(subscription *model.Subscription, reason string, now time.Time) (*model.PlanChange, error) {
+	if err := subscription.Expire(now); err != nil {
+		return nil, err
+	}
+	if err := sas.SubscriptionRepository.Save(*subscription); err != nil {
+		return nil, err
+	}
+	user, err := sas.UserRepository.FindById(&subscription.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsPremium() {
+		return nil, nil
+	}
+	change, err := user.DownGrade(reason, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := sas.savePlanChange(user, change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// startable checks that the user can start a subscription, and ends the trial which the paid subscription takes over.
+// trial is nil when the user has no active one. Nothing is saved.
+func (sas *SubscriptionApplicationService) startable(command SubscriptionStartCommand, now time.Time) (user *model.User, trial *model.Subscription, err error) {
+	user, err = sas.findUser(command.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := model.Enforce(sas.Policy.CanManageUser(command.Actor, model.ACTION_SUBSCRIBE, user), sas.AuditLog); err != nil {
+		return nil, nil, err
+	}
+	current, err := sas.SubscriptionRepository.FindByUserId(&user.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if current == nil {
+		return user, nil, nil
+	}
+	if current.IsActive(now) && !current.IsTrial() {
+		return nil, nil, model.NewConflictError("subscription", "is already active")
+	}
+	pending, err := sas.InvoiceRepository.FindOpenBySubscriptionId(&current.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pending != nil {
+		return nil, nil, model.NewConflictError("subscription", "has invoice "+pending.Id.V+" waiting for its payment")
+	}
+	if !current.IsActive(now) {
+		return user, nil, nil
+	}
+	if err := current.EndTrial(now); err != nil {
+		return nil, nil, err
+	}
+	return user, current, nil
+}
+
+// upgradeBy upgrades the user to the plan of the started subscription.
+// Users who are premium already keep their plan without a change.
+func upgradeBy(user *model.User, started *model.Subscription, now time.Time) (*model.PlanChange, error) {
+	if user.IsPremium() {
+		return nil, nil
+	}
+	return user.Upgrade(started.Plan, "subscription "+started.Id.V+" started", now)
+}
+
+// renewable renews a copy of the active subscription of the user without saving it
+func (sas *SubscriptionApplicationService) renewable(command SubscriptionRenewCommand, now time.Time) (*model.Subscription, error) {
+	user, err := sas.findUser(command.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if err := model.Enforce(sas.Policy.CanManageUser(command.Actor, model.ACTION_SUBSCRIPTION_RENEW, user), sas.AuditLog); err != nil {
+		return nil, err
+	}
+	subscription, err := sas.findActiveSubscription(user, now)
+	if err != nil {
+		return nil, err
+	}
+	renewed := *subscription
+	if err := renewed.Renew(command.Months); err != nil {
+		return nil, err
+	}
+	return &renewed, nil
+}
+
+// renewDue charges the periods which the subscription missed while the job was not running, up to the first declined one.
+// The periods are paid outside of units of work and recorded together in one afterwards.
+// The renewed subscription is nil when it is still due.
+func (sas *SubscriptionApplicationService) renewDue(subscription model.Subscription, now time.Time) (*model.Subscription, error) {
+	pending, err := sas.InvoiceRepository.FindOpenBySubscriptionId(&subscription.Id)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		return nil, nil
+	}
+
+	// every period is renewed on a copy before it is charged
+	renewed := subscription
+	var payments []payment
+	for renewed.IsDue(now) {
+		next := renewed
+		if err := next.Renew(subscription.Months); err != nil {
+			return nil, sas.refundIfFailed(err, payments)
+		}
+		invoice, err := sas.billing().issue(subscription.UserId, subscription.Id, subscription.Plan, subscription.Months, true, now)
+		if err != nil {
+			return nil, sas.refundIfFailed(err, payments)
+		}
+		paid, err := sas.billing().pay(invoice)
+		if err != nil {
+			return nil, sas.refundIfFailed(err, payments)
+		}
+		payments = append(payments, paid)
+		if paid.declined != nil {
+			break
+		}
+		renewed = next
+	}
+
+	err = sas.UnitOfWork.Do(func() error {
+		if err := sas.checkUnchanged(subscription); err != nil {
+			return err
+		}
+		for _, paid := range payments {
+			if err := sas.billing().record(paid, now); err != nil {
+				return err
+			}
+		}
+		return sas.SubscriptionRepository.Save(renewed)
+	})
+	if err != nil {
+		return nil, sas.refundIfFailed(err, payments)
+	}
+	if renewed.IsDue(now) {
+		return nil, nil
+	}
+	return &renewed, nil
+}
+
+// retryPayment collects the open invoice again. failed is true when its last retry was declined,
+// and change is the downgrade of the user whose subscription expired with it.
+func (sas *SubscriptionApplicationService) retryPayment(invoice *model.Invoice, now time.Time) (paid bool, failed bool, change *model.PlanChange, err error) {
+	subscription, err := sas.SubscriptionRepository.FindById(&invoice.SubscriptionId)
+	if err != nil {
+		return false, false, nil, err
+	}
+	// the subscription ended while the invoice was waiting
+	if subscription == nil || !subscription.IsDue(now) || subscription.IsCancelled() {
+		err := sas.UnitOfWork.Do(func() error {
+			if err := sas.checkUnsettled(*invoice); err != nil {
+				return err
+			}
+			return sas.billing().void(invoice, now)
+		})
+		return false, false, nil, err
+	}
+
+	// renews a copy first, so that nothing is collected for a subscription which can not be renewed
+	renewed := *subscription
+	if err := renewed.Renew(invoice.Months); err != nil {
+		return false, false, nil, err
+	}
+	taken, err := sas.billing().pay(invoice)
+	if err != nil {
+		return false, false, nil, err
+	}
+
+	err = sas.UnitOfWork.Do(func() error {
+		change = nil
+		if err := sas.checkUnsettled(*invoice); err != nil {
+			return err
+		}
+		if err := sas.checkUnchanged(*subscription); err != nil {
+			return err
+		}
+		if err := sas.billing().settle(taken, now); err != nil {
+			return err
+		}
+		if taken.declined == nil {
+			return sas.SubscriptionRepository.Save(renewed)
+		}
+		if invoice.IsOpen() {
+			return nil
+		}
+		change, err = sas.expire(subscription, "payment of invoice "+invoice.Id.V+" failed", now)
+		return err
+	})
+	if err != nil {
+		return false, false, nil, sas.refundIfFailed(err, []payment{taken})
+	}
+	return taken.declined == nil, taken.declined != nil && !invoice.IsOpen(), change, nil
+}
+
+// checkUnchanged reads the subscription again in the unit of work which records its payment,
+// as it may have been renewed or cancelled while the payment was taken
+func (sas *SubscriptionApplicationService) checkUnchanged(subscription model.Subscription) error {
+	current, err := sas.SubscriptionRepository.FindById(&subscription.Id)
+	if err != nil {
+		return err
+	}
+	if current == nil || !current.End.Equal(subscription.End) || current.AutoRenew != subscription.AutoRenew || !current.Cancelled.Equal(subscription.Cancelled) || !current.Expired.Equal(subscription.Expired) {
+		return model.NewConflictError("subscription", subscription.Id.V+" changed while it was being paid")
+	}
+	return nil
+}
+
+// checkUnsettled reads the open invoice again in the unit of work which settles it,
+// as another run may have settled it meanwhile
+func (sas *SubscriptionApplicationService) checkUnsettled(invoice model.Invoice) error {
+	current, err := sas.InvoiceRepository.FindById(&invoice.Id)
+	if err != nil {
+		return err
+	}
+	if current == nil || !current.IsOpen() || current.Attempts != invoice.Attempts {
+		return model.NewConflictError("invoice", invoice.Id.V+" changed while it was being paid")
+	}
+	return nil
+}
+
+// refundIfFailed gives back the payments which a failed unit of work did not record,
+// as the payment gateway is not rolled back with it. err is returned together with any failed refund.
+func (sas *SubscriptionApplicationService) refundIfFailed(err error, payments []payment) error {
+	if err == nil || len(payments) == 0 {
+		return err
+	}
+	if refundErr := sas.billing().refund(payments); refundErr != nil {
+		return errors.Join(err, refundErr)
+	}
+	return err
+}
+
+func (sas *SubscriptionApplicationService) billing() billing {
+	return billing{
+		invoiceRepository: sas.InvoiceRepository,
+		invoiceFactory:    sas.InvoiceFactory,
+		ledgerRepository:  sas.LedgerRepository,
+		paymentGateway:    sas.PaymentGateway,
+	}
+}
+
+func (sas *SubscriptionApplicationService) findActiveSubscription(user *model.User, now time.Time) (*model.Subscription, error) {
+	subscription, err := sas.SubscriptionRepository.FindByUserId(&user.Id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil || !subscription.IsActive(now) {
+		return nil, model.NewNotFoundError("subscription", user.Id.V)
+	}
+	return subscription, nil
+}
+
+func (sas *SubscriptionApplicationService) findUser(v string) (*model.User, error) {
+	id, err := model.NewUserId(v)
+	if err != nil {
+		return nil, err
+	}
+	user, err := sas.UserRepository.FindById(&id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, model.NewNotFoundError("user", id.V)
+	}
+	return user, nil
+}
@@ -0,0 +1,362 @@
+package application
+
+import (
+	"strings"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+// the name is checked by the service, which follows the name policy it is given
+func (c UserRegisterCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	if len(c.Password) != 0 {
+		_, err := model.NewPassword(c.Password, model.UserName{V: strings.TrimSpace(c.Name)})
+		errs.Add("password", err)
+	}
+	return errs.Err()
+}
+
+func (c UserGetCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.UserId)
+	errs.Add("id", err)
+	return errs.Err()
+}
+
+// the name is checked by the service, which follows the name policy it is given
+func (c UserUpdateCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.Id)
+	errs.Add("id", err)
+	return errs.Err()
+}
+
+func (c UserDeleteCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.Id)
+	errs.Add("id", err)
+	return errs.Err()
+}
+
+func (c UserChangeEmailCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.Id)
+	errs.Add("id", err)
+	_, err = model.NewEmail(c.Email)
+	errs.Add("email", err)
+	return errs.Err()
+}
+
+func (c UserIssueEmailVerificationCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.Id)
+	errs.Add("id", err)
+	return errs.Err()
+}
+
+func (c UserVerifyEmailCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.Id)
+	errs.Add("id", err)
+	if len(c.Token) == 0 {
+		errs.Add("token", model.NewValidationError("token", "is required"))
+	}
+	return errs.Err()
+}
+
+func (c UserSetPasswordCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.Id)
+	errs.Add("id", err)
+	_, err = model.NewPassword(c.Password, model.UserName{})
+	errs.Add("password", err)
+	return errs.Err()
+}
+
+// passwords are not checked against the policy here
+// since they may have been set under an older policy
+func (c UserLoginCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	if len(c.Name) == 0 {
+		errs.Add("name", model.NewValidationError("name", "is required"))
+	}
+	if len(c.Password) == 0 {
+		errs.Add("password", model.NewValidationError("password", "is required"))
+	}
+	return errs.Err()
+}
+
+func (c UserLogoutCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	if len(c.Token) == 0 {
+		errs.Add("token", model.NewValidationError("token", "is required"))
+	}
+	return errs.Err()
+}
+
+func (c UserSuspendCommand) Validate() error {
+	return validateStatusChange(c.Id, c.Reason)
+}
+
+func (c UserReinstateCommand) Validate() error {
+	return validateStatusChange(c.Id, c.Reason)
+}
+
+func (c UserDeactivateCommand) Validate() error {
+	return validateStatusChange(c.Id, c.Reason)
+}
+
+func validateStatusChange(id string, reason string) error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(id)
+	errs.Add("id", err)
+	if len(strings.TrimSpace(reason)) == 0 {
+		errs.Add("reason", model.NewValidationError("reason", "is required"))
+	}
+	return errs.Err()
+}
+
+func (c UserUpgradeCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	errs.Add("", validatePlanChange(c.Id, c.Reason))
+	if len(c.Plan) != 0 {
+		_, err := model.NewUserType(c.Plan)
+		errs.Add("plan", err)
+	}
+	return errs.Err()
+}
+
+func (c UserDowngradeCommand) Validate() error {
+	return validatePlanChange(c.Id, c.Reason)
+}
+
+func (c UserRedeemPromoCodeCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.Id)
+	errs.Add("id", err)
+	_, err = model.NewPromoCodeValue(c.Code)
+	errs.Add("code", err)
+	return errs.Err()
+}
+
+func (c PromoCodeCreateCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	code, err := model.NewPromoCodeValue(c.Code)
+	errs.Add("code", err)
+	plan, err := model.NewUserType(c.Plan)
+	errs.Add("plan", err)
+	_, err = model.NewPromoCode(code, plan, c.TrialDays, c.MaxRedemptions, c.ExpiresAt)
+	errs.Add("", err)
+	return errs.Err()
+}
+
+func validatePlanChange(id string, reason string) error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(id)
+	errs.Add("id", err)
+	if len(reason) > 500 {
+		errs.Add("reason", model.NewValidationError("reason", "must be at most 500 characters"))
+	}
+	return errs.Err()
+}
+
+func (c UserListSuspendedCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	errs.Add("limit", validateLimit(c.Limit))
+	_, err := decodeCursor(c.Cursor)
+	errs.Add("cursor", err)
+	return errs.Err()
+}
+
+func (c UserAuthenticateCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	if len(c.Token) == 0 {
+		errs.Add("token", model.NewValidationError("token", "is required"))
+	}
+	return errs.Err()
+}
+
+func (c PostCreateCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewCircleId(c.CircleId)
+	errs.Add("circleId", err)
+	_, err = model.NewPostBody(c.Body)
+	errs.Add("body", err)
+	return errs.Err()
+}
+
+func (c PostEditCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewCircleId(c.CircleId)
+	errs.Add("circleId", err)
+	_, err = model.NewPostId(c.PostId)
+	errs.Add("postId", err)
+	_, err = model.NewPostBody(c.Body)
+	errs.Add("body", err)
+	return errs.Err()
+}
+
+func (c PostDeleteCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewCircleId(c.CircleId)
+	errs.Add("circleId", err)
+	_, err = model.NewPostId(c.PostId)
+	errs.Add("postId", err)
+	return errs.Err()
+}
+
+func (c PostListCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewCircleId(c.CircleId)
+	errs.Add("circleId", err)
+	return errs.Err()
+}
+
+func (c EventScheduleCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewCircleId(c.CircleId)
+	errs.Add("circleId", err)
+	_, err = model.NewEventTitle(c.Title)
+	errs.Add("title", err)
+	if c.Start.IsZero() {
+		errs.Add("start", model.NewValidationError("start", "is required"))
+	}
+	if !c.End.After(c.Start) {
+		errs.Add("end", model.NewValidationError("end", "must be after start"))
+	}
+	if c.Capacity < 0 {
+		errs.Add("capacity", model.NewValidationError("capacity", "must not be negative"))
+	}
+	return errs.Err()
+}
+
+func (c EventRsvpCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewCircleId(c.CircleId)
+	errs.Add("circleId", err)
+	_, err = model.NewEventId(c.EventId)
+	errs.Add("eventId", err)
+	_, err = model.NewRsvpAnswer(c.Answer)
+	errs.Add("answer", err)
+	return errs.Err()
+}
+
+func (c EventListCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewCircleId(c.CircleId)
+	errs.Add("circleId", err)
+	return errs.Err()
+}
+
+func (c UserGetAllCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	errs.Add("limit", validateLimit(c.Limit))
+	_, err := decodeCursor(c.Cursor)
+	errs.Add("cursor", err)
+	if len(c.Sort) != 0 {
+		_, err = model.NewUserSortKey(strings.TrimPrefix(c.Sort, "-"))
+		errs.Add("sort", err)
+	}
+	if len(c.Type) != 0 {
+		_, err = model.NewUserType(c.Type)
+		errs.Add("type", err)
+	}
+	return errs.Err()
+}
+
+func (c SubscriptionStartCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.UserId)
+	errs.Add("userId", err)
+	if len(c.Plan) != 0 {
+		plan, err := model.NewUserType(c.Plan)
+		errs.Add("plan", err)
+		if err == nil && !model.DefaultPlanRegistry.IsPremium(plan) {
+			errs.Add("plan", model.NewValidationError("plan", "must be a premium plan"))
+		}
+	}
+	_, err = model.NewSubscriptionMonths(c.Months)
+	errs.Add("months", err)
+	return errs.Err()
+}
+
+func (c SubscriptionRenewCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.UserId)
+	errs.Add("userId", err)
+	_, err = model.NewSubscriptionMonths(c.Months)
+	errs.Add("months", err)
+	return errs.Err()
+}
+
+func (c SubscriptionCancelCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.UserId)
+	errs.Add("userId", err)
+	return errs.Err()
+}
+
+func (c SubscriptionGetCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.UserId)
+	errs.Add("userId", err)
+	return errs.Err()
+}
+
+func (c SubscriptionExpireCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	if c.Now.IsZero() {
+		errs.Add("now", model.NewValidationError("now", "is required"))
+	}
+	return errs.Err()
+}
+
+func (c SubscriptionRetryPaymentsCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	if c.Now.IsZero() {
+		errs.Add("now", model.NewValidationError("now", "is required"))
+	}
+	return errs.Err()
+}
+
+func (c BillingGetCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.UserId)
+	errs.Add("userId", err)
+	return errs.Err()
+}
+
+func (c PlanHistoryCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.UserId)
+	errs.Add("userId", err)
+	return errs.Err()
+}
+
+func (c EntitlementGetCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.UserId)
+	errs.Add("userId", err)
+	return errs.Err()
+}
+
+func (c EntitlementOverrideCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.UserId)
+	errs.Add("userId", err)
+	for _, v := range append(append([]string{}, c.Granted...), c.Revoked...) {
+		_, err = model.NewFeature(v)
+		errs.Add("features", err)
+	}
+	if len(strings.TrimSpace(c.Reason)) == 0 {
+		errs.Add("reason", model.NewValidationError("reason", "is required"))
+	}
+	return errs.Err()
+}
+
+func (c EntitlementClearOverrideCommand) Validate() error {
+	errs := model.NewValidationErrors()
+	_, err := model.NewUserId(c.UserId)
+	errs.Add("userId", err)
+	return errs.Err()
+}
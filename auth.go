@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/labstack/echo"
+	"uyutaka.com/ddd-bottom-up/application"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+const actorKey = "actor"
+
+// authenticate resolves the bearer token of the Authorization header to the actor of the request.
+// Requests without the header are served as the anonymous actor, and invalid tokens are rejected.
+func authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Set(actorKey, model.ANONYMOUS_ACTOR)
+		if len(c.Request().Header.Get(echo.HeaderAuthorization)) == 0 {
+			return next(c)
+		}
+
+		token, err := bearerToken(c)
+		if err != nil {
+			return errorResponse(c, err)
+		}
+		result, err := authApplicationService.Authenticate(application.UserAuthenticateCommand{Token: token})
+		if err != nil {
+			return errorResponse(c, err)
+		}
+		c.Set(actorKey, result.Actor)
+		return next(c)
+	}
+}
+
+func bearerToken(c echo.Context) (string, error) {
+	scheme, token, ok := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || len(token) == 0 {
+		return "", model.NewAuthenticationError("authorization header must be a bearer token")
+	}
+	return token, nil
+}
+
+func actorOf(c echo.Context) model.Actor {
+	if actor, ok := c.Get(actorKey).(model.Actor); ok {
+		return actor
+	}
+	return model.ANONYMOUS_ACTOR
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	inMemoryInfrastructure "uyutaka.com/ddd-bottom-up/InMemoryInfrastructure"
+	"uyutaka.com/ddd-bottom-up/application"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+var testJWT = inMemoryInfrastructure.NewHS256JWT([]byte("secret"), "")
+
+func setUpAuthApplicationService() {
+	setUpUserApplicationService()
+	credentialRepository := inMemoryInfrastructure.NewSliceCredentialRepository()
+	sessionRepository := inMemoryInfrastructure.NewSliceSessionRepository()
+	testUnitOfWork.Register(credentialRepository.Storage, sessionRepository.Storage)
+	// small parameters keep the test fast
+	hasher := inMemoryInfrastructure.Argon2idHasher{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	authApplicationService = application.NewAuthApplicationService(userApplicationService.UserRepository, &credentialRepository, &sessionRepository, hasher, testJWT, testUnitOfWork, inMemoryInfrastructure.NewWriterAuditLog(io.Discard))
+	userApplicationService.CredentialRepository = &credentialRepository
+	userApplicationService.PasswordHasher = hasher
+}
+
+// logIn sets the password of the user and returns a session token
+func logIn(t *testing.T, id string, name string) string {
+	actor, _ := model.NewActor(model.UserId{V: id}, model.USER_ROLE_MEMBER)
+	assert.Nil(t, authApplicationService.SetPassword(application.UserSetPasswordCommand{Actor: actor, Id: id, Password: "Secret-pass1"}))
+	result, err := authApplicationService.Login(application.UserLoginCommand{Name: name, Password: "Secret-pass1"})
+	assert.Nil(t, err)
+	return result.Token
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization func(t *testing.T) string
+		wantStatus    int
+	}{
+		{name: "anonymous", authorization: func(t *testing.T) string { return "" }, wantStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", authorization: func(t *testing.T) string { return "Basic dXNlcjE6cGFzcw==" }, wantStatus: http.StatusUnauthorized},
+		{name: "unknown token", authorization: func(t *testing.T) string { return "Bearer unknown" }, wantStatus: http.StatusUnauthorized},
+		{name: "session of the user", authorization: func(t *testing.T) string { return "Bearer " + logIn(t, "1", "user1") }, wantStatus: http.StatusOK},
+		{name: "session of another user", authorization: func(t *testing.T) string { return "Bearer " + logIn(t, "2", "user2") }, wantStatus: http.StatusForbidden},
+		{
+			name: "session of a suspended user",
+			authorization: func(t *testing.T) string {
+				token := logIn(t, "1", "user1")
+				user, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "1"})
+				assert.Nil(t, user.Suspend("spam", time.Now()))
+				assert.Nil(t, userApplicationService.UserRepository.Save(*user))
+				return "Bearer " + token
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "jwt of the user",
+			authorization: func(t *testing.T) string {
+				token, _ := testJWT.Sign(model.UserId{V: "1"}, time.Now(), time.Now().Add(time.Hour))
+				return "Bearer " + token
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "jwt of an unknown user",
+			authorization: func(t *testing.T) string {
+				token, _ := testJWT.Sign(model.UserId{V: "99"}, time.Now(), time.Now().Add(time.Hour))
+				return "Bearer " + token
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpAuthApplicationService()
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"name":"renamed"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if authorization := tt.authorization(t); authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, authorization)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			assert.Nil(t, authenticate(updateUser)(c))
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantStatus == http.StatusUnauthorized {
+				assert.Equal(t, `Bearer realm="ddd-bottom-up"`, rec.Header().Get(echo.HeaderWWWAuthenticate))
+			}
+		})
+	}
+}
+
+// failingHasher fails to hash any password
+type failingHasher struct{}
+
+func (failingHasher) Hash(model.Password) (string, error) {
+	return "", errors.New("hashing failed")
+}
+
+func (failingHasher) Verify(model.Password, string) (bool, error) {
+	return false, errors.New("hashing failed")
+}
+
+// freeUnitOfWorkHasher fails when a unit of work can not run while it hashes,
+// as the unit of work of the caller is still held
+type freeUnitOfWorkHasher struct {
+	model.IPasswordHasher
+	unitOfWork model.IUnitOfWork
+}
+
+func (h freeUnitOfWorkHasher) free() error {
+	done := make(chan error, 1)
+	go func() { done <- h.unitOfWork.Do(func() error { return nil }) }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		return errors.New("hashed in a unit of work")
+	}
+}
+
+func (h freeUnitOfWorkHasher) Hash(password model.Password) (string, error) {
+	if err := h.free(); err != nil {
+		return "", err
+	}
+	return h.IPasswordHasher.Hash(password)
+}
+
+func (h freeUnitOfWorkHasher) Verify(password model.Password, hash string) (bool, error) {
+	if err := h.free(); err != nil {
+		return false, err
+	}
+	return h.IPasswordHasher.Verify(password, hash)
+}
+
+func TestHashOutsideUnitOfWork(t *testing.T) {
+	setUpAuthApplicationService()
+	authApplicationService.PasswordHasher = freeUnitOfWorkHasher{authApplicationService.PasswordHasher, testUnitOfWork}
+	actor, _ := model.NewActor(model.UserId{V: "1"}, model.USER_ROLE_MEMBER)
+
+	assert.Nil(t, authApplicationService.SetPassword(application.UserSetPasswordCommand{Actor: actor, Id: "1", Password: "Secret-pass1"}))
+	assert.Nil(t, authApplicationService.SetPassword(application.UserSetPasswordCommand{Actor: actor, Id: "1", Password: "Secret-pass2", CurrentPassword: "Secret-pass1"}))
+	_, err := authApplicationService.Login(application.UserLoginCommand{Name: "user1", Password: "Secret-pass2"})
+	assert.Nil(t, err)
+	_, err = authApplicationService.Login(application.UserLoginCommand{Name: "user1", Password: "Secret-pass1"})
+	assert.ErrorIs(t, err, model.ErrUnauthenticated)
+	_, err = authApplicationService.Login(application.UserLoginCommand{Name: "nobody", Password: "Secret-pass1"})
+	assert.ErrorIs(t, err, model.ErrUnauthenticated)
+}
+
+func TestCreateUserWithPassword(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		hasher     model.IPasswordHasher
+		wantStatus int
+	}{
+		{name: "password", password: "Secret-pass1", wantStatus: http.StatusCreated},
+		{name: "weak password", password: "secret", wantStatus: http.StatusUnprocessableEntity},
+		{name: "hashing fails", password: "Secret-pass1", hasher: failingHasher{}, wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpAuthApplicationService()
+			if tt.hasher != nil {
+				userApplicationService.PasswordHasher = tt.hasher
+			}
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"user3","password":"`+tt.password+`"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			assert.Nil(t, createUser(e.NewContext(req, rec)))
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			created := tt.wantStatus == http.StatusCreated
+			user, _ := userApplicationService.UserRepository.FindByName(&model.UserName{V: "user3"})
+			assert.Equal(t, created, user != nil, fmt.Sprintf("user was created: %v", user != nil))
+			_, err := authApplicationService.Login(application.UserLoginCommand{Name: "user3", Password: tt.password})
+			assert.Equal(t, created, err == nil, fmt.Sprintf("user could log in: %v", err))
+		})
+	}
+}
+
+// trackingUnitOfWork tells whether a unit of work is running
+type trackingUnitOfWork struct {
+	model.IUnitOfWork
+	running *bool
+}
+
+func (u trackingUnitOfWork) Do(work func() error) error {
+	return u.IUnitOfWork.Do(func() error {
+		*u.running = true
+		defer func() { *u.running = false }()
+		return work()
+	})
+}
+
+// unitOfWorkSessionRepository fails to delete sessions outside of a unit of work
+type unitOfWorkSessionRepository struct {
+	model.ISessionRepository
+	running *bool
+}
+
+func (r unitOfWorkSessionRepository) Delete(session model.Session) error {
+	if !*r.running {
+		return errors.New("deleted outside of a unit of work")
+	}
+	return r.ISessionRepository.Delete(session)
+}
+
+func TestAuthenticate_ExpiredSession(t *testing.T) {
+	setUpAuthApplicationService()
+	running := false
+	authApplicationService.UnitOfWork = trackingUnitOfWork{authApplicationService.UnitOfWork, &running}
+	authApplicationService.SessionRepository = unitOfWorkSessionRepository{authApplicationService.SessionRepository, &running}
+	now := time.Now()
+	session := model.Session{Token: model.HashToken("expired"), UserId: model.UserId{V: "1"}, Created: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}
+	assert.Nil(t, authApplicationService.SessionRepository.Save(session))
+
+	_, err := authApplicationService.Authenticate(application.UserAuthenticateCommand{Token: "expired"})
+	assert.ErrorIs(t, err, model.ErrUnauthenticated)
+	found, _ := authApplicationService.SessionRepository.FindByToken(session.Token)
+	assert.Nil(t, found, "the expired session was not deleted")
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/application"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func getLedgerOf(t *testing.T, id string) model.LedgerResponseModel {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	token, _ := testJWT.Sign(model.UserId{V: id}, time.Now(), time.Now().Add(time.Hour))
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	assert.Nil(t, authenticate(getLedger)(c))
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var response model.LedgerResponseModel
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	return response
+}
+
+func TestStartSubscription_Payment(t *testing.T) {
+	tests := []struct {
+		name        string
+		declines    int
+		wantStatus  int
+		wantType    model.UserType
+		wantKinds   []string
+		wantBalance int
+	}{
+		{name: "paid", declines: 0, wantStatus: http.StatusCreated, wantType: model.USER_TYPE_PREMIUM, wantKinds: []string{"charge", "payment"}, wantBalance: 0},
+		{name: "declined", declines: 1, wantStatus: http.StatusPaymentRequired, wantType: model.USER_TYPE_NORMAL, wantKinds: []string{"charge", "write_off"}, wantBalance: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpSubscriptionApplicationService()
+			testPaymentGateway.Decline(model.UserId{V: "1"}, tt.declines)
+
+			rec := postForm(startSubscription, "1", "1", url.Values{"months": {"3"}})
+			assert.Equal(t, tt.wantStatus, rec.Code, fmt.Sprintf("startSubscription() = %v", rec.Body.String()))
+
+			user, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "1"})
+			assert.Equal(t, tt.wantType, user.UType)
+			ledger := getLedgerOf(t, "1")
+			kinds := []string{}
+			for _, entry := range ledger.Entries {
+				kinds = append(kinds, entry.Kind)
+				assert.Equal(t, 1500, entry.Amount)
+			}
+			assert.Equal(t, tt.wantKinds, kinds)
+			assert.Equal(t, tt.wantBalance, ledger.Balance)
+
+			// only paid invoices are linked to the subscription they started
+			invoice, _ := subscriptionApplicationService.InvoiceRepository.FindById(&model.InvoiceId{V: "1"})
+			subscription, _ := subscriptionApplicationService.SubscriptionRepository.FindByUserId(&model.UserId{V: "1"})
+			if subscription == nil {
+				assert.Equal(t, "", invoice.SubscriptionId.V)
+			} else {
+				assert.Equal(t, subscription.Id, invoice.SubscriptionId)
+			}
+		})
+	}
+}
+
+func TestStartSubscription_Plan(t *testing.T) {
+	tests := []struct {
+		name       string
+		plan       string
+		wantStatus int
+		wantType   model.UserType
+	}{
+		{name: "default", plan: "", wantStatus: http.StatusCreated, wantType: model.USER_TYPE_PREMIUM},
+		{name: "premium", plan: "premium", wantStatus: http.StatusCreated, wantType: model.USER_TYPE_PREMIUM},
+		{name: "not premium", plan: "normal", wantStatus: http.StatusUnprocessableEntity, wantType: model.USER_TYPE_NORMAL},
+		{name: "unknown", plan: "gold", wantStatus: http.StatusUnprocessableEntity, wantType: model.USER_TYPE_NORMAL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpSubscriptionApplicationService()
+
+			rec := postForm(startSubscription, "1", "1", url.Values{"plan": {tt.plan}, "months": {"1"}})
+			assert.Equal(t, tt.wantStatus, rec.Code, fmt.Sprintf("startSubscription() = %v", rec.Body.String()))
+
+			user, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "1"})
+			assert.Equal(t, tt.wantType, user.UType)
+		})
+	}
+}
+
+type failingSubscriptionRepository struct {
+	model.ISubscriptionRepository
+}
+
+func (r failingSubscriptionRepository) Save(subscription model.Subscription) error {
+	return errors.New("disk full")
+}
+
+func TestStartSubscription_RefundsWhenNotSaved(t *testing.T) {
+	setUpSubscriptionApplicationService()
+	subscriptionApplicationService.SubscriptionRepository = failingSubscriptionRepository{subscriptionApplicationService.SubscriptionRepository}
+	actor, _ := model.NewActor(model.UserId{V: "1"}, model.USER_ROLE_MEMBER)
+
+	_, err := subscriptionApplicationService.Start(application.SubscriptionStartCommand{Actor: actor, UserId: "1", Months: 1})
+	assert.NotNil(t, err)
+	assert.True(t, testPaymentGateway.Refunded("pay_1"), "the payment of the failed start was not refunded")
+
+	user, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "1"})
+	assert.Equal(t, model.USER_TYPE_NORMAL, user.UType)
+	invoice, _ := subscriptionApplicationService.InvoiceRepository.FindById(&model.InvoiceId{V: "1"})
+	assert.Nil(t, invoice)
+}
+
+// unitOfWorkCheckingGateway fails the payments which are taken while a unit of work is held
+type unitOfWorkCheckingGateway struct {
+	application.IPaymentGateway
+}
+
+func (g unitOfWorkCheckingGateway) Charge(request application.PaymentRequest) (string, error) {
+	done := make(chan error, 1)
+	go func() {
+		done <- testUnitOfWork.Do(func() error { return nil })
+	}()
+	select {
+	case <-done:
+		return g.IPaymentGateway.Charge(request)
+	case <-time.After(time.Second):
+		return "", errors.New("charged in a unit of work")
+	}
+}
+
+func TestSubscription_ChargesOutsideUnitOfWork(t *testing.T) {
+	setUpSubscriptionApplicationService()
+	subscriptionApplicationService.PaymentGateway = unitOfWorkCheckingGateway{testPaymentGateway}
+	actor, _ := model.NewActor(model.UserId{V: "1"}, model.USER_ROLE_MEMBER)
+
+	started, err := subscriptionApplicationService.Start(application.SubscriptionStartCommand{Actor: actor, UserId: "1", Months: 1, AutoRenew: true})
+	assert.Nil(t, err)
+	_, err = subscriptionApplicationService.Renew(application.SubscriptionRenewCommand{Actor: actor, UserId: "1", Months: 1})
+	assert.Nil(t, err)
+	expired, err := subscriptionApplicationService.ExpireDue(application.SubscriptionExpireCommand{Now: started.Subscription.End.AddDate(0, 1, 0)})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(expired.Renewed))
+}
+
+func TestRetryPayments(t *testing.T) {
+	tests := []struct {
+		name       string
+		declines   int
+		wantType   model.UserType
+		wantStatus []string
+	}{
+		{name: "paid on the first retry", declines: 1, wantType: model.USER_TYPE_PREMIUM, wantStatus: []string{"paid", "paid"}},
+		{name: "paid on the last retry", declines: len(model.InvoiceRetryIntervals), wantType: model.USER_TYPE_PREMIUM, wantStatus: []string{"paid", "paid"}},
+		{name: "never paid", declines: -1, wantType: model.USER_TYPE_NORMAL, wantStatus: []string{"paid", "failed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpSubscriptionApplicationService()
+			actor, _ := model.NewActor(model.UserId{V: "1"}, model.USER_ROLE_MEMBER)
+			started, err := subscriptionApplicationService.Start(application.SubscriptionStartCommand{Actor: actor, UserId: "1", Months: 1, AutoRenew: true})
+			assert.Nil(t, err)
+
+			testPaymentGateway.Decline(model.UserId{V: "1"}, tt.declines)
+			now := started.Subscription.End
+			expired, err := subscriptionApplicationService.ExpireDue(application.SubscriptionExpireCommand{Now: now})
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(expired.Renewed))
+			// the due subscription is not charged again while its invoice waits for a retry
+			_, err = subscriptionApplicationService.ExpireDue(application.SubscriptionExpireCommand{Now: now.Add(time.Hour)})
+			assert.Nil(t, err)
+			for _, interval := range model.InvoiceRetryIntervals {
+				now = now.Add(interval)
+				_, err := subscriptionApplicationService.RetryPayments(application.SubscriptionRetryPaymentsCommand{Now: now})
+				assert.Nil(t, err)
+			}
+
+			user, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "1"})
+			assert.Equal(t, tt.wantType, user.UType)
+			invoices, _ := billingApplicationService.Invoices(application.BillingGetCommand{Actor: actor, UserId: "1"})
+			status := []string{}
+			for _, invoice := range invoices.Invoices {
+				status = append(status, invoice.Status.V)
+			}
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, 0, getLedgerOf(t, "1").Balance)
+		})
+	}
+}
+
+func TestGetInvoices_Permission(t *testing.T) {
+	setUpSubscriptionApplicationService()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	token, _ := testJWT.Sign(model.UserId{V: "2"}, time.Now(), time.Now().Add(time.Hour))
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	assert.Nil(t, authenticate(getInvoices)(c))
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+}
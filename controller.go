@@ -1,29 +1,66 @@
 package main
 
 import (
-	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo"
 	"uyutaka.com/ddd-bottom-up/application"
 	"uyutaka.com/ddd-bottom-up/model"
 )
 
+// userResponse shows the email of the user only to viewers who can see it
+func userResponse(c echo.Context, user model.User) *model.UserResponseModel {
+	response := model.NewUserResponseModel(user)
+	hideEmailFrom(c, &user, response)
+	return response
+}
+
+func hideEmailFrom(c echo.Context, user *model.User, response *model.UserResponseModel) {
+	if !userApplicationService.Policy.CanViewEmail(actorOf(c), user).Allowed {
+		response.HideEmail()
+	}
+}
+
 func getUsers(c echo.Context) error {
-	result, err := userApplicationService.GetAll()
+	command := application.UserGetAllCommand{
+		Cursor:     c.QueryParam("cursor"),
+		Sort:       c.QueryParam("sort"),
+		Type:       c.QueryParam("type"),
+		NamePrefix: c.QueryParam("name_prefix"),
+	}
+	if v := c.QueryParam("limit"); len(v) != 0 {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return errorResponse(c, model.NewValidationError("limit", "must be a number"))
+		}
+		command.Limit = limit
+	}
+
+	result, err := userApplicationService.GetAll(command)
 	if err != nil {
-		fmt.Println(err)
-		return c.String(http.StatusOK, "error in GetAll()")
+		return errorResponse(c, err)
 	}
-	var output string
-	if result != nil {
 
-		for _, user := range result.Users {
-			output += user.ToString() + "\n"
-		}
+	response := model.NewUserListResponseModel(result.Users)
+	for i := range response.Users {
+		hideEmailFrom(c, &result.Users[i], &response.Users[i])
 	}
+	response.Total = result.Total
+	c.Response().Header().Set("X-Total-Count", strconv.Itoa(result.Total))
+	if len(result.NextCursor) != 0 {
+		response.Next = nextPageLink(c, result.NextCursor)
+		c.Response().Header().Set("Link", "<"+response.Next+`>; rel="next"`)
+	}
+	return render(c, http.StatusOK, response)
+}
 
-	return c.String(http.StatusOK, output)
+// nextPageLink keeps every query parameter of the request but the cursor
+func nextPageLink(c echo.Context, cursor string) string {
+	query := c.Request().URL.Query()
+	query.Set("cursor", cursor)
+	return c.Request().URL.Path + "?" + query.Encode()
 }
 
 func getUser(c echo.Context) error {
@@ -32,42 +69,589 @@ func getUser(c echo.Context) error {
 
 	result, err := userApplicationService.Get(command)
 	if err != nil {
-		return c.String(http.StatusOK, err.Error())
+		return errorResponse(c, err)
 	}
-	response := model.NewUserResponseModel(result.User)
-	if response == nil {
-		return c.String(http.StatusOK, "error")
-	}
-	output := response.Id + " " + response.Name
-	return c.String(http.StatusOK, output)
+	setETag(c, result.User.Version)
+	return render(c, http.StatusOK, userResponse(c, result.User))
 }
 
 func createUser(c echo.Context) error {
-	command := application.UserRegisterCommand{Name: c.FormValue("name")}
+	request := new(model.UserPostRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	// the format is negotiated before the user is registered, so that a request which can not be answered changes nothing
+	if _, err := negotiateFormat(c); err != nil {
+		return errorResponse(c, err)
+	}
+	command := application.UserRegisterCommand{Name: request.Name, Password: request.Password}
 
 	result, err := userApplicationService.Register(command)
 	if err != nil {
-		return c.String(http.StatusOK, err.Error())
+		return errorResponse(c, err)
 	}
-	return c.String(http.StatusOK, "userId: "+result.Id+" created!")
+	user, err := userApplicationService.Get(application.UserGetCommand{UserId: result.Id})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, "/"+result.Id)
+	setETag(c, user.User.Version)
+	return renderMessage(c, http.StatusCreated, userResponse(c, user.User), "userId: "+result.Id+" created!")
 }
 
 func updateUser(c echo.Context) error {
 	id := c.Param("id")
-	command := application.UserUpdateCommand{Id: id, Name: c.FormValue("name")}
+	request := new(model.UserPutRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	if _, err := negotiateFormat(c); err != nil {
+		return errorResponse(c, err)
+	}
+	// If-Match: "3" updates the user only while they are at version 3
+	command := application.UserUpdateCommand{Actor: actorOf(c), Id: id, Name: request.Name, Version: ifMatch(c)}
 	err := userApplicationService.Update(command)
 	if err != nil {
-		return c.String(http.StatusOK, err.Error())
+		return errorResponse(c, err)
+	}
+	user, err := userApplicationService.Get(application.UserGetCommand{UserId: id})
+	if err != nil {
+		return errorResponse(c, err)
 	}
-	return c.String(http.StatusOK, "userId: "+id+" updated!")
+	setETag(c, user.User.Version)
+	return renderMessage(c, http.StatusOK, userResponse(c, user.User), "userId: "+id+" updated!")
 }
 
 func deleteUser(c echo.Context) error {
 	id := c.Param("id")
-	command := application.UserDeleteCommand{Id: id}
-	err := userApplicationService.Delete(command)
+	format, err := negotiateFormat(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	command := application.UserDeleteCommand{Actor: actorOf(c), Id: id, Version: ifMatch(c)}
+	err = userApplicationService.Delete(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	if format == formatText {
+		return c.String(http.StatusOK, "userId: "+id+" deleted!")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func changeEmail(c echo.Context) error {
+	id := c.Param("id")
+	request := new(model.UserEmailPutRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	command := application.UserChangeEmailCommand{Actor: actorOf(c), Id: id, Email: request.Email}
+	err := userApplicationService.ChangeEmail(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	user, err := userApplicationService.Get(application.UserGetCommand{UserId: id})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return renderMessage(c, http.StatusOK, userResponse(c, user.User), "userId: "+id+" email changed!")
+}
+
+func issueEmailVerification(c echo.Context) error {
+	id := c.Param("id")
+	command := application.UserIssueEmailVerificationCommand{Actor: actorOf(c), Id: id}
+	err := userApplicationService.IssueEmailVerification(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return c.String(http.StatusAccepted, "verification token sent to the email address of userId: "+id)
+}
+
+func verifyEmail(c echo.Context) error {
+	id := c.Param("id")
+	request := new(model.UserEmailVerifyRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	command := application.UserVerifyEmailCommand{Actor: actorOf(c), Id: id, Token: request.Token}
+	err := userApplicationService.VerifyEmail(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	user, err := userApplicationService.Get(application.UserGetCommand{UserId: id})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return renderMessage(c, http.StatusOK, userResponse(c, user.User), "userId: "+id+" email verified!")
+}
+
+func setPassword(c echo.Context) error {
+	id := c.Param("id")
+	request := new(model.UserPasswordPutRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	command := application.UserSetPasswordCommand{Actor: actorOf(c), Id: id, CurrentPassword: request.CurrentPassword, Password: request.Password}
+	err := authApplicationService.SetPassword(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func login(c echo.Context) error {
+	request := new(model.LoginRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	command := application.UserLoginCommand{Name: request.Name, Password: request.Password}
+	result, err := authApplicationService.Login(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return render(c, http.StatusCreated, model.NewSessionResponseModel(result.Token, result.UserId, result.ExpiresAt))
+}
+
+func logout(c echo.Context) error {
+	token, err := bearerToken(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	err = authApplicationService.Logout(application.UserLogoutCommand{Token: token})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func suspendUser(c echo.Context) error {
+	id := c.Param("id")
+	request := new(model.UserStatusRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	err := adminApplicationService.Suspend(application.UserSuspendCommand{Actor: actorOf(c), Id: id, Reason: request.Reason})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return renderUserStatus(c, id, "suspended")
+}
+
+func reinstateUser(c echo.Context) error {
+	id := c.Param("id")
+	request := new(model.UserStatusRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	err := adminApplicationService.Reinstate(application.UserReinstateCommand{Actor: actorOf(c), Id: id, Reason: request.Reason})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return renderUserStatus(c, id, "reinstated")
+}
+
+func deactivateUser(c echo.Context) error {
+	id := c.Param("id")
+	request := new(model.UserStatusRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	err := adminApplicationService.Deactivate(application.UserDeactivateCommand{Actor: actorOf(c), Id: id, Reason: request.Reason})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return renderUserStatus(c, id, "deactivated")
+}
+
+func renderUserStatus(c echo.Context, id string, done string) error {
+	user, err := userApplicationService.Get(application.UserGetCommand{UserId: id})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return renderMessage(c, http.StatusOK, model.NewUserStatusResponseModel(user.User), "userId: "+id+" "+done+"!")
+}
+
+func upgradeUser(c echo.Context) error {
+	request := new(model.PlanChangeRequestModel)
+	if err := bindOptional(c, request); err != nil {
+		return errorResponse(c, err)
+	}
+	result, err := userApplicationService.Upgrade(application.UserUpgradeCommand{Actor: actorOf(c), Id: c.Param("id"), Plan: request.Plan, Reason: request.Reason})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return render(c, http.StatusOK, model.NewPlanChangeResponseModel(result.Change))
+}
+
+func downgradeUser(c echo.Context) error {
+	request := new(model.PlanChangeRequestModel)
+	if err := bindOptional(c, request); err != nil {
+		return errorResponse(c, err)
+	}
+	result, err := userApplicationService.Downgrade(application.UserDowngradeCommand{Actor: actorOf(c), Id: c.Param("id"), Reason: request.Reason})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return render(c, http.StatusOK, model.NewPlanChangeResponseModel(result.Change))
+}
+
+// bindOptional binds the request only when it has a body, for requests whose fields can all be left out
+func bindOptional(c echo.Context, request interface{}) error {
+	if c.Request().ContentLength == 0 {
+		return nil
+	}
+	return c.Bind(request)
+}
+
+func getEntitlements(c echo.Context) error {
+	result, err := entitlementApplicationService.Get(application.EntitlementGetCommand{Actor: actorOf(c), UserId: c.Param("id")})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return render(c, http.StatusOK, model.NewEntitlementsResponseModel(result.User, result.Entitlements, result.Overridden))
+}
+
+func overrideEntitlements(c echo.Context) error {
+	id := c.Param("id")
+	request := new(model.EntitlementOverrideRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	command := application.EntitlementOverrideCommand{
+		Actor:          actorOf(c),
+		UserId:         id,
+		MaxCircles:     request.MaxCircles,
+		CircleCapacity: request.CircleCapacity,
+		Granted:        request.Granted,
+		Revoked:        request.Revoked,
+		Reason:         request.Reason,
+	}
+	if err := entitlementApplicationService.Override(command); err != nil {
+		return errorResponse(c, err)
+	}
+	return getEntitlements(c)
+}
+
+func clearEntitlementOverride(c echo.Context) error {
+	err := entitlementApplicationService.ClearOverride(application.EntitlementClearOverrideCommand{Actor: actorOf(c), UserId: c.Param("id")})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func redeemPromoCode(c echo.Context) error {
+	request := new(model.PromoCodeRedeemRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	result, err := userApplicationService.RedeemPromoCode(application.UserRedeemPromoCodeCommand{Actor: actorOf(c), Id: c.Param("id"), Code: request.Code})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return render(c, http.StatusCreated, model.NewSubscriptionResponseModel(result.Trial, time.Now()))
+}
+
+func createPromoCode(c echo.Context) error {
+	request := new(model.PromoCodeRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	command := application.PromoCodeCreateCommand{
+		Actor:          actorOf(c),
+		Code:           request.Code,
+		Plan:           request.Plan,
+		TrialDays:      request.TrialDays,
+		MaxRedemptions: request.MaxRedemptions,
+	}
+	expiresAt, err := time.Parse(time.RFC3339, request.ExpiresAt)
+	if err != nil {
+		errs := model.NewValidationErrors()
+		errs.Add("expiresAt", model.NewValidationError("expiresAt", "must be RFC3339"))
+		// report the other invalid fields as well
+		errs.Add("", command.Validate())
+		return errorResponse(c, errs)
+	}
+	command.ExpiresAt = expiresAt
+	result, err := adminApplicationService.CreatePromoCode(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return render(c, http.StatusCreated, model.NewPromoCodeResponseModel(result.PromoCode))
+}
+
+func getSuspendedUsers(c echo.Context) error {
+	command := application.UserListSuspendedCommand{Actor: actorOf(c), Cursor: c.QueryParam("cursor")}
+	if v := c.QueryParam("limit"); len(v) != 0 {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return errorResponse(c, model.NewValidationError("limit", "must be a number"))
+		}
+		command.Limit = limit
+	}
+
+	result, err := adminApplicationService.ListSuspended(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	response := model.NewUserStatusListResponseModel(result.Users)
+	response.Total = result.Total
+	c.Response().Header().Set("X-Total-Count", strconv.Itoa(result.Total))
+	if len(result.NextCursor) != 0 {
+		response.Next = nextPageLink(c, result.NextCursor)
+		c.Response().Header().Set("Link", "<"+response.Next+`>; rel="next"`)
+	}
+	return render(c, http.StatusOK, response)
+}
+
+func getPlans(c echo.Context) error {
+	return render(c, http.StatusOK, model.NewPlanListResponseModel(model.DefaultPlanRegistry.Plans()))
+}
+
+func startSubscription(c echo.Context) error {
+	request := new(model.SubscriptionRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	command := application.SubscriptionStartCommand{Actor: actorOf(c), UserId: c.Param("id"), Plan: request.Plan, Months: request.Months, AutoRenew: request.AutoRenew}
+	result, err := subscriptionApplicationService.Start(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return render(c, http.StatusCreated, model.NewSubscriptionResponseModel(result.Subscription, time.Now()))
+}
+
+func getSubscription(c echo.Context) error {
+	result, err := subscriptionApplicationService.Get(application.SubscriptionGetCommand{Actor: actorOf(c), UserId: c.Param("id")})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return render(c, http.StatusOK, model.NewSubscriptionResponseModel(result.Subscription, time.Now()))
+}
+
+func renewSubscription(c echo.Context) error {
+	request := new(model.SubscriptionRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	command := application.SubscriptionRenewCommand{Actor: actorOf(c), UserId: c.Param("id"), Months: request.Months}
+	result, err := subscriptionApplicationService.Renew(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return render(c, http.StatusOK, model.NewSubscriptionResponseModel(result.Subscription, time.Now()))
+}
+
+func cancelSubscription(c echo.Context) error {
+	result, err := subscriptionApplicationService.Cancel(application.SubscriptionCancelCommand{Actor: actorOf(c), UserId: c.Param("id")})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return render(c, http.StatusOK, model.NewSubscriptionResponseModel(result.Subscription, time.Now()))
+}
+
+func getPlanHistory(c echo.Context) error {
+	result, err := subscriptionApplicationService.History(application.PlanHistoryCommand{Actor: actorOf(c), UserId: c.Param("id")})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return render(c, http.StatusOK, model.NewPlanChangeListResponseModel(result.Changes))
+}
+
+func getInvoices(c echo.Context) error {
+	result, err := billingApplicationService.Invoices(application.BillingGetCommand{Actor: actorOf(c), UserId: c.Param("id")})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return render(c, http.StatusOK, model.NewInvoiceListResponseModel(result.Invoices))
+}
+
+func getLedger(c echo.Context) error {
+	result, err := billingApplicationService.Ledger(application.BillingGetCommand{Actor: actorOf(c), UserId: c.Param("id")})
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return render(c, http.StatusOK, model.NewLedgerResponseModel(result.Ledger))
+}
+
+func getCircles(c echo.Context) error {
+	result, err := circleApplicationService.GetAll(model.NewCircleGetAllCommand(actorOf(c)))
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return render(c, http.StatusOK, model.NewCircleListResponseModel(result.Circles))
+}
+
+func getCircle(c echo.Context) error {
+	command := model.NewCircleGetCommand(actorOf(c), c.Param("id"))
+	result, err := circleApplicationService.Get(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	setETag(c, result.Circle.Version())
+	return render(c, http.StatusOK, model.NewCircleResponseModel(result.Circle))
+}
+
+func createCircle(c echo.Context) error {
+	request := new(model.CirclePostRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	if _, err := negotiateFormat(c); err != nil {
+		return errorResponse(c, err)
+	}
+	command := model.NewCircleCreateCommand(actorOf(c), request.Name, request.Private)
+	result, err := circleApplicationService.Create(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	circle, err := circleApplicationService.Get(model.NewCircleGetCommand(actorOf(c), result.Id))
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, "/circles/"+result.Id)
+	setETag(c, circle.Circle.Version())
+	return renderMessage(c, http.StatusCreated, model.NewCircleResponseModel(circle.Circle), "circleId: "+result.Id+" created!")
+}
+
+func joinCircle(c echo.Context) error {
+	command := model.NewCircleJoinCommand(actorOf(c), c.Param("id"), ifMatch(c))
+	err := circleApplicationService.Join(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return c.String(http.StatusOK, "joined!")
+}
+
+func kickMember(c echo.Context) error {
+	command := model.NewCircleKickCommand(actorOf(c), c.Param("id"), c.Param("memberId"))
+	err := circleApplicationService.Kick(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func appointModerator(c echo.Context) error {
+	command := model.NewCircleAppointModeratorCommand(actorOf(c), c.Param("id"), c.Param("memberId"))
+	err := circleApplicationService.AppointModerator(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func getPosts(c echo.Context) error {
+	command := application.PostListCommand{Actor: actorOf(c), CircleId: c.Param("id")}
+	result, err := postApplicationService.List(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return render(c, http.StatusOK, model.NewPostListResponseModel(result.Posts))
+}
+
+func createPost(c echo.Context) error {
+	request := new(model.PostRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	command := application.PostCreateCommand{Actor: actorOf(c), CircleId: c.Param("id"), Body: request.Body}
+	result, err := postApplicationService.Create(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return c.String(http.StatusCreated, "postId: "+result.Id+" posted!")
+}
+
+func editPost(c echo.Context) error {
+	request := new(model.PostRequestModel)
+	if err := c.Bind(request); err != nil {
+		return errorResponse(c, err)
+	}
+	command := application.PostEditCommand{Actor: actorOf(c), CircleId: c.Param("id"), PostId: c.Param("postId"), Body: request.Body}
+	err := postApplicationService.Edit(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return c.String(http.StatusOK, "postId: "+command.PostId+" edited!")
+}
+
+func deletePost(c echo.Context) error {
+	command := application.PostDeleteCommand{Actor: actorOf(c), CircleId: c.Param("id"), PostId: c.Param("postId")}
+	err := postApplicationService.Delete(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func scheduleEvent(c echo.Context) error {
+	errs := model.NewValidationErrors()
+	start, err := time.Parse(time.RFC3339, c.FormValue("start"))
+	if err != nil {
+		errs.Add("start", model.NewValidationError("start", "must be RFC3339"))
+	}
+	end, err := time.Parse(time.RFC3339, c.FormValue("end"))
+	if err != nil {
+		errs.Add("end", model.NewValidationError("end", "must be RFC3339"))
+	}
+	capacity := 0
+	if v := c.FormValue("capacity"); len(v) != 0 {
+		capacity, err = strconv.Atoi(v)
+		if err != nil {
+			errs.Add("capacity", model.NewValidationError("capacity", "must be a number"))
+		}
+	}
+
+	command := application.EventScheduleCommand{
+		Actor:    actorOf(c),
+		CircleId: c.Param("id"),
+		Title:    c.FormValue("title"),
+		Start:    start,
+		End:      end,
+		Location: c.FormValue("location"),
+		Capacity: capacity,
+	}
+	if len(errs.Errors) != 0 {
+		// report the fields which could be parsed but are still invalid as well
+		errs.Add("", command.Validate())
+		return errorResponse(c, errs)
+	}
+	result, err := eventApplicationService.Schedule(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	return c.String(http.StatusCreated, "eventId: "+result.Id+" scheduled!")
+}
+
+func rsvpEvent(c echo.Context) error {
+	command := application.EventRsvpCommand{
+		Actor:    actorOf(c),
+		CircleId: c.Param("id"),
+		EventId:  c.Param("eventId"),
+		Answer:   c.FormValue("answer"),
+	}
+	result, err := eventApplicationService.Rsvp(command)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	if result.Waitlisted {
+		return c.String(http.StatusOK, "event is full, you are on the waitlist")
+	}
+	return c.String(http.StatusOK, "answered "+command.Answer+"!")
+}
+
+func exportEvents(c echo.Context) error {
+	command := application.EventListCommand{Actor: actorOf(c), CircleId: c.Param("id")}
+	result, err := eventApplicationService.List(command)
 	if err != nil {
-		return c.String(http.StatusOK, err.Error())
+		return errorResponse(c, err)
 	}
-	return c.String(http.StatusOK, "userId: "+id+" deleted!")
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="circle-`+command.CircleId+`.ics"`)
+	return c.Blob(http.StatusOK, "text/calendar; charset=utf-8", []byte(encodeICalendar(result.Circle, result.Events, time.Now())))
 }
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	inMemoryInfrastructure "uyutaka.com/ddd-bottom-up/InMemoryInfrastructure"
+	"uyutaka.com/ddd-bottom-up/application"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+// testUnitOfWork is shared by the services of a test, which register their storages to it
+var testUnitOfWork *inMemoryInfrastructure.InMemoryUnitOfWork
+
+func setUpUserApplicationService() {
+	repo := inMemoryInfrastructure.NewSliceUserRepository()
+	userService := model.NewUserService(&repo, model.USER_NAME_COMPARISON_NORMALIZED, model.NewDefaultUserNamePolicy())
+	userFactory := inMemoryInfrastructure.NewUserFactory(repo.Storage, model.NewDefaultUserNamePolicy())
+	mailer := inMemoryInfrastructure.NewWriterMailer(io.Discard)
+	planChangeRepository := inMemoryInfrastructure.NewSlicePlanChangeRepository()
+	promoCodeRepository := inMemoryInfrastructure.NewSlicePromoCodeRepository()
+	subscriptionRepository := inMemoryInfrastructure.NewSliceSubscriptionRepository()
+	subscriptionFactory := inMemoryInfrastructure.NewSubscriptionFactory(subscriptionRepository.Storage)
+	testUnitOfWork = inMemoryInfrastructure.NewInMemoryUnitOfWork(repo.Storage, planChangeRepository.Storage, promoCodeRepository.Storage, subscriptionRepository.Storage)
+	userApplicationService = application.NewUserApplicationService(userService, &userFactory, &repo, &planChangeRepository, &promoCodeRepository, &subscriptionRepository, &subscriptionFactory, mailer, testUnitOfWork, inMemoryInfrastructure.NewWriterAuditLog(io.Discard))
+}
+
+// setUpCircleApplicationService adds user3, who is in no circle, to the users of setUpAuthApplicationService
+func setUpCircleApplicationService() inMemoryInfrastructure.SliceCircleRepository {
+	setUpAuthApplicationService()
+	userRepository := userApplicationService.UserRepository
+	_ = userRepository.Save(model.User{Id: model.UserId{V: "3"}, Name: model.UserName{V: "user3"}, UType: model.USER_TYPE_NORMAL, Role: model.USER_ROLE_MEMBER, Status: model.UserStatusRecord{Status: model.USER_STATUS_ACTIVE}})
+	circleRepository := inMemoryInfrastructure.NewSliceCircleRepository()
+	circleFactory := inMemoryInfrastructure.NewCircleFactory(circleRepository.Storage)
+	overrideRepository := inMemoryInfrastructure.NewSliceEntitlementOverrideRepository()
+	testUnitOfWork.Register(circleRepository.Storage, overrideRepository.Storage)
+	entitlements := model.NewEntitlementService(model.DefaultPlanRegistry, &overrideRepository)
+	circleApplicationService = model.NewCircleApplicationService(&circleFactory, &circleRepository, model.NewCircleService(&circleRepository), userRepository, entitlements, testUnitOfWork, inMemoryInfrastructure.NewWriterAuditLog(io.Discard), time.Now())
+	return circleRepository
+}
+
+func TestUserHandlers(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		handler     echo.HandlerFunc
+		contentType string
+		accept      string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:       "list as json by default",
+			method:     http.MethodGet,
+			handler:    getUsers,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"users":[{"id":"1","name":"user1","type":"normal"},{"id":"2","name":"user2","type":"premium"}],"total":2}`,
+		},
+		{
+			name:       "list as text on request",
+			method:     http.MethodGet,
+			handler:    getUsers,
+			accept:     echo.MIMETextPlain,
+			wantStatus: http.StatusOK,
+			wantBody:   "1 user1 normal\n2 user2 premium\n",
+		},
+		{
+			name:       "first page",
+			method:     http.MethodGet,
+			target:     "/?limit=1&sort=-name",
+			handler:    getUsers,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"users":[{"id":"2","name":"user2","type":"premium"}],"total":2,"next":"/?cursor=b2Zmc2V0OjE\u0026limit=1\u0026sort=-name"}`,
+		},
+		{
+			name:       "last page",
+			method:     http.MethodGet,
+			target:     "/?limit=1&sort=-name&cursor=b2Zmc2V0OjE",
+			handler:    getUsers,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"users":[{"id":"1","name":"user1","type":"normal"}],"total":2}`,
+		},
+		{
+			name:       "filtered by type",
+			method:     http.MethodGet,
+			target:     "/?type=premium",
+			handler:    getUsers,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"users":[{"id":"2","name":"user2","type":"premium"}],"total":1}`,
+		},
+		{
+			name:       "invalid paging",
+			method:     http.MethodGet,
+			target:     "/?limit=1000&sort=age",
+			handler:    getUsers,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "create from json",
+			method:      http.MethodPost,
+			handler:     createUser,
+			contentType: echo.MIMEApplicationJSON,
+			body:        `{"name":"user3"}`,
+			wantStatus:  http.StatusCreated,
+			wantBody:    `{"id":"3","name":"user3","type":"normal"}`,
+		},
+		{
+			name:        "create from form",
+			method:      http.MethodPost,
+			handler:     createUser,
+			contentType: echo.MIMEApplicationForm,
+			body:        "name=user3",
+			wantStatus:  http.StatusCreated,
+			wantBody:    `{"id":"3","name":"user3","type":"normal"}`,
+		},
+		{
+			name:        "duplicated name",
+			method:      http.MethodPost,
+			handler:     createUser,
+			contentType: echo.MIMEApplicationJSON,
+			body:        `{"name":"USER1"}`,
+			wantStatus:  http.StatusConflict,
+		},
+		{
+			name:        "malformed json",
+			method:      http.MethodPost,
+			handler:     createUser,
+			contentType: echo.MIMEApplicationJSON,
+			body:        `{"name":`,
+			wantStatus:  http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpUserApplicationService()
+			e := echo.New()
+			target := tt.target
+			if target == "" {
+				target = "/"
+			}
+			req := httptest.NewRequest(tt.method, target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set(echo.HeaderContentType, tt.contentType)
+			}
+			if tt.accept != "" {
+				req.Header.Set(echo.HeaderAccept, tt.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			assert.Nil(t, tt.handler(e.NewContext(req, rec)))
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantBody != "" {
+				assert.Equal(t, strings.TrimSuffix(tt.wantBody, "\n"), strings.TrimSuffix(rec.Body.String(), "\n"))
+			}
+		})
+	}
+}
+
+func TestGetUsersEmail(t *testing.T) {
+	tests := []struct {
+		name       string
+		viewer     string
+		role       model.UserRole
+		wantEmails []string
+	}{
+		{name: "anonymous", wantEmails: []string{"", ""}},
+		{name: "the user", viewer: "1", role: model.USER_ROLE_MEMBER, wantEmails: []string{"user1@example.com", ""}},
+		{name: "an admin", viewer: "2", role: model.USER_ROLE_ADMIN, wantEmails: []string{"user1@example.com", "user2@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpAuthApplicationService()
+			for _, id := range []string{"1", "2"} {
+				user, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: id})
+				user.Email = model.Email{V: "user" + id + "@example.com"}
+				if id == tt.viewer {
+					user.Role = tt.role
+				}
+				assert.Nil(t, userApplicationService.UserRepository.Save(*user))
+			}
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/?sort=id", nil)
+			if tt.viewer != "" {
+				token, _ := testJWT.Sign(model.UserId{V: tt.viewer}, time.Now(), time.Now().Add(time.Hour))
+				req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+
+			assert.Nil(t, authenticate(getUsers)(e.NewContext(req, rec)))
+			assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			var response model.UserListResponseModel
+			assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			emails := []string{}
+			for _, user := range response.Users {
+				emails = append(emails, user.Email)
+				assert.Equal(t, user.Email != "", user.EmailVerified != nil, fmt.Sprintf("emailVerified of user %s", user.Id))
+			}
+			assert.Equal(t, tt.wantEmails, emails)
+		})
+	}
+}
+
+func TestUserHandlersUnsupportedFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		accept     string
+		handler    echo.HandlerFunc
+		wantStatus int
+	}{
+		{name: "create with unknown format", method: http.MethodPost, target: "/?format=xml", handler: createUser, wantStatus: http.StatusBadRequest},
+		{name: "create with unacceptable media type", method: http.MethodPost, accept: "application/xml", handler: createUser, wantStatus: http.StatusNotAcceptable},
+		{name: "update with unknown format", method: http.MethodPut, target: "/?format=xml", handler: updateUser, wantStatus: http.StatusBadRequest},
+		{name: "update with unacceptable media type", method: http.MethodPut, accept: "application/xml", handler: updateUser, wantStatus: http.StatusNotAcceptable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpUserApplicationService()
+			e := echo.New()
+			target := tt.target
+			if target == "" {
+				target = "/"
+			}
+			req := httptest.NewRequest(tt.method, target, strings.NewReader(`{"name":"renamed"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.accept != "" {
+				req.Header.Set(echo.HeaderAccept, tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+			actor, _ := model.NewActor(model.UserId{V: "1"}, model.USER_ROLE_MEMBER)
+			c.Set(actorKey, actor)
+
+			assert.Nil(t, tt.handler(c))
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			users, _ := userApplicationService.UserRepository.FindAll()
+			assert.Equal(t, 2, len(*users), "a user was created")
+			user, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "1"})
+			assert.Equal(t, "user1", user.Name.V, "the user was updated")
+		})
+	}
+}
+
+func TestCreateCircle(t *testing.T) {
+	setUpCircleApplicationService()
+	token, _ := testJWT.Sign(model.UserId{V: "3"}, time.Now(), time.Now().Add(time.Hour))
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=circle2"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	rec := httptest.NewRecorder()
+
+	assert.Nil(t, authenticate(createCircle)(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var response model.CircleResponseModel
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "circle2", response.Name)
+	assert.Equal(t, "3", response.Owner)
+	assert.Equal(t, "/circles/"+response.Id, rec.Header().Get(echo.HeaderLocation))
+	assert.NotEmpty(t, rec.Header().Get(HeaderETag))
+}
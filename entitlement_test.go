@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	inMemoryInfrastructure "uyutaka.com/ddd-bottom-up/InMemoryInfrastructure"
+	"uyutaka.com/ddd-bottom-up/application"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func setUpEntitlementApplicationService() {
+	setUpAuthApplicationService()
+	admin, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "2"})
+	admin.GrantAdmin()
+	_ = userApplicationService.UserRepository.Save(*admin)
+	overrideRepository := inMemoryInfrastructure.NewSliceEntitlementOverrideRepository()
+	testUnitOfWork.Register(overrideRepository.Storage)
+	entitlements := model.NewEntitlementService(model.DefaultPlanRegistry, &overrideRepository)
+	entitlementApplicationService = application.NewEntitlementApplicationService(userApplicationService.UserRepository, &overrideRepository, entitlements, testUnitOfWork, inMemoryInfrastructure.NewWriterAuditLog(io.Discard))
+}
+
+func TestOverrideEntitlements(t *testing.T) {
+	tests := []struct {
+		name       string
+		actorId    string
+		body       string
+		wantStatus int
+		want       model.EntitlementsResponseModel
+	}{
+		{
+			name:       "overridden by an admin",
+			actorId:    "2",
+			body:       `{"circleCapacity":100,"granted":["private_circles"],"reason":"partner"}`,
+			wantStatus: http.StatusOK,
+			want:       model.EntitlementsResponseModel{UserId: "1", Plan: "normal", MaxCircles: 3, CircleCapacity: 100, Features: []string{"events", "private_circles"}, Overridden: true},
+		},
+		{name: "by the user themself", actorId: "1", body: `{"circleCapacity":100,"reason":"me"}`, wantStatus: http.StatusForbidden},
+		{name: "no reason", actorId: "2", body: `{"circleCapacity":100}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "unknown feature", actorId: "2", body: `{"granted":["Everything"],"reason":"partner"}`, wantStatus: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpEntitlementApplicationService()
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			token, _ := testJWT.Sign(model.UserId{V: tt.actorId}, time.Now(), time.Now().Add(time.Hour))
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			assert.Nil(t, authenticate(overrideEntitlements)(c))
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got model.EntitlementsResponseModel
+			assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+// etag tags a version of an aggregate, such as "3" for the third save of a user
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func setETag(c echo.Context, version int) {
+	c.Response().Header().Set(HeaderETag, etag(version))
+}
+
+// ifMatch returns the version in the If-Match header. It is nil without the header or with *,
+// which change any version. Tags which are not versions never match, so they are returned as -1.
+func ifMatch(c echo.Context) *int {
+	tag := strings.TrimSpace(c.Request().Header.Get(HeaderIfMatch))
+	if len(tag) == 0 || tag == "*" {
+		return nil
+	}
+	version := -1
+	if len(tag) > 2 && strings.HasPrefix(tag, `"`) && strings.HasSuffix(tag, `"`) {
+		if v, err := strconv.Atoi(tag[1 : len(tag)-1]); err == nil && v >= 0 {
+			version = v
+		}
+	}
+	return &version
+}
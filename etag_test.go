@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestGetUserETag(t *testing.T) {
+	setUpUserApplicationService()
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	assert.Nil(t, getUser(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"0"`, rec.Header().Get(HeaderETag))
+}
+
+func TestUpdateUserIfMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		ifMatch    string
+		saves      int
+		wantStatus int
+		wantETag   string
+	}{
+		{name: "without If-Match", saves: 1, wantStatus: http.StatusOK, wantETag: `"2"`},
+		{name: "any version", ifMatch: "*", saves: 1, wantStatus: http.StatusOK, wantETag: `"2"`},
+		{name: "current version", ifMatch: `"0"`, wantStatus: http.StatusOK, wantETag: `"1"`},
+		{name: "stale version", ifMatch: `"0"`, saves: 1, wantStatus: http.StatusPreconditionFailed},
+		{name: "not a version", ifMatch: `W/"abc"`, wantStatus: http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpAuthApplicationService()
+			// another client saves the user before the update
+			for i := 0; i < tt.saves; i++ {
+				user, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "1"})
+				assert.Nil(t, userApplicationService.UserRepository.Save(*user))
+			}
+			token, _ := testJWT.Sign(model.UserId{V: "1"}, time.Now(), time.Now().Add(time.Hour))
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"name":"renamed"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			if tt.ifMatch != "" {
+				req.Header.Set(HeaderIfMatch, tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			assert.Nil(t, authenticate(updateUser)(c))
+			assert.Equal(t, tt.wantStatus, rec.Code, fmt.Sprintf("status with If-Match %s", tt.ifMatch))
+			assert.Equal(t, tt.wantETag, rec.Header().Get(HeaderETag))
+		})
+	}
+}
+
+func TestJoinCircleIfMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		ifMatch    string
+		saves      int
+		wantStatus int
+		wantJoined bool
+	}{
+		{name: "without If-Match", saves: 1, wantStatus: http.StatusOK, wantJoined: true},
+		{name: "current version", ifMatch: `"0"`, wantStatus: http.StatusOK, wantJoined: true},
+		{name: "stale version", ifMatch: `"0"`, saves: 1, wantStatus: http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			circleRepository := setUpCircleApplicationService()
+			// another client saves the circle before the join
+			for i := 0; i < tt.saves; i++ {
+				circle, _ := circleRepository.FindById(model.CircleId{V: "1"})
+				assert.Nil(t, circleRepository.Save(circle))
+			}
+			token, _ := testJWT.Sign(model.UserId{V: "3"}, time.Now(), time.Now().Add(time.Hour))
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			if tt.ifMatch != "" {
+				req.Header.Set(HeaderIfMatch, tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			assert.Nil(t, authenticate(joinCircle)(c))
+			assert.Equal(t, tt.wantStatus, rec.Code, fmt.Sprintf("status with If-Match %s", tt.ifMatch))
+			circle, _ := circleRepository.FindById(model.CircleId{V: "1"})
+			assert.Equal(t, tt.wantJoined, len(circle.Members()) == 2, fmt.Sprintf("members = %v", circle.Members()))
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+const icalTimeFormat = "20060102T150405Z"
+
+// encodeICalendar renders the events of the circle as an RFC 5545 calendar
+func encodeICalendar(circle model.Circle, events []model.Event, stamp time.Time) string {
+	var b strings.Builder
+	writeICalLine(&b, "BEGIN:VCALENDAR")
+	writeICalLine(&b, "VERSION:2.0")
+	writeICalLine(&b, "PRODID:-//ddd-bottom-up//circle events//EN")
+	writeICalLine(&b, "CALSCALE:GREGORIAN")
+	writeICalLine(&b, "X-WR-CALNAME:"+escapeICalText(circle.Name().V))
+	for _, event := range events {
+		writeICalLine(&b, "BEGIN:VEVENT")
+		writeICalLine(&b, "UID:event-"+event.Id.V+"@circle-"+event.CircleId.V+".ddd-bottom-up")
+		writeICalLine(&b, "DTSTAMP:"+stamp.UTC().Format(icalTimeFormat))
+		writeICalLine(&b, "DTSTART:"+event.Start.UTC().Format(icalTimeFormat))
+		writeICalLine(&b, "DTEND:"+event.End.UTC().Format(icalTimeFormat))
+		writeICalLine(&b, "SUMMARY:"+escapeICalText(event.Title.V))
+		if len(event.Location) != 0 {
+			writeICalLine(&b, "LOCATION:"+escapeICalText(event.Location))
+		}
+		writeICalLine(&b, "END:VEVENT")
+	}
+	writeICalLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+func escapeICalText(v string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(v)
+}
+
+// lines longer than 75 octets are folded without splitting a multibyte character
+func writeICalLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space of a continuation line counts towards the limit
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestEncodeICalendar(t *testing.T) {
+	id, _ := model.NewCircleId("1")
+	name, _ := model.NewCircleName("circle1")
+	owner, _ := model.NewUserId("1")
+	circle, _ := model.NewCircle(&id, &name, &owner, []model.UserId{})
+
+	start := time.Date(2023, 8, 1, 19, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+	event, _ := model.NewEvent(model.EventId{V: "3"}, id, model.EventTitle{V: "Meetup, vol.1"}, start, start.Add(2*time.Hour), "Shibuya; 5F", 10)
+	stamp := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	got := encodeICalendar(circle, []model.Event{event}, stamp)
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//ddd-bottom-up//circle events//EN",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:circle1",
+		"BEGIN:VEVENT",
+		"UID:event-3@circle-1.ddd-bottom-up",
+		"DTSTAMP:20230701T000000Z",
+		"DTSTART:20230801T100000Z",
+		"DTEND:20230801T120000Z",
+		`SUMMARY:Meetup\, vol.1`,
+		`LOCATION:Shibuya\; 5F`,
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	assert.Equal(t, want, got, fmt.Sprintf("encodeICalendar() = %q, want %q", got, want))
+}
+
+func TestWriteICalLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "short line",
+			line: "SUMMARY:short",
+			want: "SUMMARY:short\r\n",
+		},
+		{
+			name: "folded line",
+			line: "SUMMARY:" + strings.Repeat("a", 80),
+			want: "SUMMARY:" + strings.Repeat("a", 67) + "\r\n " + strings.Repeat("a", 13) + "\r\n",
+		},
+		{
+			name: "multibyte characters are not split",
+			line: "SUMMARY:" + strings.Repeat("あ", 30),
+			want: "SUMMARY:" + strings.Repeat("あ", 22) + "\r\n " + strings.Repeat("あ", 8) + "\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			writeICalLine(&b, tt.line)
+			assert.Equal(t, tt.want, b.String(),
+				fmt.Sprintf("writeICalLine() = %q, want %q", b.String(), tt.want))
+		})
+	}
+}
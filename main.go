@@ -1,6 +1,12 @@
 package main
 
 import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/labstack/echo"
 	inMemoryInfrastructure "uyutaka.com/ddd-bottom-up/InMemoryInfrastructure"
 	"uyutaka.com/ddd-bottom-up/application"
@@ -8,33 +14,312 @@ import (
 )
 
 var (
-	userApplicationService application.UserApplicationService
+	userApplicationService   application.UserApplicationService
+	circleApplicationService model.CircleApplicationService
+	eventApplicationService  application.EventApplicationService
+	postApplicationService   application.PostApplicationService
+	authApplicationService   application.AuthApplicationService
+	adminApplicationService  application.AdminApplicationService
+
+	subscriptionApplicationService application.SubscriptionApplicationService
+	entitlementApplicationService  application.EntitlementApplicationService
+	billingApplicationService      application.BillingApplicationService
 )
 
 func main() {
+	// USER_NAME_COMPARISON=exact|case-insensitive|normalized decides which names are duplicated
+	nameComparison, err := model.NewUserNameComparison(getenv("USER_NAME_COMPARISON", model.USER_NAME_COMPARISON_NORMALIZED.V))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// USER_NAME_MAX_LENGTH=30 and USER_NAME_RESERVED=admin,root,... override the rules of user names
+	namePolicy := model.NewDefaultUserNamePolicy()
+	if v, ok := os.LookupEnv("USER_NAME_MAX_LENGTH"); ok {
+		maxLength, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatal("USER_NAME_MAX_LENGTH must be a number")
+		}
+		namePolicy.MaxLength = maxLength
+	}
+	if v, ok := os.LookupEnv("USER_NAME_RESERVED"); ok {
+		namePolicy.Reserved = strings.Split(v, ",")
+	}
+
+	// PLANS_FILE=plans.json adds plans such as team or enterprise, or changes the entitlements of the built-in ones
+	if path, ok := os.LookupEnv("PLANS_FILE"); ok {
+		if err := inMemoryInfrastructure.LoadPlans(path, model.DefaultPlanRegistry); err != nil {
+			log.Fatal("PLANS_FILE: ", err)
+		}
+	}
+
 	repo := inMemoryInfrastructure.NewSliceUserRepository()
-	userService := model.NewUserService(&repo)
+	userService := model.NewUserService(&repo, nameComparison, namePolicy)
 	// TODO use DI
-	userFactory := inMemoryInfrastructure.NewUserFactory(repo.Storage)
+	userFactory := inMemoryInfrastructure.NewUserFactory(repo.Storage, namePolicy)
 	userRepository := &repo
-	userApplicationService = application.NewUserApplicationService(userService, &userFactory, userRepository)
+	// MAIL_FILE=mails.txt keeps outbound mails in a file instead of printing them
+	var mailer application.IMailer = inMemoryInfrastructure.NewStdoutMailer()
+	if path, ok := os.LookupEnv("MAIL_FILE"); ok {
+		mailer, err = inMemoryInfrastructure.NewFileMailer(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	// AUDIT_LOG_FILE=audit.log keeps denied decisions in a file instead of printing them
+	var auditLog model.IAuditLog = inMemoryInfrastructure.NewStderrAuditLog()
+	if path, ok := os.LookupEnv("AUDIT_LOG_FILE"); ok {
+		auditLog, err = inMemoryInfrastructure.NewFileAuditLog(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	planChangeRepository := inMemoryInfrastructure.NewSlicePlanChangeRepository()
+	promoCodeRepository := inMemoryInfrastructure.NewSlicePromoCodeRepository()
+	subscriptionRepository := inMemoryInfrastructure.NewSliceSubscriptionRepository()
+	subscriptionFactory := inMemoryInfrastructure.NewSubscriptionFactory(subscriptionRepository.Storage)
+	// every storage is registered to the unit of work, so that a failing use case leaves none of its writes
+	unitOfWork := inMemoryInfrastructure.NewInMemoryUnitOfWork(repo.Storage, planChangeRepository.Storage, promoCodeRepository.Storage, subscriptionRepository.Storage)
+	// use cases which lose to another writer run again, except for subscriptions, which must not take payments twice
+	retryingUnitOfWork := application.NewRetryingUnitOfWork(unitOfWork, application.DefaultRetryPolicy)
+	userApplicationService = application.NewUserApplicationService(userService, &userFactory, userRepository, &planChangeRepository, &promoCodeRepository, &subscriptionRepository, &subscriptionFactory, mailer, retryingUnitOfWork, auditLog)
+	userApplicationService.OnPlanChanged(application.PlanChangedHandlerFunc(logPlanChange))
+
+	adminApplicationService = application.NewAdminApplicationService(userRepository, &promoCodeRepository, retryingUnitOfWork, auditLog)
+	// ADMIN_USER_IDS=1,2 grants the admin role to existing users
+	if v, ok := os.LookupEnv("ADMIN_USER_IDS"); ok {
+		for _, id := range strings.Split(v, ",") {
+			user, err := userRepository.FindById(&model.UserId{V: id})
+			if err != nil || user == nil {
+				log.Fatal("ADMIN_USER_IDS: user " + id + " not found")
+			}
+			user.GrantAdmin()
+			if err := userRepository.Save(*user); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	credentialRepository := inMemoryInfrastructure.NewSliceCredentialRepository()
+	sessionRepository := inMemoryInfrastructure.NewSliceSessionRepository()
+	unitOfWork.Register(credentialRepository.Storage, sessionRepository.Storage)
+	// JWT_SECRET enables HS256 JWTs whose subject is the user id, and JWT_ISSUER restricts their issuer
+	var accessTokenVerifier application.IAccessTokenVerifier
+	if secret, ok := os.LookupEnv("JWT_SECRET"); ok {
+		accessTokenVerifier = inMemoryInfrastructure.NewHS256JWT([]byte(secret), os.Getenv("JWT_ISSUER"))
+	}
+	authApplicationService = application.NewAuthApplicationService(userRepository, &credentialRepository, &sessionRepository, inMemoryInfrastructure.NewArgon2idHasher(), accessTokenVerifier, retryingUnitOfWork, auditLog)
+	authApplicationService.UserNamePolicy = namePolicy
+	userApplicationService.CredentialRepository = &credentialRepository
+	userApplicationService.PasswordHasher = authApplicationService.PasswordHasher
+
+	invoiceRepository := inMemoryInfrastructure.NewSliceInvoiceRepository()
+	invoiceFactory := inMemoryInfrastructure.NewInvoiceFactory(invoiceRepository.Storage)
+	ledgerRepository := inMemoryInfrastructure.NewSliceLedgerRepository()
+	unitOfWork.Register(invoiceRepository.Storage, ledgerRepository.Storage)
+	paymentGateway := inMemoryInfrastructure.NewFakePaymentGateway()
+	// PAYMENT_DECLINED_USER_IDS=3,4 makes the fake payment gateway decline every payment of the users
+	if v, ok := os.LookupEnv("PAYMENT_DECLINED_USER_IDS"); ok {
+		for _, id := range strings.Split(v, ",") {
+			paymentGateway.Decline(model.UserId{V: id}, -1)
+		}
+	}
+	subscriptionApplicationService = application.NewSubscriptionApplicationService(userRepository, &subscriptionRepository, &subscriptionFactory, &planChangeRepository, &invoiceRepository, &invoiceFactory, &ledgerRepository, paymentGateway, unitOfWork, auditLog)
+	subscriptionApplicationService.OnPlanChanged(application.PlanChangedHandlerFunc(logPlanChange))
+	billingApplicationService = application.NewBillingApplicationService(userRepository, &invoiceRepository, &ledgerRepository, auditLog)
+	// SUBSCRIPTION_EXPIRY_INTERVAL=1m decides how often due subscriptions are renewed or downgraded, and declined payments retried
+	expiryInterval, err := time.ParseDuration(getenv("SUBSCRIPTION_EXPIRY_INTERVAL", "1m"))
+	if err != nil || expiryInterval <= 0 {
+		log.Fatal("SUBSCRIPTION_EXPIRY_INTERVAL must be a positive duration")
+	}
+	go expireSubscriptions(time.NewTicker(expiryInterval).C)
+
+	entitlementOverrideRepository := inMemoryInfrastructure.NewSliceEntitlementOverrideRepository()
+	unitOfWork.Register(entitlementOverrideRepository.Storage)
+	entitlementService := model.NewEntitlementService(model.DefaultPlanRegistry, &entitlementOverrideRepository)
+	entitlementApplicationService = application.NewEntitlementApplicationService(userRepository, &entitlementOverrideRepository, entitlementService, retryingUnitOfWork, auditLog)
+
+	circleRepository := inMemoryInfrastructure.NewSliceCircleRepository()
+	circleFactory := inMemoryInfrastructure.NewCircleFactory(circleRepository.Storage)
+	unitOfWork.Register(circleRepository.Storage)
+	circleService := model.NewCircleService(&circleRepository)
+	circleApplicationService = model.NewCircleApplicationService(&circleFactory, &circleRepository, circleService, userRepository, entitlementService, retryingUnitOfWork, auditLog, time.Now())
+	eventRepository := inMemoryInfrastructure.NewSliceEventRepository()
+	eventFactory := inMemoryInfrastructure.NewEventFactory(eventRepository.Storage)
+	unitOfWork.Register(eventRepository.Storage)
+	eventApplicationService = application.NewEventApplicationService(&eventFactory, &eventRepository, &circleRepository, userRepository, entitlementService, retryingUnitOfWork, auditLog)
+	postRepository := inMemoryInfrastructure.NewSlicePostRepository()
+	postFactory := inMemoryInfrastructure.NewPostFactory(postRepository.Storage)
+	unitOfWork.Register(postRepository.Storage)
+	postApplicationService = application.NewPostApplicationService(&postFactory, &postRepository, &circleRepository, userRepository, retryingUnitOfWork, auditLog)
 
 	e := echo.New()
+	e.HTTPErrorHandler = problemErrorHandler
+	// every route is served to anonymous users unless the use case requires an actor
+	e.Use(authenticate)
 
 	// curl localhost:1323
+	// curl -H 'Accept: text/csv' localhost:1323
+	// curl localhost:1323?format=text
+	// curl 'localhost:1323?limit=10&sort=-name&type=premium&name_prefix=user'
 	e.GET("/", getUsers)
 
 	// curl localhost:1323/1
 	e.GET("/:id", getUser)
 
-	// curl -X POST --data-urlencode 'name=xxxx' localhost:1323
+	// curl -X POST -H 'Content-Type: application/json' -d '{"name":"xxxx"}' localhost:1323
+	// curl -X POST -H 'Content-Type: application/json' -d '{"name":"xxxx","password":"Secret-pass1"}' localhost:1323
+	// curl -X POST --data-urlencode 'name=xxxx' 'localhost:1323?format=text'
 	e.POST("/", createUser)
 
-	// curl -X PUT --data-urlencode 'name=updated!' localhost:1323/1
+	// curl -X PUT -H "Authorization: Bearer $TOKEN" -H 'Content-Type: application/json' -d '{"name":"updated"}' localhost:1323/1
+	// curl -X PUT -H "Authorization: Bearer $TOKEN" --data-urlencode 'name=updated' 'localhost:1323/1?format=text'
+	// curl -X PUT -H "Authorization: Bearer $TOKEN" -H 'If-Match: "0"' --data-urlencode 'name=updated' 'localhost:1323/1?format=text'
 	e.PUT("/:id", updateUser)
 
-	// curl -X DELETE localhost:1323/1
+	// curl -X DELETE -H "Authorization: Bearer $TOKEN" 'localhost:1323/1?format=text'
 	e.DELETE("/:id", deleteUser)
 
+	// curl -X PUT -H "Authorization: Bearer $TOKEN" --data-urlencode 'email=user1@example.com' localhost:1323/1/email
+	e.PUT("/:id/email", changeEmail)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" localhost:1323/1/email/verification
+	e.POST("/:id/email/verification", issueEmailVerification)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" --data-urlencode 'token=xxxx' localhost:1323/1/email/verify
+	e.POST("/:id/email/verify", verifyEmail)
+
+	// curl -X PUT -H "Authorization: Bearer $TOKEN" --data-urlencode 'current_password=Secret-pass1' --data-urlencode 'password=Secret-pass2' localhost:1323/3/password
+	e.PUT("/:id/password", setPassword)
+
+	// TOKEN=$(curl -s -X POST --data-urlencode 'name=xxxx' --data-urlencode 'password=Secret-pass1' 'localhost:1323/login?format=text')
+	e.POST("/login", login)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" localhost:1323/logout
+	e.POST("/logout", logout)
+
+	// curl 'localhost:1323/plans?format=text'
+	e.GET("/plans", getPlans)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" --data-urlencode 'months=1' --data-urlencode 'auto_renew=true' localhost:1323/1/subscription
+	e.POST("/:id/subscription", startSubscription)
+
+	// curl -H "Authorization: Bearer $TOKEN" localhost:1323/1/subscription
+	e.GET("/:id/subscription", getSubscription)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" --data-urlencode 'months=12' localhost:1323/1/subscription/renewal
+	e.POST("/:id/subscription/renewal", renewSubscription)
+
+	// curl -X DELETE -H "Authorization: Bearer $TOKEN" localhost:1323/1/subscription
+	e.DELETE("/:id/subscription", cancelSubscription)
+
+	// curl -H "Authorization: Bearer $TOKEN" 'localhost:1323/1/plan-changes?format=text'
+	e.GET("/:id/plan-changes", getPlanHistory)
+
+	// curl -H "Authorization: Bearer $TOKEN" 'localhost:1323/1/invoices?format=text'
+	e.GET("/:id/invoices", getInvoices)
+
+	// curl -H "Authorization: Bearer $TOKEN" 'localhost:1323/1/ledger?format=text'
+	e.GET("/:id/ledger", getLedger)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" --data-urlencode 'reason=sponsor' localhost:1323/admin/users/1/upgrade
+	e.POST("/admin/users/:id/upgrade", upgradeUser)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" localhost:1323/admin/users/2/downgrade
+	e.POST("/admin/users/:id/downgrade", downgradeUser)
+
+	// curl -H "Authorization: Bearer $TOKEN" localhost:1323/1/entitlements
+	e.GET("/:id/entitlements", getEntitlements)
+
+	// curl -X PUT -H "Authorization: Bearer $TOKEN" -H 'Content-Type: application/json' -d '{"circleCapacity":100,"granted":["private_circles"],"reason":"partner"}' localhost:1323/admin/users/1/entitlements
+	e.PUT("/admin/users/:id/entitlements", overrideEntitlements)
+
+	// curl -X DELETE -H "Authorization: Bearer $TOKEN" localhost:1323/admin/users/1/entitlements
+	e.DELETE("/admin/users/:id/entitlements", clearEntitlementOverride)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" --data-urlencode 'code=WELCOME-2023' localhost:1323/1/promo-code
+	e.POST("/:id/promo-code", redeemPromoCode)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" --data-urlencode 'code=WELCOME-2023' --data-urlencode 'plan=premium' --data-urlencode 'trial_days=14' --data-urlencode 'max_redemptions=100' --data-urlencode 'expires_at=2023-12-31T23:59:59+09:00' localhost:1323/admin/promo-codes
+	e.POST("/admin/promo-codes", createPromoCode)
+
+	// curl -H "Authorization: Bearer $TOKEN" localhost:1323/admin/users/suspended
+	e.GET("/admin/users/suspended", getSuspendedUsers)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" --data-urlencode 'reason=spam' localhost:1323/admin/users/2/suspend
+	e.POST("/admin/users/:id/suspend", suspendUser)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" --data-urlencode 'reason=appeal accepted' localhost:1323/admin/users/2/reinstate
+	e.POST("/admin/users/:id/reinstate", reinstateUser)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" --data-urlencode 'reason=requested by the user' localhost:1323/admin/users/2/deactivate
+	e.POST("/admin/users/:id/deactivate", deactivateUser)
+
+	// curl -H 'Accept: text/csv' localhost:1323/circles
+	e.GET("/circles", getCircles)
+
+	// curl localhost:1323/circles/1?format=text
+	e.GET("/circles/:id", getCircle)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" --data-urlencode 'name=circle2' --data-urlencode 'private=true' localhost:1323/circles
+	e.POST("/circles", createCircle)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" -H 'If-Match: "0"' localhost:1323/circles/1/members
+	e.POST("/circles/:id/members", joinCircle)
+
+	// curl -X DELETE -H "Authorization: Bearer $TOKEN" localhost:1323/circles/1/members/2
+	e.DELETE("/circles/:id/members/:memberId", kickMember)
+
+	// curl -X PUT -H "Authorization: Bearer $TOKEN" localhost:1323/circles/1/moderators/2
+	e.PUT("/circles/:id/moderators/:memberId", appointModerator)
+
+	// curl 'localhost:1323/circles/1/posts?format=text'
+	e.GET("/circles/:id/posts", getPosts)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" --data-urlencode 'body=hello' localhost:1323/circles/1/posts
+	e.POST("/circles/:id/posts", createPost)
+
+	// curl -X PUT -H "Authorization: Bearer $TOKEN" --data-urlencode 'body=hello again' localhost:1323/circles/1/posts/1
+	e.PUT("/circles/:id/posts/:postId", editPost)
+
+	// curl -X DELETE -H "Authorization: Bearer $TOKEN" localhost:1323/circles/1/posts/1
+	e.DELETE("/circles/:id/posts/:postId", deletePost)
+
+	// curl -X POST -H "Authorization: Bearer $TOKEN" --data-urlencode 'title=meetup' --data-urlencode 'start=2023-08-01T19:00:00+09:00' --data-urlencode 'end=2023-08-01T21:00:00+09:00' --data-urlencode 'capacity=10' localhost:1323/circles/1/events
+	e.POST("/circles/:id/events", scheduleEvent)
+
+	// curl -X PUT -H "Authorization: Bearer $TOKEN" --data-urlencode 'answer=yes' localhost:1323/circles/1/events/1/rsvp
+	e.PUT("/circles/:id/events/:eventId/rsvp", rsvpEvent)
+
+	// curl localhost:1323/circles/1/events.ics
+	e.GET("/circles/:id/events.ics", exportEvents)
+
 	e.Logger.Fatal(e.Start(":1323"))
 }
+
+func getenv(key string, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
+
+// expireSubscriptions renews or expires the subscriptions which are due at every tick,
+// and retries the payments which were declined
+func expireSubscriptions(ticks <-chan time.Time) {
+	for now := range ticks {
+		_, err := subscriptionApplicationService.ExpireDue(application.SubscriptionExpireCommand{Now: now})
+		if err != nil {
+			log.Println("expiring subscriptions:", err)
+		}
+		_, err = subscriptionApplicationService.RetryPayments(application.SubscriptionRetryPaymentsCommand{Now: now})
+		if err != nil {
+			log.Println("retrying payments:", err)
+		}
+	}
+}
+
+func logPlanChange(change model.PlanChange) error {
+	log.Println("user " + change.UserId.V + " changed from " + change.From.V + " to " + change.To.V + ": " + change.Reason)
+	return nil
+}
@@ -0,0 +1,40 @@
+package model
+
+type (
+	// Actor is the user performing a use case.
+	// It is authenticated by the presentation layer and handed to the use case with its command.
+	Actor struct {
+		UserId UserId
+		Role   UserRole
+	}
+)
+
+// ANONYMOUS_ACTOR performs use cases open to everyone, such as registration and login
+var ANONYMOUS_ACTOR = Actor{}
+
+func NewActor(userId UserId, role UserRole) (Actor, error) {
+	if len(userId.V) == 0 {
+		return Actor{}, NewValidationError("userId", "is required")
+	}
+	return Actor{UserId: userId, Role: role}, nil
+}
+
+func (a Actor) IsAnonymous() bool {
+	return len(a.UserId.V) == 0
+}
+
+func (a Actor) IsAdmin() bool {
+	return !a.IsAnonymous() && a.Role == USER_ROLE_ADMIN
+}
+
+func (a Actor) Is(id UserId) bool {
+	return !a.IsAnonymous() && a.UserId.V == id.V
+}
+
+// Authenticated fails for the anonymous actor
+func (a Actor) Authenticated() error {
+	if a.IsAnonymous() {
+		return NewAuthenticationError("authentication required")
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+package model
+
+import (
+	"strconv"
+	"time"
+)
+
+var (
+	INVOICE_STATUS_OPEN   = InvoiceStatus{V: "open"}
+	INVOICE_STATUS_PAID   = InvoiceStatus{V: "paid"}
+	INVOICE_STATUS_FAILED = InvoiceStatus{V: "failed"}
+	INVOICE_STATUS_VOID   = InvoiceStatus{V: "void"}
+
+	LEDGER_ENTRY_KIND_CHARGE    = LedgerEntryKind{V: "charge"}
+	LEDGER_ENTRY_KIND_PAYMENT   = LedgerEntryKind{V: "payment"}
+	LEDGER_ENTRY_KIND_WRITE_OFF = LedgerEntryKind{V: "write_off"}
+)
+
+// waits before the retries of a failed payment. The payment fails for good when the last retry fails.
+var InvoiceRetryIntervals = []time.Duration{24 * time.Hour, 3 * 24 * time.Hour, 7 * 24 * time.Hour}
+
+type (
+	InvoiceId struct {
+		V string
+	}
+
+	// open invoices are waiting for their payment. Failed and void invoices are never paid.
+	InvoiceStatus struct {
+		V string
+	}
+
+	// Aggregate Root
+	// Invoice bills a user for Months of Plan. Amounts are in yen.
+	// Invoices which can be retried are paid again on NextAttempt after their payment failed.
+	// The invoice of a new subscription is linked to it after it is paid, as the subscription starts then.
+	Invoice struct {
+		Id             InvoiceId
+		UserId         UserId
+		SubscriptionId SubscriptionId
+		Plan           UserType
+		Months         int
+		Amount         int
+		Status         InvoiceStatus
+		Retry          bool
+		Attempts       int
+		NextAttempt    time.Time
+		PaymentId      string
+		FailureReason  string
+		Issued         time.Time
+		Closed         time.Time
+	}
+
+	IInvoiceRepository interface {
+		Save(invoice Invoice) error
+		FindById(id *InvoiceId) (*Invoice, error)
+		// invoices of the user in the order they were issued
+		FindByUserId(id *UserId) ([]Invoice, error)
+		// the open invoice of the subscription, if any
+		FindOpenBySubscriptionId(id *SubscriptionId) (*Invoice, error)
+		// open invoices whose next attempt is at or before t
+		FindRetryDue(t time.Time) ([]Invoice, error)
+	}
+
+	IInvoiceFactory interface {
+		// subscriptionId is empty for a new subscription
+		Create(userId *UserId, subscriptionId SubscriptionId, plan *UserType, months int, amount int, retry bool, issued time.Time) (*Invoice, error)
+	}
+
+	LedgerEntryKind struct {
+		V string
+	}
+
+	// LedgerEntry is a line of the ledger of a user.
+	// Charges are what the user owes, and payments and write-offs settle them.
+	LedgerEntry struct {
+		UserId      UserId
+		InvoiceId   InvoiceId
+		Kind        LedgerEntryKind
+		Amount      int
+		Description string
+		Recorded    time.Time
+	}
+
+	Ledger struct {
+		UserId  UserId
+		Entries []LedgerEntry
+	}
+
+	ILedgerRepository interface {
+		// entries are only appended
+		Save(entry LedgerEntry) error
+		FindByUserId(id *UserId) (*Ledger, error)
+	}
+
+	InvoiceResponseModel struct {
+		Id             string `json:"id"`
+		UserId         string `json:"userId"`
+		SubscriptionId string `json:"subscriptionId"`
+		Plan           string `json:"plan"`
+		Months         int    `json:"months"`
+		Amount         int    `json:"amount"`
+		Status         string `json:"status"`
+		Attempts       int    `json:"attempts"`
+		NextAttempt    string `json:"nextAttempt,omitempty"`
+		FailureReason  string `json:"failureReason,omitempty"`
+		Issued         string `json:"issued"`
+	}
+
+	InvoiceListResponseModel struct {
+		Invoices []InvoiceResponseModel `json:"invoices"`
+	}
+
+	LedgerEntryResponseModel struct {
+		InvoiceId   string `json:"invoiceId"`
+		Kind        string `json:"kind"`
+		Amount      int    `json:"amount"`
+		Description string `json:"description"`
+		Recorded    string `json:"recorded"`
+	}
+
+	LedgerResponseModel struct {
+		UserId  string                     `json:"userId"`
+		Balance int                        `json:"balance"`
+		Entries []LedgerEntryResponseModel `json:"entries"`
+	}
+)
+
+func NewInvoiceId(v string) (InvoiceId, error) {
+	if len(v) == 0 {
+		return InvoiceId{}, NewValidationError("id", "is required")
+	}
+	return InvoiceId{V: v}, nil
+}
+
+func NewInvoice(id InvoiceId, userId UserId, subscriptionId SubscriptionId, plan UserType, months int, amount int, retry bool, issued time.Time) (Invoice, error) {
+	errs := NewValidationErrors()
+	if len(id.V) == 0 {
+		errs.Add("id", NewValidationError("id", "is required"))
+	}
+	if len(userId.V) == 0 {
+		errs.Add("userId", NewValidationError("userId", "is required"))
+	}
+	if _, err := NewSubscriptionMonths(months); err != nil {
+		errs.Add("months", err)
+	}
+	if amount < 0 {
+		errs.Add("amount", NewValidationError("amount", "must not be negative"))
+	}
+	if err := errs.Err(); err != nil {
+		return Invoice{}, err
+	}
+	return Invoice{
+		Id:             id,
+		UserId:         userId,
+		SubscriptionId: subscriptionId,
+		Plan:           plan,
+		Months:         months,
+		Amount:         amount,
+		Status:         INVOICE_STATUS_OPEN,
+		Retry:          retry,
+		Issued:         issued,
+	}, nil
+}
+
+// Link records the subscription started by the paid invoice
+func (i *Invoice) Link(subscriptionId SubscriptionId) error {
+	if i.Status != INVOICE_STATUS_PAID {
+		return NewConflictError("invoice", "is "+i.Status.V)
+	}
+	if len(i.SubscriptionId.V) != 0 {
+		return NewConflictError("invoice", "is already linked to subscription "+i.SubscriptionId.V)
+	}
+	i.SubscriptionId = subscriptionId
+	return nil
+}
+
+func (i *Invoice) IsOpen() bool {
+	return i.Status == INVOICE_STATUS_OPEN
+}
+
+// Pay closes the invoice with the payment taken by the gateway
+func (i *Invoice) Pay(paymentId string, at time.Time) error {
+	if !i.IsOpen() {
+		return NewConflictError("invoice", "is "+i.Status.V)
+	}
+	i.Attempts++
+	i.Status = INVOICE_STATUS_PAID
+	i.PaymentId = paymentId
+	i.NextAttempt = time.Time{}
+	i.Closed = at
+	return nil
+}
+
+// Fail records a declined payment. The invoice stays open until its next attempt
+// while it can be retried, and fails otherwise.
+func (i *Invoice) Fail(reason string, at time.Time) error {
+	if !i.IsOpen() {
+		return NewConflictError("invoice", "is "+i.Status.V)
+	}
+	i.Attempts++
+	i.FailureReason = reason
+	if i.Retry && i.Attempts <= len(InvoiceRetryIntervals) {
+		i.NextAttempt = at.Add(InvoiceRetryIntervals[i.Attempts-1])
+		return nil
+	}
+	i.Status = INVOICE_STATUS_FAILED
+	i.NextAttempt = time.Time{}
+	i.Closed = at
+	return nil
+}
+
+// Void closes an open invoice which no longer has to be paid, such as the one of an ended subscription
+func (i *Invoice) Void(at time.Time) error {
+	if !i.IsOpen() {
+		return NewConflictError("invoice", "is "+i.Status.V)
+	}
+	i.Status = INVOICE_STATUS_VOID
+	i.NextAttempt = time.Time{}
+	i.Closed = at
+	return nil
+}
+
+func (i *Invoice) IsRetryDue(now time.Time) bool {
+	return i.IsOpen() && !i.NextAttempt.IsZero() && !now.Before(i.NextAttempt)
+}
+
+func NewLedgerEntry(invoice Invoice, kind LedgerEntryKind, description string, recorded time.Time) LedgerEntry {
+	return LedgerEntry{
+		UserId:      invoice.UserId,
+		InvoiceId:   invoice.Id,
+		Kind:        kind,
+		Amount:      invoice.Amount,
+		Description: description,
+		Recorded:    recorded,
+	}
+}
+
+// Balance is what the user owes. It is 0 when every charge is settled.
+func (l *Ledger) Balance() int {
+	balance := 0
+	for _, entry := range l.Entries {
+		if entry.Kind == LEDGER_ENTRY_KIND_CHARGE {
+			balance += entry.Amount
+		} else {
+			balance -= entry.Amount
+		}
+	}
+	return balance
+}
+
+func NewInvoiceResponseModel(invoice Invoice) *InvoiceResponseModel {
+	response := &InvoiceResponseModel{
+		Id:             invoice.Id.V,
+		UserId:         invoice.UserId.V,
+		SubscriptionId: invoice.SubscriptionId.V,
+		Plan:           invoice.Plan.V,
+		Months:         invoice.Months,
+		Amount:         invoice.Amount,
+		Status:         invoice.Status.V,
+		Attempts:       invoice.Attempts,
+		FailureReason:  invoice.FailureReason,
+		Issued:         invoice.Issued.UTC().Format(time.RFC3339),
+	}
+	if !invoice.NextAttempt.IsZero() {
+		response.NextAttempt = invoice.NextAttempt.UTC().Format(time.RFC3339)
+	}
+	return response
+}
+
+func NewInvoiceListResponseModel(invoices []Invoice) *InvoiceListResponseModel {
+	responses := []InvoiceResponseModel{}
+	for _, invoice := range invoices {
+		responses = append(responses, *NewInvoiceResponseModel(invoice))
+	}
+	return &InvoiceListResponseModel{Invoices: responses}
+}
+
+func NewLedgerResponseModel(ledger Ledger) *LedgerResponseModel {
+	entries := []LedgerEntryResponseModel{}
+	for _, entry := range ledger.Entries {
+		entries = append(entries, LedgerEntryResponseModel{
+			InvoiceId:   entry.InvoiceId.V,
+			Kind:        entry.Kind.V,
+			Amount:      entry.Amount,
+			Description: entry.Description,
+			Recorded:    entry.Recorded.UTC().Format(time.RFC3339),
+		})
+	}
+	return &LedgerResponseModel{UserId: ledger.UserId.V, Balance: ledger.Balance(), Entries: entries}
+}
+
+func (m *InvoiceResponseModel) CSVHeader() []string {
+	return []string{"id", "userId", "subscriptionId", "plan", "months", "amount", "status", "attempts", "nextAttempt", "failureReason", "issued"}
+}
+
+func (m *InvoiceResponseModel) CSVRecords() [][]string {
+	return [][]string{{m.Id, m.UserId, m.SubscriptionId, m.Plan, strconv.Itoa(m.Months), strconv.Itoa(m.Amount), m.Status, strconv.Itoa(m.Attempts), m.NextAttempt, m.FailureReason, m.Issued}}
+}
+
+func (m *InvoiceResponseModel) Text() string {
+	return m.Id + " " + m.Plan + " " + strconv.Itoa(m.Months) + " months " + strconv.Itoa(m.Amount) + " yen " + m.Status
+}
+
+func (m *InvoiceListResponseModel) CSVHeader() []string {
+	return (&InvoiceResponseModel{}).CSVHeader()
+}
+
+func (m *InvoiceListResponseModel) CSVRecords() [][]string {
+	records := [][]string{}
+	for _, invoice := range m.Invoices {
+		records = append(records, invoice.CSVRecords()...)
+	}
+	return records
+}
+
+func (m *InvoiceListResponseModel) Text() string {
+	var output string
+	for _, invoice := range m.Invoices {
+		output += invoice.Text() + "\n"
+	}
+	return output
+}
+
+func (m *LedgerResponseModel) CSVHeader() []string {
+	return []string{"invoiceId", "kind", "amount", "description", "recorded"}
+}
+
+func (m *LedgerResponseModel) CSVRecords() [][]string {
+	records := [][]string{}
+	for _, entry := range m.Entries {
+		records = append(records, []string{entry.InvoiceId, entry.Kind, strconv.Itoa(entry.Amount), entry.Description, entry.Recorded})
+	}
+	return records
+}
+
+func (m *LedgerResponseModel) Text() string {
+	var output string
+	for _, entry := range m.Entries {
+		output += entry.Recorded + " " + entry.Kind + " " + strconv.Itoa(entry.Amount) + " " + entry.Description + "\n"
+	}
+	return output + "balance " + strconv.Itoa(m.Balance) + "\n"
+}
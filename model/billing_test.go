@@ -0,0 +1,91 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewInvoice(t *testing.T) {
+	tests := []struct {
+		name   string
+		userId UserId
+		months int
+		amount int
+		want   error
+	}{
+		{name: "normal", userId: UserId{"1"}, months: 1, amount: 500, want: nil},
+		{name: "free", userId: UserId{"1"}, months: 1, amount: 0, want: nil},
+		{name: "no user", userId: UserId{}, months: 1, amount: 500, want: ErrValidation},
+		{name: "no months", userId: UserId{"1"}, months: 0, amount: 500, want: ErrValidation},
+		{name: "negative amount", userId: UserId{"1"}, months: 1, amount: -1, want: ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewInvoice(InvoiceId{"1"}, tt.userId, SubscriptionId{"1"}, USER_TYPE_PREMIUM, tt.months, tt.amount, false, time.Now())
+			assert.Equal(t, true, errors.Is(err, tt.want),
+				fmt.Sprintf("NewInvoice() error = %v, want %v", err, tt.want))
+		})
+	}
+}
+
+func TestInvoice_Fail(t *testing.T) {
+	issued := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		retry        bool
+		failures     int
+		wantStatus   InvoiceStatus
+		wantRetryDue time.Time
+	}{
+		{name: "not retried", retry: false, failures: 1, wantStatus: INVOICE_STATUS_FAILED},
+		{name: "first retry", retry: true, failures: 1, wantStatus: INVOICE_STATUS_OPEN, wantRetryDue: issued.Add(InvoiceRetryIntervals[0])},
+		{name: "last retry", retry: true, failures: len(InvoiceRetryIntervals), wantStatus: INVOICE_STATUS_OPEN, wantRetryDue: issued.Add(InvoiceRetryIntervals[len(InvoiceRetryIntervals)-1])},
+		{name: "retries used up", retry: true, failures: len(InvoiceRetryIntervals) + 1, wantStatus: INVOICE_STATUS_FAILED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice, _ := NewInvoice(InvoiceId{"1"}, UserId{"1"}, SubscriptionId{"1"}, USER_TYPE_PREMIUM, 1, 500, tt.retry, issued)
+			for i := 0; i < tt.failures; i++ {
+				assert.Nil(t, invoice.Fail("card declined", issued))
+			}
+			assert.Equal(t, tt.wantStatus, invoice.Status)
+			assert.Equal(t, tt.wantRetryDue, invoice.NextAttempt)
+			assert.Equal(t, !tt.wantRetryDue.IsZero(), invoice.IsRetryDue(tt.wantRetryDue))
+			assert.Equal(t, false, invoice.IsRetryDue(tt.wantRetryDue.Add(-time.Second)))
+		})
+	}
+}
+
+func TestInvoice_Pay(t *testing.T) {
+	issued := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	invoice, _ := NewInvoice(InvoiceId{"1"}, UserId{"1"}, SubscriptionId{}, USER_TYPE_PREMIUM, 1, 500, true, issued)
+	assert.Equal(t, true, errors.Is(invoice.Link(SubscriptionId{"1"}), ErrConflict))
+	assert.Nil(t, invoice.Fail("card declined", issued))
+	assert.Nil(t, invoice.Pay("pay_1", issued.Add(InvoiceRetryIntervals[0])))
+	assert.Equal(t, INVOICE_STATUS_PAID, invoice.Status)
+	assert.Equal(t, 2, invoice.Attempts)
+	assert.Nil(t, invoice.Link(SubscriptionId{"1"}))
+	assert.Equal(t, true, errors.Is(invoice.Link(SubscriptionId{"2"}), ErrConflict))
+	assert.Equal(t, true, errors.Is(invoice.Pay("pay_2", issued), ErrConflict))
+	assert.Equal(t, true, errors.Is(invoice.Fail("card declined", issued), ErrConflict))
+	assert.Equal(t, true, errors.Is(invoice.Void(issued), ErrConflict))
+}
+
+func TestLedger_Balance(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	paid := Invoice{Id: InvoiceId{"1"}, UserId: UserId{"1"}, Amount: 500}
+	open := Invoice{Id: InvoiceId{"2"}, UserId: UserId{"1"}, Amount: 1500}
+	failed := Invoice{Id: InvoiceId{"3"}, UserId: UserId{"1"}, Amount: 500}
+	ledger := Ledger{UserId: UserId{"1"}, Entries: []LedgerEntry{
+		NewLedgerEntry(paid, LEDGER_ENTRY_KIND_CHARGE, "", now),
+		NewLedgerEntry(paid, LEDGER_ENTRY_KIND_PAYMENT, "", now),
+		NewLedgerEntry(open, LEDGER_ENTRY_KIND_CHARGE, "", now),
+		NewLedgerEntry(failed, LEDGER_ENTRY_KIND_CHARGE, "", now),
+		NewLedgerEntry(failed, LEDGER_ENTRY_KIND_WRITE_OFF, "", now),
+	}}
+	assert.Equal(t, 1500, ledger.Balance())
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,11 +16,16 @@ type (
 	}
 	// Aggregate Root
 	Circle struct {
-		id      *CircleId
-		name    *CircleName
-		owner   *UserId
-		members []UserId
+		id         *CircleId
+		name       *CircleName
+		owner      *UserId
+		members    []UserId
+		moderators []UserId
+		// private circles are visible to their members only
+		private bool
 		created time.Time
+		// counts the saves of the circle. Repositories reject saves of a version which is not the latest.
+		version int
 	}
 
 	ICircleRepository interface {
@@ -38,9 +45,11 @@ type (
 		repo ICircleRepository
 	}
 
+	// the actor becomes the owner
 	CircleCreateCommand struct {
-		userId string
-		name   string
+		actor   Actor
+		name    string
+		private bool
 	}
 
 	CircleApplicationService struct {
@@ -48,22 +57,85 @@ type (
 		circleRepository ICircleRepository
 		circleService    CircleService
 		userRepository   IUserRepository
+		entitlements     EntitlementService
+		policy           AuthorizationPolicy
+		auditLog         IAuditLog
+		unitOfWork       IUnitOfWork
 		now              time.Time
 	}
 
+	// the actor joins the circle
+	// version is the version of the circle the client read. Nil joins any version.
 	CircleJoinCommand struct {
-		userId   string
+		actor    Actor
 		circleId string
+		version  *int
 	}
 
+	// the capacity of a circle is an entitlement of its owner
 	CircleFullSpecification struct {
-		repo IUserRepository
+		repo         IUserRepository
+		entitlements EntitlementService
 	}
 
 	CircleGetRecommendResult struct {
 		circles []Circle
 	}
 
+	CircleGetCommand struct {
+		actor    Actor
+		circleId string
+	}
+
+	// circles the actor can not view are left out
+	CircleGetAllCommand struct {
+		actor Actor
+	}
+
+	// the actor removes member from the circle, or leaves it when member is the actor
+	CircleKickCommand struct {
+		actor    Actor
+		circleId string
+		memberId string
+	}
+
+	// the owner makes member a moderator of the circle
+	CircleAppointModeratorCommand struct {
+		actor    Actor
+		circleId string
+		memberId string
+	}
+
+	CircleCreateResult struct {
+		Id string
+	}
+
+	CircleGetResult struct {
+		Circle Circle
+	}
+
+	CircleGetAllResult struct {
+		Circles []Circle
+	}
+
+	CircleResponseModel struct {
+		Id          string   `json:"id"`
+		Name        string   `json:"name"`
+		Owner       string   `json:"owner"`
+		Members     []string `json:"members"`
+		MemberCount int      `json:"memberCount"`
+		Private     bool     `json:"private"`
+	}
+
+	CirclePostRequestModel struct {
+		Name    string `json:"name" form:"name"`
+		Private bool   `json:"private" form:"private"`
+	}
+
+	CircleListResponseModel struct {
+		Circles []CircleResponseModel `json:"circles"`
+	}
+
 	CircleRecommendSpecification struct {
 		executeDateTime time.Time
 	}
@@ -79,22 +151,18 @@ func NewCircleService(repo ICircleRepository) CircleService {
 
 func (s *CircleService) Exist(circle *Circle) bool {
 	duplicated, _ := s.repo.FindByName(circle.name)
-	return duplicated.name.V != ""
+	return duplicated.name != nil && duplicated.name.V != ""
 }
 
-func NewCircle(id *CircleId, name *CircleName, owner *UserId, users []UserId) (Circle, bool) {
+func NewCircle(id *CircleId, name *CircleName, owner *UserId, users []UserId) (Circle, error) {
 	if id == nil {
-		return Circle{}, false
+		return Circle{}, NewValidationError("id", "is required")
 	}
 	if name == nil {
-		return Circle{}, false
+		return Circle{}, NewValidationError("name", "is required")
 	}
 	if owner == nil {
-		return Circle{}, false
-	}
-
-	if len(users) == 0 {
-		return Circle{}, false
+		return Circle{}, NewValidationError("owner", "is required")
 	}
 
 	return Circle{
@@ -102,79 +170,156 @@ func NewCircle(id *CircleId, name *CircleName, owner *UserId, users []UserId) (C
 		name:    name,
 		owner:   owner,
 		members: users,
-	}, true
+	}, nil
+}
+
+func NewCircleCreateCommand(actor Actor, name string, private bool) CircleCreateCommand {
+	return CircleCreateCommand{actor: actor, name: name, private: private}
 }
 
-func NewCircleCreateCommand(userId string, userName string) CircleCreateCommand {
-	return CircleCreateCommand{userId: userId, name: userName}
+func (c CircleCreateCommand) Validate() error {
+	errs := NewValidationErrors()
+	_, err := NewCircleName(c.name)
+	errs.Add("name", err)
+	return errs.Err()
 }
 
-func NewCircleApplicationService(circleFactory ICircleFactory, circleRepository ICircleRepository, circleService CircleService, userRepository IUserRepository, now time.Time) CircleApplicationService {
+func NewCircleApplicationService(circleFactory ICircleFactory, circleRepository ICircleRepository, circleService CircleService, userRepository IUserRepository, entitlements EntitlementService, unitOfWork IUnitOfWork, auditLog IAuditLog, now time.Time) CircleApplicationService {
 	return CircleApplicationService{
 		circleFactory:    circleFactory,
 		circleRepository: circleRepository,
 		circleService:    circleService,
 		userRepository:   userRepository,
+		entitlements:     entitlements,
+		policy:           NewAuthorizationPolicy(),
+		auditLog:         auditLog,
+		unitOfWork:       unitOfWork,
 		now:              time.Now(),
 	}
 }
 
-func (cas *CircleApplicationService) Create(command CircleCreateCommand) bool {
-
-	// TX Starts
+func (cas *CircleApplicationService) Create(command CircleCreateCommand) (*CircleCreateResult, error) {
 
-	// find owner's user id
-	ownerId, _ := NewUserId(command.userId)
-	owner, err := cas.userRepository.FindById(&ownerId)
-	if err != nil {
-		return false
+	if err := command.Validate(); err != nil {
+		return nil, err
 	}
-
-	name, _ := NewCircleName(command.name)
-	circle, _ := cas.circleFactory.Create(&name, owner)
-
-	// check duplication
-	if cas.circleService.Exist(circle) {
-		return false
+	if err := Enforce(cas.policy.CanCreateCircle(command.actor), cas.auditLog); err != nil {
+		return nil, err
 	}
 
-	cas.circleRepository.Save(circle)
-	return true
-	// TX Ends
-}
-
-func (cas *CircleApplicationService) Join(command CircleJoinCommand) bool {
-	// TX Starts
+	var id CircleId
+	err := cas.unitOfWork.Do(func() error {
+		// find owner's user id
+		ownerId := command.actor.UserId
+		owner, err := cas.userRepository.FindById(&ownerId)
+		if err != nil {
+			return err
+		}
+		if owner == nil {
+			return NewNotFoundError("user", ownerId.V)
+		}
 
-	memberId, _ := NewUserId(command.userId)
+		name, err := NewCircleName(command.name)
+		if err != nil {
+			return err
+		}
+		circle, err := cas.circleFactory.Create(&name, owner)
+		if err != nil {
+			return err
+		}
+		if command.private {
+			if err := cas.entitlements.RequireFeature(owner, FEATURE_PRIVATE_CIRCLES); err != nil {
+				return err
+			}
+			circle.MakePrivate()
+		}
+		if err := cas.checkMaxCircles(owner); err != nil {
+			return err
+		}
 
-	user, err := cas.userRepository.FindById(&memberId)
+		// check duplication
+		if cas.circleService.Exist(circle) {
+			return NewConflictError("circle", "already exists")
+		}
 
+		id = circle.Id()
+		return cas.circleRepository.Save(circle)
+	})
 	if err != nil {
-		return false
+		return nil, err
 	}
+	return &CircleCreateResult{Id: id.V}, nil
+}
 
-	circleId, _ := NewCircleId(command.circleId)
-	circle, err := cas.circleRepository.FindById(circleId)
+// checkMaxCircles keeps the number of circles the owner has within their plan
+func (cas *CircleApplicationService) checkMaxCircles(owner *User) error {
+	max, err := cas.entitlements.Limit(owner, LIMIT_MAX_CIRCLES)
 	if err != nil {
-		return false
+		return err
 	}
-
-	cfs := NewCircleFullSpecification(cas.userRepository)
-	if cfs.IsSatisfiedBy(circle) {
-		return false
+	if max == 0 {
+		return nil
+	}
+	circles, err := cas.circleRepository.FindAll()
+	if err != nil {
+		return err
 	}
+	owned := 0
+	for _, circle := range circles {
+		if circle.Owner().V == owner.Id.V {
+			owned++
+		}
+	}
+	if owned >= max {
+		return NewCapacityError("circles of the user", max)
+	}
+	return nil
+}
 
-	// This violates Law of Demeter (See List 12.2 & Chap 12.1.2)
-	// circle.members = append(circle.members, memberId)
-	if !circle.Join(user) {
-		return false
+func (cas *CircleApplicationService) Join(command CircleJoinCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
 	}
 
-	cas.circleRepository.Save(circle)
-	return true
-	// TX Ends
+	return cas.unitOfWork.Do(func() error {
+		memberId := command.actor.UserId
+
+		user, err := cas.userRepository.FindById(&memberId)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return NewNotFoundError("user", memberId.V)
+		}
+
+		circleId, err := NewCircleId(command.circleId)
+		if err != nil {
+			return err
+		}
+		circle, err := cas.circleRepository.FindById(circleId)
+		if err != nil {
+			return err
+		}
+		if err := Enforce(cas.policy.CanJoinCircle(command.actor, circle, user), cas.auditLog); err != nil {
+			return err
+		}
+		if err := circle.RequireVersion(command.version); err != nil {
+			return err
+		}
+
+		cfs := NewCircleFullSpecification(cas.userRepository, cas.entitlements)
+		if cfs.IsSatisfiedBy(circle) {
+			return NewCapacityError("circle", cfs.UpperLimit(circle))
+		}
+
+		// This violates Law of Demeter (See List 12.2 & Chap 12.1.2)
+		// circle.members = append(circle.members, memberId)
+		if err := circle.Join(user, cfs.UpperLimit(circle)); err != nil {
+			return err
+		}
 
+		return cas.circleRepository.Save(circle)
+	})
 }
 
 func (cas *CircleApplicationService) GetRecommend() CircleGetRecommendResult {
@@ -193,33 +338,315 @@ func (cas *CircleApplicationService) GetRecommend() CircleGetRecommendResult {
 	return CircleGetRecommendResult{circles: recommendCircles}
 }
 
-func (c *Circle) Join(member *User) bool {
+func NewCircleGetCommand(actor Actor, circleId string) CircleGetCommand {
+	return CircleGetCommand{actor: actor, circleId: circleId}
+}
+
+func NewCircleGetAllCommand(actor Actor) CircleGetAllCommand {
+	return CircleGetAllCommand{actor: actor}
+}
+
+func NewCircleKickCommand(actor Actor, circleId string, memberId string) CircleKickCommand {
+	return CircleKickCommand{actor: actor, circleId: circleId, memberId: memberId}
+}
+
+func NewCircleAppointModeratorCommand(actor Actor, circleId string, memberId string) CircleAppointModeratorCommand {
+	return CircleAppointModeratorCommand{actor: actor, circleId: circleId, memberId: memberId}
+}
+
+func (c CircleAppointModeratorCommand) Validate() error {
+	errs := NewValidationErrors()
+	_, err := NewCircleId(c.circleId)
+	errs.Add("circleId", err)
+	_, err = NewUserId(c.memberId)
+	errs.Add("memberId", err)
+	return errs.Err()
+}
+
+func (c CircleKickCommand) Validate() error {
+	errs := NewValidationErrors()
+	_, err := NewCircleId(c.circleId)
+	errs.Add("circleId", err)
+	_, err = NewUserId(c.memberId)
+	errs.Add("memberId", err)
+	return errs.Err()
+}
+
+func (c CircleGetCommand) Validate() error {
+	errs := NewValidationErrors()
+	_, err := NewCircleId(c.circleId)
+	errs.Add("circleId", err)
+	return errs.Err()
+}
+
+func (cas *CircleApplicationService) Get(command CircleGetCommand) (*CircleGetResult, error) {
+	if err := command.Validate(); err != nil {
+		return nil, err
+	}
+
+	circleId, err := NewCircleId(command.circleId)
+	if err != nil {
+		return nil, err
+	}
+	circle, err := cas.circleRepository.FindById(circleId)
+	if err != nil {
+		return nil, err
+	}
+	if err := Enforce(cas.policy.CanViewCircle(command.actor, circle), cas.auditLog); err != nil {
+		return nil, err
+	}
+	return &CircleGetResult{Circle: *circle}, nil
+}
+
+func (cas *CircleApplicationService) GetAll(command CircleGetAllCommand) (*CircleGetAllResult, error) {
+	circles, err := cas.circleRepository.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	visible := []Circle{}
+	for _, circle := range circles {
+		if cas.policy.CanViewCircle(command.actor, &circle).Allowed {
+			visible = append(visible, circle)
+		}
+	}
+	return &CircleGetAllResult{Circles: visible}, nil
+}
+
+func (cas *CircleApplicationService) Kick(command CircleKickCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+
+	return cas.unitOfWork.Do(func() error {
+		circleId, err := NewCircleId(command.circleId)
+		if err != nil {
+			return err
+		}
+		circle, err := cas.circleRepository.FindById(circleId)
+		if err != nil {
+			return err
+		}
+		memberId, err := NewUserId(command.memberId)
+		if err != nil {
+			return err
+		}
+		if err := Enforce(cas.policy.CanKickMember(command.actor, circle, memberId), cas.auditLog); err != nil {
+			return err
+		}
+
+		if err := circle.Remove(memberId); err != nil {
+			return err
+		}
+
+		return cas.circleRepository.Save(circle)
+	})
+}
+
+func (cas *CircleApplicationService) AppointModerator(command CircleAppointModeratorCommand) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+
+	return cas.unitOfWork.Do(func() error {
+		circleId, err := NewCircleId(command.circleId)
+		if err != nil {
+			return err
+		}
+		circle, err := cas.circleRepository.FindById(circleId)
+		if err != nil {
+			return err
+		}
+		memberId, err := NewUserId(command.memberId)
+		if err != nil {
+			return err
+		}
+		if err := Enforce(cas.policy.CanAppointModerator(command.actor, circle, memberId), cas.auditLog); err != nil {
+			return err
+		}
+
+		member, err := cas.userRepository.FindById(&memberId)
+		if err != nil {
+			return err
+		}
+		if member == nil {
+			return NewNotFoundError("user", memberId.V)
+		}
+		if err := circle.AppointModerator(member); err != nil {
+			return err
+		}
+
+		return cas.circleRepository.Save(circle)
+	})
+}
+
+// capacity is the number of people the circle can have, including the owner
+func (c *Circle) Join(member *User, capacity int) error {
 	if member == nil {
-		return false
+		return NewValidationError("member", "is required")
 	}
 
-	if c.IsFull() {
-		return false
+	if c.IsMember(member.Id) {
+		return NewConflictError("member", "already joined")
+	}
+
+	if c.CountMembers() >= capacity {
+		return NewCapacityError("circle", capacity)
 	}
 
 	c.members = append(c.members, member.Id)
-	return true
+	return nil
+}
+
+func (c *Circle) Id() CircleId {
+	return *c.id
 }
 
-func (c *Circle) IsFull() bool {
-	return c.CountMembers() >= 30
+func (c *Circle) Name() CircleName {
+	return *c.name
+}
+
+func (c *Circle) Owner() UserId {
+	return *c.owner
+}
+
+func (c *Circle) Members() []UserId {
+	return append([]UserId{}, c.members...)
+}
+
+// owner is treated as a member as well
+func (c *Circle) IsMember(id UserId) bool {
+	if c.owner.V == id.V {
+		return true
+	}
+	for _, member := range c.members {
+		if member.V == id.V {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Circle) IsModerator(id UserId) bool {
+	for _, moderator := range c.moderators {
+		if moderator.V == id.V {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Circle) CanModerate(id UserId) bool {
+	return c.owner.V == id.V || c.IsModerator(id)
+}
+
+func (c *Circle) IsPrivate() bool {
+	return c.private
+}
+
+func (c *Circle) MakePrivate() {
+	c.private = true
+}
+
+func (c *Circle) MakePublic() {
+	c.private = false
+}
+
+// Remove takes member out of the circle, together with the moderator role
+func (c *Circle) Remove(member UserId) error {
+	if c.owner.V == member.V {
+		return NewConflictError("owner", "can not leave the circle")
+	}
+	for i, m := range c.members {
+		if m.V == member.V {
+			c.members = append(c.members[:i:i], c.members[i+1:]...)
+			c.removeModerator(member)
+			return nil
+		}
+	}
+	return NewNotFoundError("member", member.V)
+}
+
+func (c *Circle) removeModerator(id UserId) {
+	moderators := []UserId{}
+	for _, moderator := range c.moderators {
+		if moderator.V != id.V {
+			moderators = append(moderators, moderator)
+		}
+	}
+	c.moderators = moderators
+}
+
+func (c *Circle) AppointModerator(member *User) error {
+	if member == nil {
+		return NewValidationError("member", "is required")
+	}
+	if !c.IsMember(member.Id) {
+		return NewPermissionError("appoint moderator", "user is not a member of the circle")
+	}
+	if c.CanModerate(member.Id) {
+		return NewConflictError("moderator", "already appointed")
+	}
+
+	c.moderators = append(c.moderators, member.Id)
+	return nil
 }
 
 func (c *Circle) CountMembers() int {
 	return len(c.members) + 1
 }
 
-func NewCircleJoinCommand(userId string, circleId string) CircleJoinCommand {
-	return CircleJoinCommand{userId: userId, circleId: circleId}
+func (c *Circle) Version() int {
+	return c.version
+}
+
+// RequireVersion fails unless the circle is at the version the client read. A nil version matches any version.
+func (c *Circle) RequireVersion(version *int) error {
+	if version != nil && *version != c.version {
+		return NewPreconditionFailedError("circle", c.Id().V, *version, c.version)
+	}
+	return nil
 }
 
-func NewCircleFullSpecification(repo IUserRepository) CircleFullSpecification {
-	return CircleFullSpecification{repo: repo}
+// NextVersion returns a copy of the circle as it is stored by its next save
+func (c *Circle) NextVersion() Circle {
+	clone := c.Clone()
+	clone.version++
+	return clone
+}
+
+// Clone returns a copy which shares nothing with the circle, so that changing one leaves the other as it is
+func (c *Circle) Clone() Circle {
+	clone := *c
+	if c.id != nil {
+		id := *c.id
+		clone.id = &id
+	}
+	if c.name != nil {
+		name := *c.name
+		clone.name = &name
+	}
+	if c.owner != nil {
+		owner := *c.owner
+		clone.owner = &owner
+	}
+	clone.members = append([]UserId{}, c.members...)
+	clone.moderators = append([]UserId{}, c.moderators...)
+	return clone
+}
+
+func NewCircleJoinCommand(actor Actor, circleId string, version *int) CircleJoinCommand {
+	return CircleJoinCommand{actor: actor, circleId: circleId, version: version}
+}
+
+func (c CircleJoinCommand) Validate() error {
+	errs := NewValidationErrors()
+	_, err := NewCircleId(c.circleId)
+	errs.Add("circleId", err)
+	return errs.Err()
+}
+
+func NewCircleFullSpecification(repo IUserRepository, entitlements EntitlementService) CircleFullSpecification {
+	return CircleFullSpecification{repo: repo, entitlements: entitlements}
 }
 
 func NewCircleRecommendSpecification(executeDateTime time.Time) CircleRecommendSpecification {
@@ -227,13 +654,17 @@ func NewCircleRecommendSpecification(executeDateTime time.Time) CircleRecommendS
 }
 
 func (cfs *CircleFullSpecification) IsSatisfiedBy(circle *Circle) bool {
+	return circle.CountMembers() >= cfs.UpperLimit(circle)
+}
+
+// circles whose owner can not be found have the capacity of the normal plan
+func (cfs *CircleFullSpecification) UpperLimit(circle *Circle) int {
 	owner, _ := cfs.repo.FindById(circle.owner)
-	upperLimit := 30
-	if owner.IsPremium() {
-		upperLimit = 50
+	capacity, err := cfs.entitlements.Limit(owner, LIMIT_CIRCLE_CAPACITY)
+	if err != nil {
+		capacity, _ = cfs.entitlements.Limit(nil, LIMIT_CIRCLE_CAPACITY)
 	}
-
-	return circle.CountMembers() >= upperLimit
+	return capacity
 }
 
 func (crs *CircleRecommendSpecification) IsSatisfiedBy(circle Circle) bool {
@@ -244,23 +675,78 @@ func (crs *CircleRecommendSpecification) IsSatisfiedBy(circle Circle) bool {
 	return circle.created.Before(crs.executeDateTime.AddDate(0, -1, 0))
 }
 
-func NewCircleId(v string) (CircleId, bool) {
+func NewCircleId(v string) (CircleId, error) {
 	if len(v) == 0 {
-		return CircleId{}, false
+		return CircleId{}, NewValidationError("id", "is required")
 	}
-	return CircleId{V: v}, true
+	return CircleId{V: v}, nil
 }
 
-func NewCircleName(v string) (CircleName, bool) {
+func NewCircleName(v string) (CircleName, error) {
 	if len(v) == 0 {
-		return CircleName{}, false
+		return CircleName{}, NewValidationError("name", "is required")
 	}
 	if len(v) < 3 {
-		return CircleName{}, false
+		return CircleName{}, NewValidationError("name", "must be at least 3 characters")
 	}
 	if len(v) > 20 {
-		return CircleName{}, false
+		return CircleName{}, NewValidationError("name", "must be at most 20 characters")
+	}
+
+	return CircleName{V: v}, nil
+}
+
+func NewCircleResponseModel(circle Circle) *CircleResponseModel {
+	members := []string{}
+	for _, member := range circle.Members() {
+		members = append(members, member.V)
 	}
+	return &CircleResponseModel{
+		Id:          circle.Id().V,
+		Name:        circle.Name().V,
+		Owner:       circle.Owner().V,
+		Members:     members,
+		MemberCount: circle.CountMembers(),
+		Private:     circle.IsPrivate(),
+	}
+}
+
+func NewCircleListResponseModel(circles []Circle) *CircleListResponseModel {
+	responses := []CircleResponseModel{}
+	for _, circle := range circles {
+		responses = append(responses, *NewCircleResponseModel(circle))
+	}
+	return &CircleListResponseModel{Circles: responses}
+}
 
-	return CircleName{V: v}, true
+func (m *CircleResponseModel) CSVHeader() []string {
+	return []string{"id", "name", "owner", "memberCount", "members", "private"}
+}
+
+func (m *CircleResponseModel) CSVRecords() [][]string {
+	return [][]string{{m.Id, m.Name, m.Owner, strconv.Itoa(m.MemberCount), strings.Join(m.Members, " "), strconv.FormatBool(m.Private)}}
+}
+
+func (m *CircleResponseModel) Text() string {
+	return m.Id + " " + m.Name + " " + m.Owner + " " + strconv.Itoa(m.MemberCount)
+}
+
+func (m *CircleListResponseModel) CSVHeader() []string {
+	return (&CircleResponseModel{}).CSVHeader()
+}
+
+func (m *CircleListResponseModel) CSVRecords() [][]string {
+	records := [][]string{}
+	for _, circle := range m.Circles {
+		records = append(records, circle.CSVRecords()...)
+	}
+	return records
+}
+
+func (m *CircleListResponseModel) Text() string {
+	var output string
+	for _, circle := range m.Circles {
+		output += circle.Text() + "\n"
+	}
+	return output
 }
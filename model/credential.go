@@ -0,0 +1,71 @@
+package model
+
+import (
+	"time"
+)
+
+type (
+	// Aggregate Root
+	// password credential of a user, kept apart from User so that the hash never leaves this subsystem
+	Credential struct {
+		UserId         UserId
+		PasswordHash   string
+		FailedAttempts int
+		LockedUntil    time.Time
+		Changed        time.Time
+	}
+
+	// the credential is locked for Duration after MaxAttempts failures in a row
+	LockoutPolicy struct {
+		MaxAttempts int
+		Duration    time.Duration
+	}
+
+	ICredentialRepository interface {
+		Save(credential Credential) error
+		FindByUserId(id *UserId) (*Credential, error)
+		Delete(credential Credential) error
+	}
+)
+
+var DefaultLockoutPolicy = LockoutPolicy{MaxAttempts: 5, Duration: 15 * time.Minute}
+
+func NewCredential(userId UserId, passwordHash string, changed time.Time) (Credential, error) {
+	if len(userId.V) == 0 {
+		return Credential{}, NewValidationError("userId", "is required")
+	}
+	if len(passwordHash) == 0 {
+		return Credential{}, NewValidationError("password", "is required")
+	}
+	return Credential{UserId: userId, PasswordHash: passwordHash, Changed: changed}, nil
+}
+
+// ChangePassword replaces the hash and clears the failures
+func (c *Credential) ChangePassword(passwordHash string, changed time.Time) error {
+	if len(passwordHash) == 0 {
+		return NewValidationError("password", "is required")
+	}
+	c.PasswordHash = passwordHash
+	c.FailedAttempts = 0
+	c.LockedUntil = time.Time{}
+	c.Changed = changed
+	return nil
+}
+
+func (c *Credential) IsLocked(now time.Time) bool {
+	return now.Before(c.LockedUntil)
+}
+
+// RecordFailure counts a wrong password and locks the credential when the policy says so
+func (c *Credential) RecordFailure(now time.Time, policy LockoutPolicy) {
+	c.FailedAttempts++
+	if policy.MaxAttempts > 0 && c.FailedAttempts >= policy.MaxAttempts {
+		c.LockedUntil = now.Add(policy.Duration)
+		c.FailedAttempts = 0
+	}
+}
+
+func (c *Credential) RecordSuccess() {
+	c.FailedAttempts = 0
+	c.LockedUntil = time.Time{}
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		v        string
+		userName UserName
+		want     error
+	}{
+		{name: "normal", v: "Correct-horse7", want: nil},
+		{name: "three classes without symbols", v: "Correcthorse7", want: nil},
+		{name: "empty", v: "", want: ErrValidation},
+		{name: "short", v: "Sh0rt-pw", want: ErrValidation},
+		{name: "long", v: "Aa1-" + strings.Repeat("a", 125), want: ErrValidation},
+		{name: "two classes", v: "correcthorse7", want: ErrValidation},
+		{name: "control character", v: "Correct\thorse7", want: ErrValidation},
+		{name: "contains user name", v: "Taro-horse77", userName: UserName{"taro"}, want: ErrValidation},
+		{name: "common", v: "MyPassword-1", want: ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPassword(tt.v, tt.userName)
+			assert.Equal(t, true, errors.Is(err, tt.want),
+				fmt.Sprintf("NewPassword() error = %v, want %v", err, tt.want))
+		})
+	}
+}
+
+func TestCredential_RecordFailure(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := LockoutPolicy{MaxAttempts: 3, Duration: 15 * time.Minute}
+	tests := []struct {
+		name     string
+		failures int
+		at       time.Time
+		want     bool
+	}{
+		{name: "below the limit", failures: 2, at: now, want: false},
+		{name: "locked at the limit", failures: 3, at: now, want: true},
+		{name: "still locked", failures: 3, at: now.Add(14 * time.Minute), want: true},
+		{name: "unlocked after the duration", failures: 3, at: now.Add(15 * time.Minute), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credential, err := NewCredential(UserId{"1"}, "hash", now)
+			assert.Nil(t, err)
+			for i := 0; i < tt.failures; i++ {
+				credential.RecordFailure(now, policy)
+			}
+			assert.Equal(t, tt.want, credential.IsLocked(tt.at),
+				fmt.Sprintf("IsLocked() got = %v, want %v", credential.IsLocked(tt.at), tt.want))
+		})
+	}
+}
+
+func TestCredential_ChangePassword(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	credential, _ := NewCredential(UserId{"1"}, "old", now)
+	credential.RecordFailure(now, LockoutPolicy{MaxAttempts: 1, Duration: time.Hour})
+	assert.Equal(t, true, credential.IsLocked(now))
+
+	assert.Nil(t, credential.ChangePassword("new", now))
+	assert.Equal(t, "new", credential.PasswordHash)
+	assert.Equal(t, false, credential.IsLocked(now))
+	assert.Equal(t, true, errors.Is(credential.ChangePassword("", now), ErrValidation))
+}
+
+func TestSession_IsExpired(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	session, err := NewSession(HashToken("token"), UserId{"1"}, now, now.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, false, session.IsExpired(now.Add(59*time.Minute)))
+	assert.Equal(t, true, session.IsExpired(now.Add(time.Hour)))
+
+	_, err = NewSession("", UserId{"1"}, now, now.Add(time.Hour))
+	assert.Equal(t, true, errors.Is(err, ErrValidation))
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"crypto/subtle"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+type (
+	Email struct {
+		V string
+	}
+
+	// Token keeps only the hash of the token sent to the user
+	EmailVerification struct {
+		Token      string
+		ExpiresAt  time.Time
+		VerifiedAt time.Time
+	}
+)
+
+// NewEmail accepts a bare address such as "taro@example.com"
+// and normalises it to lower case
+func NewEmail(v string) (Email, error) {
+	v = strings.TrimSpace(v)
+	if len(v) == 0 {
+		return Email{}, NewValidationError("email", "is required")
+	}
+	if len(v) > 254 {
+		return Email{}, NewValidationError("email", "must be at most 254 characters")
+	}
+
+	address, err := mail.ParseAddress(v)
+	if err != nil || address.Address != v {
+		return Email{}, NewValidationError("email", "is not a valid address")
+	}
+	at := strings.LastIndex(v, "@")
+	if at > 64 {
+		return Email{}, NewValidationError("email", "local part must be at most 64 characters")
+	}
+	domain := v[at+1:]
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return Email{}, NewValidationError("email", "domain is not valid")
+	}
+
+	return Email{V: strings.ToLower(v)}, nil
+}
+
+func (e *Email) Equals(other Email) bool {
+	return e.V == other.V
+}
+
+func (u *User) ChangeEmail(email *Email) error {
+	if email == nil {
+		return NewValidationError("email", "is required")
+	}
+	if u.Email.Equals(*email) {
+		return nil
+	}
+	u.Email = *email
+	// the new address has to be verified again
+	u.EmailVerification = EmailVerification{}
+	return nil
+}
+
+func (u *User) IsEmailVerified() bool {
+	return !u.EmailVerification.VerifiedAt.IsZero()
+}
+
+// IssueEmailVerification starts a verification with the hash of a token sent to the address
+func (u *User) IssueEmailVerification(tokenHash string, expiresAt time.Time) error {
+	if len(u.Email.V) == 0 {
+		return NewValidationError("email", "is not registered")
+	}
+	if u.IsEmailVerified() {
+		return NewConflictError("email", "is already verified")
+	}
+	if len(tokenHash) == 0 {
+		return NewValidationError("token", "is required")
+	}
+	u.EmailVerification = EmailVerification{Token: tokenHash, ExpiresAt: expiresAt}
+	return nil
+}
+
+func (u *User) VerifyEmail(token string, now time.Time) error {
+	if u.IsEmailVerified() {
+		return NewConflictError("email", "is already verified")
+	}
+	if len(u.EmailVerification.Token) == 0 {
+		return NewValidationError("token", "has not been issued")
+	}
+	if subtle.ConstantTimeCompare([]byte(HashToken(token)), []byte(u.EmailVerification.Token)) != 1 {
+		return NewValidationError("token", "is invalid")
+	}
+	if now.After(u.EmailVerification.ExpiresAt) {
+		return NewValidationError("token", "has expired")
+	}
+	u.EmailVerification = EmailVerification{VerifiedAt: now}
+	return nil
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEmail(t *testing.T) {
+	type wants struct {
+		email Email
+		err   error
+	}
+	tests := []struct {
+		name  string
+		v     string
+		wants wants
+	}{
+		{name: "normal", v: "taro@example.com", wants: wants{email: Email{V: "taro@example.com"}}},
+		{name: "normalised", v: "  Taro.Yamada@Example.COM ", wants: wants{email: Email{V: "taro.yamada@example.com"}}},
+		{name: "empty", v: "", wants: wants{err: ErrValidation}},
+		{name: "no at mark", v: "taro.example.com", wants: wants{err: ErrValidation}},
+		{name: "display name", v: "Taro <taro@example.com>", wants: wants{err: ErrValidation}},
+		{name: "no top level domain", v: "taro@localhost", wants: wants{err: ErrValidation}},
+		{name: "long local part", v: strings.Repeat("a", 65) + "@example.com", wants: wants{err: ErrValidation}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, err := NewEmail(tt.v)
+			assert.Equal(t, tt.wants.email, email,
+				fmt.Sprintf("NewEmail() got = %v, want %v", email, tt.wants.email))
+			assert.Equal(t, true, errors.Is(err, tt.wants.err),
+				fmt.Sprintf("NewEmail() error = %v, want %v", err, tt.wants.err))
+		})
+	}
+}
+
+func TestUser_VerifyEmail(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		token    string
+		now      time.Time
+		want     error
+		verified bool
+	}{
+		{name: "verified", token: "secret", now: now, want: nil, verified: true},
+		{name: "wrong token", token: "guess", now: now, want: ErrValidation, verified: false},
+		{name: "expired", token: "secret", now: now.Add(25 * time.Hour), want: ErrValidation, verified: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := User{Id: UserId{"1"}, Name: UserName{"taro"}}
+			email := Email{V: "taro@example.com"}
+			assert.Nil(t, user.ChangeEmail(&email))
+			assert.Nil(t, user.IssueEmailVerification(HashToken("secret"), now.Add(24*time.Hour)))
+
+			err := user.VerifyEmail(tt.token, tt.now)
+			assert.Equal(t, true, errors.Is(err, tt.want),
+				fmt.Sprintf("User.VerifyEmail() error = %v, want %v", err, tt.want))
+			assert.Equal(t, tt.verified, user.IsEmailVerified())
+		})
+	}
+}
+
+func TestUser_ChangeEmail(t *testing.T) {
+	user := User{Id: UserId{"1"}, Name: UserName{"taro"}}
+	email := Email{V: "taro@example.com"}
+	assert.Nil(t, user.ChangeEmail(&email))
+	assert.Nil(t, user.IssueEmailVerification(HashToken("secret"), time.Now().Add(time.Hour)))
+	assert.Nil(t, user.VerifyEmail("secret", time.Now()))
+
+	// verifying again is a conflict
+	err := user.IssueEmailVerification(HashToken("other"), time.Now().Add(time.Hour))
+	assert.Equal(t, true, errors.Is(err, ErrConflict))
+
+	// changing the address requires a new verification
+	other := Email{V: "jiro@example.com"}
+	assert.Nil(t, user.ChangeEmail(&other))
+	assert.Equal(t, false, user.IsEmailVerified())
+	assert.Equal(t, true, errors.Is(user.ChangeEmail(nil), ErrValidation))
+}
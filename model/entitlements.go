@@ -0,0 +1,226 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+var (
+	LIMIT_MAX_CIRCLES     = Limit{V: "maxCircles"}
+	LIMIT_CIRCLE_CAPACITY = Limit{V: "circleCapacity"}
+)
+
+type (
+	Limit struct {
+		V string
+	}
+
+	// EntitlementOverride changes the entitlements of the plan for one user.
+	// Nil limits are left as the plan gives them.
+	EntitlementOverride struct {
+		UserId         UserId
+		MaxCircles     *int
+		CircleCapacity *int
+		Granted        []Feature
+		Revoked        []Feature
+		Reason         string
+	}
+
+	IEntitlementOverrideRepository interface {
+		Save(override EntitlementOverride) error
+		FindByUserId(id *UserId) (*EntitlementOverride, error)
+		Delete(id *UserId) error
+	}
+
+	// EntitlementService answers what a user can do and how much,
+	// from the plan of the user and the override for them
+	EntitlementService struct {
+		registry  *PlanRegistry
+		overrides IEntitlementOverrideRepository
+	}
+
+	EntitlementOverrideRequestModel struct {
+		MaxCircles     *int     `json:"maxCircles"`
+		CircleCapacity *int     `json:"circleCapacity"`
+		Granted        []string `json:"granted"`
+		Revoked        []string `json:"revoked"`
+		Reason         string   `json:"reason"`
+	}
+
+	EntitlementsResponseModel struct {
+		UserId         string   `json:"userId"`
+		Plan           string   `json:"plan"`
+		MaxCircles     int      `json:"maxCircles"`
+		CircleCapacity int      `json:"circleCapacity"`
+		Features       []string `json:"features"`
+		Overridden     bool     `json:"overridden"`
+	}
+)
+
+func NewEntitlementOverride(userId UserId, maxCircles *int, circleCapacity *int, granted []Feature, revoked []Feature, reason string) (EntitlementOverride, error) {
+	errs := NewValidationErrors()
+	if len(userId.V) == 0 {
+		errs.Add("userId", NewValidationError("userId", "is required"))
+	}
+	if maxCircles != nil && *maxCircles < 0 {
+		errs.Add("maxCircles", NewValidationError("maxCircles", "must not be negative"))
+	}
+	if circleCapacity != nil && *circleCapacity < 1 {
+		errs.Add("circleCapacity", NewValidationError("circleCapacity", "must be at least 1"))
+	}
+	for _, feature := range granted {
+		if containsFeature(revoked, feature) {
+			errs.Add("revoked", NewValidationError("revoked", feature.V+" is also granted"))
+		}
+	}
+	if reason = strings.TrimSpace(reason); len(reason) == 0 {
+		errs.Add("reason", NewValidationError("reason", "is required"))
+	}
+	if err := errs.Err(); err != nil {
+		return EntitlementOverride{}, err
+	}
+	return EntitlementOverride{
+		UserId:         userId,
+		MaxCircles:     maxCircles,
+		CircleCapacity: circleCapacity,
+		Granted:        granted,
+		Revoked:        revoked,
+		Reason:         reason,
+	}, nil
+}
+
+// Clone returns a copy which shares no limits or features with the override
+func (o *EntitlementOverride) Clone() EntitlementOverride {
+	clone := *o
+	if o.MaxCircles != nil {
+		maxCircles := *o.MaxCircles
+		clone.MaxCircles = &maxCircles
+	}
+	if o.CircleCapacity != nil {
+		circleCapacity := *o.CircleCapacity
+		clone.CircleCapacity = &circleCapacity
+	}
+	clone.Granted = append([]Feature{}, o.Granted...)
+	clone.Revoked = append([]Feature{}, o.Revoked...)
+	return clone
+}
+
+// Apply returns the entitlements with the override applied
+func (o EntitlementOverride) Apply(e Entitlements) Entitlements {
+	applied := Entitlements{MaxCircles: e.MaxCircles, CircleCapacity: e.CircleCapacity, Features: []Feature{}}
+	if o.MaxCircles != nil {
+		applied.MaxCircles = *o.MaxCircles
+	}
+	if o.CircleCapacity != nil {
+		applied.CircleCapacity = *o.CircleCapacity
+	}
+	for _, feature := range append(append([]Feature{}, e.Features...), o.Granted...) {
+		if !containsFeature(o.Revoked, feature) && !containsFeature(applied.Features, feature) {
+			applied.Features = append(applied.Features, feature)
+		}
+	}
+	return applied
+}
+
+func (e Entitlements) Limit(limit Limit) int {
+	switch limit {
+	case LIMIT_MAX_CIRCLES:
+		return e.MaxCircles
+	case LIMIT_CIRCLE_CAPACITY:
+		return e.CircleCapacity
+	}
+	return 0
+}
+
+func NewEntitlementService(registry *PlanRegistry, overrides IEntitlementOverrideRepository) EntitlementService {
+	return EntitlementService{registry: registry, overrides: overrides}
+}
+
+// Of returns the effective entitlements of the user.
+// A nil user, such as the deleted owner of a circle, gets the normal plan.
+func (s EntitlementService) Of(user *User) (Entitlements, error) {
+	uType := USER_TYPE_NORMAL
+	if user != nil {
+		uType = user.UType
+	}
+	plan, ok := s.registry.Find(uType)
+	if !ok {
+		return Entitlements{}, NewNotFoundError("plan", uType.V)
+	}
+	if user == nil || s.overrides == nil {
+		return plan.Entitlements, nil
+	}
+	override, err := s.overrides.FindByUserId(&user.Id)
+	if err != nil {
+		return Entitlements{}, err
+	}
+	if override == nil {
+		return plan.Entitlements, nil
+	}
+	return override.Apply(plan.Entitlements), nil
+}
+
+func (s EntitlementService) HasFeature(user *User, feature Feature) (bool, error) {
+	entitlements, err := s.Of(user)
+	if err != nil {
+		return false, err
+	}
+	return entitlements.HasFeature(feature), nil
+}
+
+// Limit returns the limit of the user. 0 means no limit for MaxCircles
+func (s EntitlementService) Limit(user *User, limit Limit) (int, error) {
+	entitlements, err := s.Of(user)
+	if err != nil {
+		return 0, err
+	}
+	return entitlements.Limit(limit), nil
+}
+
+// RequireFeature is a permission error when the user does not have the feature
+func (s EntitlementService) RequireFeature(user *User, feature Feature) error {
+	ok, err := s.HasFeature(user, feature)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NewPermissionError("use "+feature.V, "the "+user.UType.V+" plan does not include "+feature.V)
+	}
+	return nil
+}
+
+func containsFeature(features []Feature, feature Feature) bool {
+	for _, f := range features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+func NewEntitlementsResponseModel(user User, entitlements Entitlements, overridden bool) *EntitlementsResponseModel {
+	features := []string{}
+	for _, feature := range entitlements.Features {
+		features = append(features, feature.V)
+	}
+	return &EntitlementsResponseModel{
+		UserId:         user.Id.V,
+		Plan:           user.UType.V,
+		MaxCircles:     entitlements.MaxCircles,
+		CircleCapacity: entitlements.CircleCapacity,
+		Features:       features,
+		Overridden:     overridden,
+	}
+}
+
+func (m *EntitlementsResponseModel) CSVHeader() []string {
+	return []string{"userId", "plan", "maxCircles", "circleCapacity", "features", "overridden"}
+}
+
+func (m *EntitlementsResponseModel) CSVRecords() [][]string {
+	return [][]string{{m.UserId, m.Plan, strconv.Itoa(m.MaxCircles), strconv.Itoa(m.CircleCapacity), strings.Join(m.Features, " "), strconv.FormatBool(m.Overridden)}}
+}
+
+func (m *EntitlementsResponseModel) Text() string {
+	return m.Plan + " " + strconv.Itoa(m.MaxCircles) + " circles of " + strconv.Itoa(m.CircleCapacity) + " members " + strings.Join(m.Features, " ")
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubEntitlementOverrideRepository struct {
+	overrides []EntitlementOverride
+}
+
+func (r *stubEntitlementOverrideRepository) Save(override EntitlementOverride) error { return nil }
+func (r *stubEntitlementOverrideRepository) Delete(id *UserId) error                 { return nil }
+func (r *stubEntitlementOverrideRepository) FindByUserId(id *UserId) (*EntitlementOverride, error) {
+	for _, override := range r.overrides {
+		if override.UserId == *id {
+			return &override, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestEntitlementService_Of(t *testing.T) {
+	capacity := 100
+	noCircles := 0
+	overrides := &stubEntitlementOverrideRepository{overrides: []EntitlementOverride{
+		{UserId: UserId{"3"}, CircleCapacity: &capacity, MaxCircles: &noCircles, Granted: []Feature{FEATURE_PRIVATE_CIRCLES}, Revoked: []Feature{FEATURE_EVENTS}, Reason: "partner"},
+	}}
+	service := NewEntitlementService(DefaultPlanRegistry, overrides)
+	tests := []struct {
+		name string
+		user *User
+		want Entitlements
+		err  error
+	}{
+		{
+			name: "normal",
+			user: &User{Id: UserId{"1"}, UType: USER_TYPE_NORMAL},
+			want: Entitlements{MaxCircles: 3, CircleCapacity: 30, Features: []Feature{FEATURE_EVENTS}},
+		},
+		{
+			name: "premium",
+			user: &User{Id: UserId{"2"}, UType: USER_TYPE_PREMIUM},
+			want: Entitlements{MaxCircles: 10, CircleCapacity: 50, Features: []Feature{FEATURE_EVENTS, FEATURE_PRIVATE_CIRCLES}},
+		},
+		{
+			name: "overridden",
+			user: &User{Id: UserId{"3"}, UType: USER_TYPE_NORMAL},
+			want: Entitlements{MaxCircles: 0, CircleCapacity: 100, Features: []Feature{FEATURE_PRIVATE_CIRCLES}},
+		},
+		{
+			name: "no user",
+			user: nil,
+			want: Entitlements{MaxCircles: 3, CircleCapacity: 30, Features: []Feature{FEATURE_EVENTS}},
+		},
+		{
+			name: "unknown plan",
+			user: &User{Id: UserId{"4"}, UType: UserType{V: "gold"}},
+			err:  ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.Of(tt.user)
+			assert.Equal(t, true, errors.Is(err, tt.err),
+				fmt.Sprintf("EntitlementService.Of() error = %v, want %v", err, tt.err))
+			if tt.err == nil {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEntitlementService_RequireFeature(t *testing.T) {
+	service := NewEntitlementService(DefaultPlanRegistry, &stubEntitlementOverrideRepository{})
+	normal := &User{Id: UserId{"1"}, UType: USER_TYPE_NORMAL}
+	premium := &User{Id: UserId{"2"}, UType: USER_TYPE_PREMIUM}
+
+	assert.Nil(t, service.RequireFeature(premium, FEATURE_PRIVATE_CIRCLES))
+	err := service.RequireFeature(normal, FEATURE_PRIVATE_CIRCLES)
+	assert.Equal(t, true, errors.Is(err, ErrPermission))
+	assert.Equal(t, "cannot use private_circles: the normal plan does not include private_circles", err.Error())
+}
+
+func TestCircleFullSpecification_UpperLimit(t *testing.T) {
+	capacity := 5
+	users := &stubUserRepository{users: []User{
+		{Id: UserId{"1"}, UType: USER_TYPE_NORMAL},
+		{Id: UserId{"2"}, UType: USER_TYPE_PREMIUM},
+		{Id: UserId{"3"}, UType: USER_TYPE_PREMIUM},
+	}}
+	overrides := &stubEntitlementOverrideRepository{overrides: []EntitlementOverride{{UserId: UserId{"3"}, CircleCapacity: &capacity, Reason: "trial"}}}
+	cfs := NewCircleFullSpecification(users, NewEntitlementService(DefaultPlanRegistry, overrides))
+	tests := []struct {
+		name  string
+		owner UserId
+		want  int
+	}{
+		{name: "normal owner", owner: UserId{"1"}, want: 30},
+		{name: "premium owner", owner: UserId{"2"}, want: 50},
+		{name: "overridden owner", owner: UserId{"3"}, want: 5},
+		{name: "deleted owner", owner: UserId{"9"}, want: 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := CircleId{"1"}
+			name := CircleName{"circle"}
+			circle, _ := NewCircle(&id, &name, &tt.owner, []UserId{})
+			got := cfs.UpperLimit(&circle)
+			assert.Equal(t, tt.want, got,
+				fmt.Sprintf("CircleFullSpecification.UpperLimit() = %v, want %v", got, tt.want))
+		})
+	}
+}
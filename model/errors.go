@@ -0,0 +1,273 @@
+package model
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sentinels to classify domain errors with errors.Is
+var (
+	ErrValidation = errors.New("validation failed")
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrCapacity   = errors.New("capacity exceeded")
+	ErrPermission = errors.New("permission denied")
+	// ErrUnauthenticated is returned when the caller could not prove who they are
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrLocked          = errors.New("locked")
+	// ErrPaymentDeclined is returned when the payment gateway refused to take a payment
+	ErrPaymentDeclined = errors.New("payment declined")
+	// ErrStale is returned when an aggregate was saved by someone else after it was read.
+	// Stale errors are conflicts as well.
+	ErrStale = errors.New("stale")
+	// ErrPreconditionFailed is returned when the client changes an aggregate from a version which is not the latest
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+type (
+	ValidationError struct {
+		Field  string
+		Reason string
+	}
+
+	// ValidationErrors collects every field error found while validating a command
+	ValidationErrors struct {
+		Errors []*ValidationError
+	}
+
+	NotFoundError struct {
+		Resource string
+		Id       string
+	}
+
+	ConflictError struct {
+		Resource string
+		Reason   string
+	}
+
+	// the name is already used by another user
+	DuplicateUserNameError struct {
+		Name string
+	}
+
+	CapacityError struct {
+		Resource string
+		Limit    int
+	}
+
+	PermissionError struct {
+		Action string
+		Reason string
+	}
+
+	AuthenticationError struct {
+		Reason string
+	}
+
+	PaymentDeclinedError struct {
+		Reason string
+	}
+
+	LockedError struct {
+		Resource string
+		Until    time.Time
+	}
+
+	// the aggregate was saved as Version by someone else
+	StaleError struct {
+		Resource string
+		Id       string
+		Version  int
+	}
+
+	PreconditionFailedError struct {
+		Resource string
+		Id       string
+		Expected int
+		Version  int
+	}
+)
+
+func NewValidationError(field string, reason string) *ValidationError {
+	return &ValidationError{Field: field, Reason: reason}
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid " + e.Field + ": " + e.Reason
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func NewValidationErrors() *ValidationErrors {
+	return &ValidationErrors{Errors: []*ValidationError{}}
+}
+
+// Add records err under field. Errors other than validation errors are ignored,
+// and only the first error of each field is kept.
+// Errors collected by another ValidationErrors keep their own fields.
+func (e *ValidationErrors) Add(field string, err error) {
+	var errs *ValidationErrors
+	if errors.As(err, &errs) {
+		for _, validationError := range errs.Errors {
+			e.add(validationError.Field, validationError.Reason)
+		}
+		return
+	}
+	var validationError *ValidationError
+	if errors.As(err, &validationError) {
+		e.add(field, validationError.Reason)
+	}
+}
+
+func (e *ValidationErrors) add(field string, reason string) {
+	for _, err := range e.Errors {
+		if err.Field == field {
+			return
+		}
+	}
+	e.Errors = append(e.Errors, NewValidationError(field, reason))
+}
+
+// Err returns nil when nothing has been collected
+func (e *ValidationErrors) Err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationErrors) Error() string {
+	messages := []string{}
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationErrors) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func NewNotFoundError(resource string, id string) *NotFoundError {
+	return &NotFoundError{Resource: resource, Id: id}
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Resource + " not found"
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+func NewConflictError(resource string, reason string) *ConflictError {
+	return &ConflictError{Resource: resource, Reason: reason}
+}
+
+func (e *ConflictError) Error() string {
+	return e.Resource + " " + e.Reason
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func NewDuplicateUserNameError(name string) *DuplicateUserNameError {
+	return &DuplicateUserNameError{Name: name}
+}
+
+func (e *DuplicateUserNameError) Error() string {
+	return "user name " + strconv.Quote(e.Name) + " is already taken"
+}
+
+func (e *DuplicateUserNameError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func NewCapacityError(resource string, limit int) *CapacityError {
+	return &CapacityError{Resource: resource, Limit: limit}
+}
+
+func (e *CapacityError) Error() string {
+	return e.Resource + " is full (limit " + strconv.Itoa(e.Limit) + ")"
+}
+
+func (e *CapacityError) Is(target error) bool {
+	return target == ErrCapacity
+}
+
+func NewPermissionError(action string, reason string) *PermissionError {
+	return &PermissionError{Action: action, Reason: reason}
+}
+
+func (e *PermissionError) Error() string {
+	return "cannot " + e.Action + ": " + e.Reason
+}
+
+func (e *PermissionError) Is(target error) bool {
+	return target == ErrPermission
+}
+
+func NewAuthenticationError(reason string) *AuthenticationError {
+	return &AuthenticationError{Reason: reason}
+}
+
+func (e *AuthenticationError) Error() string {
+	return "authentication failed: " + e.Reason
+}
+
+func (e *AuthenticationError) Is(target error) bool {
+	return target == ErrUnauthenticated
+}
+
+func NewLockedError(resource string, until time.Time) *LockedError {
+	return &LockedError{Resource: resource, Until: until}
+}
+
+func (e *LockedError) Error() string {
+	return e.Resource + " is locked until " + e.Until.UTC().Format(time.RFC3339)
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+func NewPaymentDeclinedError(reason string) *PaymentDeclinedError {
+	return &PaymentDeclinedError{Reason: reason}
+}
+
+func (e *PaymentDeclinedError) Error() string {
+	return "payment declined: " + e.Reason
+}
+
+func (e *PaymentDeclinedError) Is(target error) bool {
+	return target == ErrPaymentDeclined
+}
+
+func NewStaleError(resource string, id string, version int) *StaleError {
+	return &StaleError{Resource: resource, Id: id, Version: version}
+}
+
+func (e *StaleError) Error() string {
+	return e.Resource + " " + e.Id + " was changed by someone else (version " + strconv.Itoa(e.Version) + ")"
+}
+
+func (e *StaleError) Is(target error) bool {
+	return target == ErrStale || target == ErrConflict
+}
+
+func NewPreconditionFailedError(resource string, id string, expected int, version int) *PreconditionFailedError {
+	return &PreconditionFailedError{Resource: resource, Id: id, Expected: expected, Version: version}
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return e.Resource + " " + e.Id + " is at version " + strconv.Itoa(e.Version) + ", not " + strconv.Itoa(e.Expected)
+}
+
+func (e *PreconditionFailedError) Is(target error) bool {
+	return target == ErrPreconditionFailed
+}
@@ -0,0 +1,147 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainErrors_Is(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		target error
+		want   bool
+	}{
+		{name: "validation", err: NewValidationError("name", "is required"), target: ErrValidation, want: true},
+		{name: "not found", err: NewNotFoundError("user", "1"), target: ErrNotFound, want: true},
+		{name: "conflict", err: NewConflictError("user", "already exists"), target: ErrConflict, want: true},
+		{name: "capacity", err: NewCapacityError("circle", 30), target: ErrCapacity, want: true},
+		{name: "permission", err: NewPermissionError("delete post", "not the author"), target: ErrPermission, want: true},
+		{name: "wrapped", err: fmt.Errorf("register: %w", NewValidationError("name", "is required")), target: ErrValidation, want: true},
+		{name: "different kind", err: NewNotFoundError("user", "1"), target: ErrConflict, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := errors.Is(tt.err, tt.target)
+			assert.Equal(t, tt.want, got,
+				fmt.Sprintf("errors.Is(%v, %v) = %v, want %v", tt.err, tt.target, got, tt.want))
+		})
+	}
+}
+
+func TestDomainErrors_As(t *testing.T) {
+	err := fmt.Errorf("register: %w", NewValidationError("name", "must be at least 3 characters"))
+
+	var validationError *ValidationError
+	assert.Equal(t, true, errors.As(err, &validationError))
+	assert.Equal(t, "name", validationError.Field)
+	assert.Equal(t, "must be at least 3 characters", validationError.Reason)
+
+	var notFoundError *NotFoundError
+	assert.Equal(t, false, errors.As(err, &notFoundError))
+}
+
+func TestCircle_Join(t *testing.T) {
+	id := CircleId{"1"}
+	name := CircleName{"circle"}
+	owner := UserId{"1"}
+	fullMembers := []UserId{}
+	for i := 0; i < 29; i++ {
+		fullMembers = append(fullMembers, UserId{fmt.Sprintf("m%d", i)})
+	}
+	tests := []struct {
+		name    string
+		members []UserId
+		member  *User
+		want    error
+	}{
+		{name: "joined", members: []UserId{}, member: &User{Id: UserId{"2"}}, want: nil},
+		{name: "member is nil", members: []UserId{}, member: nil, want: ErrValidation},
+		{name: "already joined", members: []UserId{{"2"}}, member: &User{Id: UserId{"2"}}, want: ErrConflict},
+		{name: "owner cannot join", members: []UserId{}, member: &User{Id: UserId{"1"}}, want: ErrConflict},
+		{name: "full", members: fullMembers, member: &User{Id: UserId{"2"}}, want: ErrCapacity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			circle, _ := NewCircle(&id, &name, &owner, tt.members)
+			err := circle.Join(tt.member, 30)
+			assert.Equal(t, true, errors.Is(err, tt.want),
+				fmt.Sprintf("Circle.Join() error = %v, want %v", err, tt.want))
+		})
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	errs := NewValidationErrors()
+	assert.Nil(t, errs.Err())
+
+	_, err := NewUserId("")
+	errs.Add("userId", err)
+	_, err = NewCircleName("ab")
+	errs.Add("name", err)
+	errs.Add("ignored", nil)
+	errs.Add("ignored", NewNotFoundError("user", "1"))
+
+	err = errs.Err()
+	assert.Equal(t, true, errors.Is(err, ErrValidation))
+	assert.Equal(t, "invalid userId: is required; invalid name: must be at least 3 characters", err.Error())
+
+	var got *ValidationErrors
+	assert.Equal(t, true, errors.As(err, &got))
+	assert.Equal(t, []*ValidationError{
+		{Field: "userId", Reason: "is required"},
+		{Field: "name", Reason: "must be at least 3 characters"},
+	}, got.Errors)
+}
+
+func TestCircleCreateCommand_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		command CircleCreateCommand
+		want    []*ValidationError
+	}{
+		{
+			name:    "valid",
+			command: NewCircleCreateCommand(Actor{UserId: UserId{"1"}}, "circle", false),
+			want:    nil,
+		},
+		{
+			name:    "the actor is not validated",
+			command: NewCircleCreateCommand(ANONYMOUS_ACTOR, "ab", false),
+			want: []*ValidationError{
+				{Field: "name", Reason: "must be at least 3 characters"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.command.Validate()
+			if tt.want == nil {
+				assert.Nil(t, err)
+				return
+			}
+			var errs *ValidationErrors
+			assert.Equal(t, true, errors.As(err, &errs))
+			assert.Equal(t, tt.want, errs.Errors)
+		})
+	}
+}
+
+func TestValidationErrors_Add(t *testing.T) {
+	other := NewValidationErrors()
+	other.Add("end", NewValidationError("end", "must be after start"))
+	other.Add("title", NewValidationError("title", "is required"))
+
+	errs := NewValidationErrors()
+	errs.Add("end", NewValidationError("end", "must be RFC3339"))
+	errs.Add("end", NewValidationError("end", "is required"))
+	errs.Add("", other)
+
+	assert.Equal(t, []*ValidationError{
+		{Field: "end", Reason: "must be RFC3339"},
+		{Field: "title", Reason: "is required"},
+	}, errs.Errors)
+}
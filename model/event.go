@@ -0,0 +1,186 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	RSVP_ANSWER_YES   = RsvpAnswer{V: "yes"}
+	RSVP_ANSWER_NO    = RsvpAnswer{V: "no"}
+	RSVP_ANSWER_MAYBE = RsvpAnswer{V: "maybe"}
+)
+
+type (
+	EventId struct {
+		V string
+	}
+
+	EventTitle struct {
+		V string
+	}
+
+	RsvpAnswer struct {
+		V string
+	}
+
+	Rsvp struct {
+		Member     UserId
+		Answer     RsvpAnswer
+		Waitlisted bool
+		Responded  time.Time
+	}
+
+	// Aggregate Root
+	// Capacity 0 means the event has no limit of attendees
+	Event struct {
+		Id       EventId
+		CircleId CircleId
+		Title    EventTitle
+		Start    time.Time
+		End      time.Time
+		Location string
+		Capacity int
+		Rsvps    []Rsvp
+	}
+
+	IEventRepository interface {
+		Save(event Event) error
+		FindById(id *EventId) (*Event, error)
+		FindByCircle(circleId *CircleId) ([]Event, error)
+	}
+
+	IEventFactory interface {
+		Create(circleId *CircleId, title *EventTitle, start time.Time, end time.Time, location string, capacity int) (*Event, error)
+	}
+)
+
+func NewEventId(v string) (EventId, error) {
+	if len(v) == 0 {
+		return EventId{}, NewValidationError("id", "is required")
+	}
+	return EventId{V: v}, nil
+}
+
+func NewEventTitle(v string) (EventTitle, error) {
+	if len(strings.TrimSpace(v)) == 0 {
+		return EventTitle{}, NewValidationError("title", "is required")
+	}
+	if utf8.RuneCountInString(v) > 100 {
+		return EventTitle{}, NewValidationError("title", "must be at most 100 characters")
+	}
+	return EventTitle{V: v}, nil
+}
+
+func NewRsvpAnswer(v string) (RsvpAnswer, error) {
+	switch v {
+	case RSVP_ANSWER_YES.V, RSVP_ANSWER_NO.V, RSVP_ANSWER_MAYBE.V:
+		return RsvpAnswer{V: v}, nil
+	}
+	return RsvpAnswer{}, NewValidationError("answer", "must be yes, no or maybe")
+}
+
+func NewEvent(id EventId, circleId CircleId, title EventTitle, start time.Time, end time.Time, location string, capacity int) (Event, error) {
+	if len(id.V) == 0 {
+		return Event{}, NewValidationError("id", "is required")
+	}
+	if len(circleId.V) == 0 {
+		return Event{}, NewValidationError("circleId", "is required")
+	}
+	if len(title.V) == 0 {
+		return Event{}, NewValidationError("title", "is required")
+	}
+	if !end.After(start) {
+		return Event{}, NewValidationError("end", "must be after start")
+	}
+	if capacity < 0 {
+		return Event{}, NewValidationError("capacity", "must not be negative")
+	}
+
+	return Event{
+		Id:       id,
+		CircleId: circleId,
+		Title:    title,
+		Start:    start,
+		End:      end,
+		Location: location,
+		Capacity: capacity,
+		Rsvps:    []Rsvp{},
+	}, nil
+}
+
+// Clone returns a copy which shares no rsvps with the event
+func (e *Event) Clone() Event {
+	clone := *e
+	clone.Rsvps = append([]Rsvp{}, e.Rsvps...)
+	return clone
+}
+
+// Respond records the member's answer.
+// A "yes" beyond the capacity puts the member on the waitlist,
+// and waitlisted members are promoted in order when seats are freed.
+func (e *Event) Respond(member UserId, answer RsvpAnswer, responded time.Time) error {
+	if len(member.V) == 0 {
+		return NewValidationError("member", "is required")
+	}
+	if _, err := NewRsvpAnswer(answer.V); err != nil {
+		return err
+	}
+
+	for i, rsvp := range e.Rsvps {
+		if rsvp.Member.V != member.V {
+			continue
+		}
+		// keep the place in the list when the answer is unchanged
+		if rsvp.Answer == answer {
+			return nil
+		}
+		e.Rsvps = append(e.Rsvps[:i], e.Rsvps[i+1:]...)
+		break
+	}
+
+	rsvp := Rsvp{Member: member, Answer: answer, Responded: responded}
+	if answer == RSVP_ANSWER_YES && e.IsFull() {
+		rsvp.Waitlisted = true
+	}
+	e.Rsvps = append(e.Rsvps, rsvp)
+
+	e.promoteWaitlist()
+	return nil
+}
+
+func (e *Event) IsFull() bool {
+	return e.Capacity > 0 && len(e.Attendees()) >= e.Capacity
+}
+
+func (e *Event) Attendees() []UserId {
+	attendees := []UserId{}
+	for _, rsvp := range e.Rsvps {
+		if rsvp.Answer == RSVP_ANSWER_YES && !rsvp.Waitlisted {
+			attendees = append(attendees, rsvp.Member)
+		}
+	}
+	return attendees
+}
+
+func (e *Event) Waitlist() []UserId {
+	waitlist := []UserId{}
+	for _, rsvp := range e.Rsvps {
+		if rsvp.Waitlisted {
+			waitlist = append(waitlist, rsvp.Member)
+		}
+	}
+	return waitlist
+}
+
+func (e *Event) promoteWaitlist() {
+	for i := range e.Rsvps {
+		if e.IsFull() {
+			return
+		}
+		if e.Rsvps[i].Waitlisted {
+			e.Rsvps[i].Waitlisted = false
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRsvpAnswer(t *testing.T) {
+	tests := []struct {
+		name string
+		v    string
+		want error
+	}{
+		{name: "yes", v: "yes", want: nil},
+		{name: "no", v: "no", want: nil},
+		{name: "maybe", v: "maybe", want: nil},
+		{name: "unknown", v: "sure", want: ErrValidation},
+		{name: "empty", v: "", want: ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRsvpAnswer(tt.v)
+			assert.Equal(t, true, errors.Is(err, tt.want),
+				fmt.Sprintf("NewRsvpAnswer() error = %v, want %v", err, tt.want))
+		})
+	}
+}
+
+func TestEvent_Respond(t *testing.T) {
+	start := time.Date(2023, 8, 1, 19, 0, 0, 0, time.UTC)
+	type response struct {
+		member UserId
+		answer RsvpAnswer
+	}
+	type wants struct {
+		attendees []UserId
+		waitlist  []UserId
+	}
+	tests := []struct {
+		name      string
+		capacity  int
+		responses []response
+		wants     wants
+	}{
+		{
+			name:     "within capacity",
+			capacity: 2,
+			responses: []response{
+				{UserId{"1"}, RSVP_ANSWER_YES},
+				{UserId{"2"}, RSVP_ANSWER_MAYBE},
+				{UserId{"3"}, RSVP_ANSWER_YES},
+			},
+			wants: wants{attendees: []UserId{{"1"}, {"3"}}, waitlist: []UserId{}},
+		},
+		{
+			name:     "waitlisted when full",
+			capacity: 1,
+			responses: []response{
+				{UserId{"1"}, RSVP_ANSWER_YES},
+				{UserId{"2"}, RSVP_ANSWER_YES},
+				{UserId{"3"}, RSVP_ANSWER_YES},
+			},
+			wants: wants{attendees: []UserId{{"1"}}, waitlist: []UserId{{"2"}, {"3"}}},
+		},
+		{
+			name:     "first waitlisted member is promoted",
+			capacity: 1,
+			responses: []response{
+				{UserId{"1"}, RSVP_ANSWER_YES},
+				{UserId{"2"}, RSVP_ANSWER_YES},
+				{UserId{"3"}, RSVP_ANSWER_YES},
+				{UserId{"1"}, RSVP_ANSWER_NO},
+			},
+			wants: wants{attendees: []UserId{{"2"}}, waitlist: []UserId{{"3"}}},
+		},
+		{
+			name:     "answering yes again keeps the place",
+			capacity: 1,
+			responses: []response{
+				{UserId{"1"}, RSVP_ANSWER_YES},
+				{UserId{"2"}, RSVP_ANSWER_YES},
+				{UserId{"1"}, RSVP_ANSWER_YES},
+			},
+			wants: wants{attendees: []UserId{{"1"}}, waitlist: []UserId{{"2"}}},
+		},
+		{
+			name:     "no capacity limit",
+			capacity: 0,
+			responses: []response{
+				{UserId{"1"}, RSVP_ANSWER_YES},
+				{UserId{"2"}, RSVP_ANSWER_YES},
+			},
+			wants: wants{attendees: []UserId{{"1"}, {"2"}}, waitlist: []UserId{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, _ := NewEvent(EventId{"1"}, CircleId{"1"}, EventTitle{"meetup"}, start, start.Add(time.Hour), "", tt.capacity)
+			for i, r := range tt.responses {
+				err := event.Respond(r.member, r.answer, start.Add(-time.Duration(len(tt.responses)-i)*time.Minute))
+				assert.Nil(t, err, fmt.Sprintf("Event.Respond() error = %v", err))
+			}
+
+			assert.Equal(t, true, reflect.DeepEqual(event.Attendees(), tt.wants.attendees),
+				fmt.Sprintf("Event.Attendees() = %v, want %v", event.Attendees(), tt.wants.attendees))
+			assert.Equal(t, true, reflect.DeepEqual(event.Waitlist(), tt.wants.waitlist),
+				fmt.Sprintf("Event.Waitlist() = %v, want %v", event.Waitlist(), tt.wants.waitlist))
+		})
+	}
+}
+
+func TestEvent_Clone(t *testing.T) {
+	start := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	event, _ := NewEvent(EventId{"1"}, CircleId{"1"}, EventTitle{"meetup"}, start, start.Add(time.Hour), "", 1)
+	_ = event.Respond(UserId{"1"}, RSVP_ANSWER_YES, start.Add(-2*time.Minute))
+	_ = event.Respond(UserId{"2"}, RSVP_ANSWER_YES, start.Add(-time.Minute))
+
+	clone := event.Clone()
+	// promotes user 2 from the waitlist of the clone
+	assert.Nil(t, clone.Respond(UserId{"1"}, RSVP_ANSWER_NO, start))
+
+	assert.Equal(t, []UserId{{"1"}}, event.Attendees(), "the attendees of the event are kept")
+	assert.Equal(t, []UserId{{"2"}}, event.Waitlist(), "the waitlist of the event is kept")
+	assert.Equal(t, []UserId{{"2"}}, clone.Attendees())
+}
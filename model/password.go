@@ -0,0 +1,105 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type (
+	// plain password given by the user. It is never stored, only its hash is
+	Password struct {
+		V string
+	}
+
+	// rules to accept a password. Lengths are counted in characters (runes), not bytes
+	PasswordPolicy struct {
+		MinLength int
+		MaxLength int
+		// number of classes out of lower case, upper case, digits and symbols
+		MinCharacterClasses int
+		// the password must not contain the name of the user, ignoring case
+		ForbidUserName bool
+		// compared ignoring case
+		Forbidden []string
+	}
+
+	// hashes passwords with a key derivation function
+	IPasswordHasher interface {
+		Hash(password Password) (string, error)
+		Verify(password Password, hash string) (bool, error)
+	}
+)
+
+// policy used by NewPassword
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:           10,
+	MaxLength:           128,
+	MinCharacterClasses: 3,
+	ForbidUserName:      true,
+	Forbidden:           []string{"password", "qwerty", "letmein", "1234567890"},
+}
+
+// NewPassword checks v against DefaultPasswordPolicy.
+// name may be empty when the owner of the password is not known yet
+func NewPassword(v string, name UserName) (Password, error) {
+	return DefaultPasswordPolicy.NewPassword(v, name)
+}
+
+func (p PasswordPolicy) NewPassword(v string, name UserName) (Password, error) {
+	if len(v) == 0 {
+		return Password{}, NewValidationError("password", "is required")
+	}
+
+	length := utf8.RuneCountInString(v)
+	if length < p.MinLength {
+		return Password{}, NewValidationError("password", "must be at least "+strconv.Itoa(p.MinLength)+" characters")
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return Password{}, NewValidationError("password", "must be at most "+strconv.Itoa(p.MaxLength)+" characters")
+	}
+	for _, r := range v {
+		if unicode.IsControl(r) {
+			return Password{}, NewValidationError("password", "must not contain control characters")
+		}
+	}
+	if characterClasses(v) < p.MinCharacterClasses {
+		return Password{}, NewValidationError("password", "must mix at least "+strconv.Itoa(p.MinCharacterClasses)+" of lower case, upper case, digits and symbols")
+	}
+
+	lower := strings.ToLower(v)
+	if p.ForbidUserName && len(name.V) > 0 && strings.Contains(lower, strings.ToLower(name.V)) {
+		return Password{}, NewValidationError("password", "must not contain the user name")
+	}
+	for _, forbidden := range p.Forbidden {
+		if strings.Contains(lower, strings.ToLower(forbidden)) {
+			return Password{}, NewValidationError("password", "is too common")
+		}
+	}
+
+	return Password{V: v}, nil
+}
+
+func characterClasses(v string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range v {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	count := 0
+	for _, class := range []bool{lower, upper, digit, symbol} {
+		if class {
+			count++
+		}
+	}
+	return count
+}
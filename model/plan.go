@@ -0,0 +1,211 @@
+package model
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	FEATURE_PRIVATE_CIRCLES = Feature{V: "private_circles"}
+	FEATURE_EVENTS          = Feature{V: "events"}
+)
+
+type (
+	Feature struct {
+		V string
+	}
+
+	// Entitlements are what a plan gives to its users.
+	// MaxCircles is the number of circles a user can own, and 0 means no limit.
+	Entitlements struct {
+		MaxCircles     int
+		CircleCapacity int
+		Features       []Feature
+	}
+
+	// Premium plans are the paid ones, which subscriptions and upgrades grant.
+	// MonthlyPrice is in yen.
+	Plan struct {
+		Type         UserType
+		Premium      bool
+		MonthlyPrice int
+		Entitlements Entitlements
+	}
+
+	// PlanRegistry knows every plan a user can be on
+	PlanRegistry struct {
+		plans []Plan
+	}
+
+	PlanResponseModel struct {
+		Type           string   `json:"type"`
+		Premium        bool     `json:"premium"`
+		MonthlyPrice   int      `json:"monthlyPrice"`
+		MaxCircles     int      `json:"maxCircles"`
+		CircleCapacity int      `json:"circleCapacity"`
+		Features       []string `json:"features"`
+	}
+
+	PlanListResponseModel struct {
+		Plans []PlanResponseModel `json:"plans"`
+	}
+)
+
+var planTypePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,29}$`)
+
+// registry used by NewUserType. Plans such as "team" can be added to it at startup
+var DefaultPlanRegistry = NewPlanRegistry(
+	Plan{Type: USER_TYPE_NORMAL, Entitlements: Entitlements{MaxCircles: 3, CircleCapacity: 30, Features: []Feature{FEATURE_EVENTS}}},
+	Plan{Type: USER_TYPE_PREMIUM, Premium: true, MonthlyPrice: 500, Entitlements: Entitlements{MaxCircles: 10, CircleCapacity: 50, Features: []Feature{FEATURE_EVENTS, FEATURE_PRIVATE_CIRCLES}}},
+)
+
+// NewPlanRegistry panics on invalid plans, as they are written in the code
+func NewPlanRegistry(plans ...Plan) *PlanRegistry {
+	r := &PlanRegistry{plans: []Plan{}}
+	for _, plan := range plans {
+		if err := r.Register(plan); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+func NewFeature(v string) (Feature, error) {
+	if !planTypePattern.MatchString(v) {
+		return Feature{}, NewValidationError("feature", "must be lower case letters, digits, _ or -")
+	}
+	return Feature{V: v}, nil
+}
+
+func NewEntitlements(maxCircles int, circleCapacity int, features []Feature) (Entitlements, error) {
+	errs := NewValidationErrors()
+	if maxCircles < 0 {
+		errs.Add("maxCircles", NewValidationError("maxCircles", "must not be negative"))
+	}
+	if circleCapacity < 1 {
+		errs.Add("circleCapacity", NewValidationError("circleCapacity", "must be at least 1"))
+	}
+	if err := errs.Err(); err != nil {
+		return Entitlements{}, err
+	}
+	return Entitlements{MaxCircles: maxCircles, CircleCapacity: circleCapacity, Features: features}, nil
+}
+
+// Register adds a plan, or replaces the plan of the same type
+func (r *PlanRegistry) Register(plan Plan) error {
+	if !planTypePattern.MatchString(plan.Type.V) {
+		return NewValidationError("type", "must be lower case letters, digits, _ or -")
+	}
+	if plan.MonthlyPrice < 0 {
+		return NewValidationError("monthlyPrice", "must not be negative")
+	}
+	if _, err := NewEntitlements(plan.Entitlements.MaxCircles, plan.Entitlements.CircleCapacity, plan.Entitlements.Features); err != nil {
+		return err
+	}
+	for i, p := range r.plans {
+		if p.Type == plan.Type {
+			r.plans[i] = plan
+			return nil
+		}
+	}
+	r.plans = append(r.plans, plan)
+	return nil
+}
+
+func (r *PlanRegistry) Find(uType UserType) (Plan, bool) {
+	for _, plan := range r.plans {
+		if plan.Type == uType {
+			return plan, true
+		}
+	}
+	return Plan{}, false
+}
+
+// Plans returns the plans in the order they were registered
+func (r *PlanRegistry) Plans() []Plan {
+	return append([]Plan{}, r.plans...)
+}
+
+func (r *PlanRegistry) NewUserType(v string) (UserType, error) {
+	if len(v) == 0 {
+		return UserType{}, NewValidationError("type", "is required")
+	}
+	if _, ok := r.Find(UserType{V: v}); !ok {
+		types := []string{}
+		for _, plan := range r.plans {
+			types = append(types, plan.Type.V)
+		}
+		return UserType{}, NewValidationError("type", "must be one of "+strings.Join(types, ", "))
+	}
+	return UserType{V: v}, nil
+}
+
+func (r *PlanRegistry) IsPremium(uType UserType) bool {
+	plan, ok := r.Find(uType)
+	return ok && plan.Premium
+}
+
+// Price is what the plan costs for the months
+func (p Plan) Price(months int) int {
+	return p.MonthlyPrice * months
+}
+
+func (e Entitlements) HasFeature(feature Feature) bool {
+	return containsFeature(e.Features, feature)
+}
+
+func NewPlanResponseModel(plan Plan) *PlanResponseModel {
+	features := []string{}
+	for _, feature := range plan.Entitlements.Features {
+		features = append(features, feature.V)
+	}
+	return &PlanResponseModel{
+		Type:           plan.Type.V,
+		Premium:        plan.Premium,
+		MonthlyPrice:   plan.MonthlyPrice,
+		MaxCircles:     plan.Entitlements.MaxCircles,
+		CircleCapacity: plan.Entitlements.CircleCapacity,
+		Features:       features,
+	}
+}
+
+func NewPlanListResponseModel(plans []Plan) *PlanListResponseModel {
+	responses := []PlanResponseModel{}
+	for _, plan := range plans {
+		responses = append(responses, *NewPlanResponseModel(plan))
+	}
+	return &PlanListResponseModel{Plans: responses}
+}
+
+func (m *PlanResponseModel) CSVHeader() []string {
+	return []string{"type", "premium", "monthlyPrice", "maxCircles", "circleCapacity", "features"}
+}
+
+func (m *PlanResponseModel) CSVRecords() [][]string {
+	return [][]string{{m.Type, strconv.FormatBool(m.Premium), strconv.Itoa(m.MonthlyPrice), strconv.Itoa(m.MaxCircles), strconv.Itoa(m.CircleCapacity), strings.Join(m.Features, " ")}}
+}
+
+func (m *PlanResponseModel) Text() string {
+	return m.Type + " " + strconv.Itoa(m.MaxCircles) + " circles of " + strconv.Itoa(m.CircleCapacity) + " members for " + strconv.Itoa(m.MonthlyPrice) + " yen a month"
+}
+
+func (m *PlanListResponseModel) CSVHeader() []string {
+	return (&PlanResponseModel{}).CSVHeader()
+}
+
+func (m *PlanListResponseModel) CSVRecords() [][]string {
+	records := [][]string{}
+	for _, plan := range m.Plans {
+		records = append(records, plan.CSVRecords()...)
+	}
+	return records
+}
+
+func (m *PlanListResponseModel) Text() string {
+	var output string
+	for _, plan := range m.Plans {
+		output += plan.Text() + "\n"
+	}
+	return output
+}
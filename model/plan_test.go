@@ -0,0 +1,58 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanRegistry_Register(t *testing.T) {
+	team := Plan{Type: UserType{V: "team"}, Premium: true, Entitlements: Entitlements{MaxCircles: 20, CircleCapacity: 100}}
+	tests := []struct {
+		name string
+		plan Plan
+		want error
+	}{
+		{name: "new plan", plan: team, want: nil},
+		{name: "replaces a plan", plan: Plan{Type: USER_TYPE_NORMAL, Entitlements: Entitlements{CircleCapacity: 10}}, want: nil},
+		{name: "empty type", plan: Plan{Entitlements: Entitlements{CircleCapacity: 10}}, want: ErrValidation},
+		{name: "upper case type", plan: Plan{Type: UserType{V: "Team"}, Entitlements: Entitlements{CircleCapacity: 10}}, want: ErrValidation},
+		{name: "no capacity", plan: Plan{Type: UserType{V: "team"}}, want: ErrValidation},
+		{name: "negative price", plan: Plan{Type: UserType{V: "team"}, MonthlyPrice: -1, Entitlements: Entitlements{CircleCapacity: 10}}, want: ErrValidation},
+		{name: "negative circles", plan: Plan{Type: UserType{V: "team"}, Entitlements: Entitlements{MaxCircles: -1, CircleCapacity: 10}}, want: ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewPlanRegistry(DefaultPlanRegistry.Plans()...)
+			err := registry.Register(tt.plan)
+			assert.Equal(t, true, errors.Is(err, tt.want),
+				fmt.Sprintf("PlanRegistry.Register() error = %v, want %v", err, tt.want))
+			if err != nil {
+				assert.Equal(t, 2, len(registry.Plans()))
+				return
+			}
+			plan, ok := registry.Find(tt.plan.Type)
+			assert.Equal(t, true, ok)
+			assert.Equal(t, tt.plan, plan)
+		})
+	}
+}
+
+func TestPlanRegistry_NewUserType(t *testing.T) {
+	registry := NewPlanRegistry(DefaultPlanRegistry.Plans()...)
+	assert.Nil(t, registry.Register(Plan{Type: UserType{V: "team"}, Premium: true, Entitlements: Entitlements{CircleCapacity: 100}}))
+
+	uType, err := registry.NewUserType("team")
+	assert.Nil(t, err)
+	assert.Equal(t, UserType{V: "team"}, uType)
+	assert.Equal(t, true, registry.IsPremium(uType))
+	assert.Equal(t, false, registry.IsPremium(USER_TYPE_NORMAL))
+
+	_, err = registry.NewUserType("enterprise")
+	assert.Equal(t, "invalid type: must be one of normal, premium, team", err.Error())
+	// plans added to another registry are unknown to the default one
+	_, err = NewUserType("team")
+	assert.Equal(t, true, errors.Is(err, ErrValidation))
+}
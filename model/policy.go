@@ -0,0 +1,328 @@
+package model
+
+var (
+	ACTION_USER_UPDATE           = Action{V: "update user"}
+	ACTION_USER_DELETE           = Action{V: "delete user"}
+	ACTION_USER_CHANGE_EMAIL     = Action{V: "change email"}
+	ACTION_USER_VERIFY_EMAIL     = Action{V: "verify email"}
+	ACTION_USER_SET_PASSWORD     = Action{V: "set password"}
+	ACTION_USER_LOG_IN           = Action{V: "log in"}
+	ACTION_USER_SUSPEND          = Action{V: "suspend user"}
+	ACTION_USER_REINSTATE        = Action{V: "reinstate user"}
+	ACTION_USER_DEACTIVATE       = Action{V: "deactivate user"}
+	ACTION_USER_LIST_STATUS      = Action{V: "list users by status"}
+	ACTION_USER_UPGRADE          = Action{V: "upgrade user"}
+	ACTION_USER_DOWNGRADE        = Action{V: "downgrade user"}
+	ACTION_USER_VIEW_EMAIL       = Action{V: "view email"}
+	ACTION_SUBSCRIBE             = Action{V: "subscribe"}
+	ACTION_SUBSCRIPTION_RENEW    = Action{V: "renew subscription"}
+	ACTION_SUBSCRIPTION_CANCEL   = Action{V: "cancel subscription"}
+	ACTION_SUBSCRIPTION_VIEW     = Action{V: "view subscription"}
+	ACTION_PLAN_HISTORY_VIEW     = Action{V: "view plan history"}
+	ACTION_PROMO_CODE_CREATE     = Action{V: "create promo code"}
+	ACTION_PROMO_CODE_REDEEM     = Action{V: "redeem promo code"}
+	ACTION_INVOICES_VIEW         = Action{V: "view invoices"}
+	ACTION_LEDGER_VIEW           = Action{V: "view ledger"}
+	ACTION_ENTITLEMENTS_VIEW     = Action{V: "view entitlements"}
+	ACTION_ENTITLEMENTS_OVERRIDE = Action{V: "override entitlements"}
+	ACTION_CIRCLE_CREATE         = Action{V: "create circle"}
+	ACTION_CIRCLE_VIEW           = Action{V: "view circle"}
+	ACTION_CIRCLE_JOIN           = Action{V: "join circle"}
+	ACTION_CIRCLE_KICK           = Action{V: "kick member"}
+	ACTION_CIRCLE_MODERATE       = Action{V: "appoint moderator"}
+	ACTION_POST_CREATE           = Action{V: "create post"}
+	ACTION_POST_EDIT             = Action{V: "edit post"}
+	ACTION_POST_DELETE           = Action{V: "delete post"}
+	ACTION_EVENT_SCHEDULE        = Action{V: "schedule event"}
+	ACTION_EVENT_RSVP            = Action{V: "respond to event"}
+)
+
+type (
+	Action struct {
+		V string
+	}
+
+	// Decision answers whether Actor may perform Action on Resource, and why
+	Decision struct {
+		Allowed  bool
+		Action   Action
+		Actor    Actor
+		Resource string
+		Reason   string
+	}
+
+	// port to keep decisions of the policy for later review
+	IAuditLog interface {
+		Record(decision Decision) error
+	}
+
+	// AuthorizationPolicy is the single place which decides who can do what.
+	// It only reads the aggregates handed to it, so decisions can be tested on their own.
+	AuthorizationPolicy struct{}
+)
+
+func NewAuthorizationPolicy() AuthorizationPolicy {
+	return AuthorizationPolicy{}
+}
+
+func allow(action Action, actor Actor, resource string, reason string) Decision {
+	return Decision{Allowed: true, Action: action, Actor: actor, Resource: resource, Reason: reason}
+}
+
+func deny(action Action, actor Actor, resource string, reason string) Decision {
+	return Decision{Allowed: false, Action: action, Actor: actor, Resource: resource, Reason: reason}
+}
+
+// Err is nil for allowed decisions.
+// Denials of the anonymous actor ask for authentication, and the others are permission errors.
+func (d Decision) Err() error {
+	if d.Allowed {
+		return nil
+	}
+	if d.Actor.IsAnonymous() {
+		return NewAuthenticationError(d.Reason)
+	}
+	return NewPermissionError(d.Action.V, d.Reason)
+}
+
+// Enforce records a denied decision in the audit log and returns it as an error
+func Enforce(decision Decision, auditLog IAuditLog) error {
+	if !decision.Allowed && auditLog != nil {
+		if err := auditLog.Record(decision); err != nil {
+			return err
+		}
+	}
+	return decision.Err()
+}
+
+// CanManageUser decides the actions a user can only take on themself,
+// such as renaming, deleting and changing the email or password
+func (p AuthorizationPolicy) CanManageUser(actor Actor, action Action, user *User) Decision {
+	resource := "user:" + user.Id.V
+	if actor.IsAnonymous() {
+		return deny(action, actor, resource, "authentication required")
+	}
+	if !actor.Is(user.Id) {
+		return deny(action, actor, resource, "only the user themself can "+action.V)
+	}
+	return allow(action, actor, resource, "actor is the user")
+}
+
+// only active users can log in or use their tokens
+func (p AuthorizationPolicy) CanLogIn(actor Actor, user *User) Decision {
+	resource := "user:" + user.Id.V
+	if !user.IsActive() {
+		return deny(ACTION_USER_LOG_IN, actor, resource, "account is "+user.Status.Status.V)
+	}
+	return allow(ACTION_USER_LOG_IN, actor, resource, "account is active")
+}
+
+// CanAdministerUser decides the actions only admins can take, such as suspension.
+// Admins can not take them on their own account.
+func (p AuthorizationPolicy) CanAdministerUser(actor Actor, action Action, user *User) Decision {
+	resource := "user:" + user.Id.V
+	if actor.IsAnonymous() {
+		return deny(action, actor, resource, "authentication required")
+	}
+	if !actor.IsAdmin() {
+		return deny(action, actor, resource, "only admins can "+action.V)
+	}
+	if actor.Is(user.Id) {
+		return deny(action, actor, resource, "admins can not act on their own account")
+	}
+	return allow(action, actor, resource, "actor is an admin")
+}
+
+func (p AuthorizationPolicy) CanListUsersByStatus(actor Actor) Decision {
+	if actor.IsAnonymous() {
+		return deny(ACTION_USER_LIST_STATUS, actor, "user", "authentication required")
+	}
+	if !actor.IsAdmin() {
+		return deny(ACTION_USER_LIST_STATUS, actor, "user", "only admins can "+ACTION_USER_LIST_STATUS.V)
+	}
+	return allow(ACTION_USER_LIST_STATUS, actor, "user", "actor is an admin")
+}
+
+// the email of a user can be seen by the user themself and by admins
+func (p AuthorizationPolicy) CanViewEmail(actor Actor, user *User) Decision {
+	resource := "user:" + user.Id.V
+	if actor.IsAnonymous() {
+		return deny(ACTION_USER_VIEW_EMAIL, actor, resource, "authentication required")
+	}
+	if actor.Is(user.Id) {
+		return allow(ACTION_USER_VIEW_EMAIL, actor, resource, "actor is the user")
+	}
+	if actor.IsAdmin() {
+		return allow(ACTION_USER_VIEW_EMAIL, actor, resource, "actor is an admin")
+	}
+	return deny(ACTION_USER_VIEW_EMAIL, actor, resource, "only the user themself and admins can "+ACTION_USER_VIEW_EMAIL.V)
+}
+
+// subscriptions and plan histories can be viewed by the user themself and by admins
+func (p AuthorizationPolicy) CanViewPlan(actor Actor, action Action, user *User) Decision {
+	resource := "user:" + user.Id.V
+	if actor.IsAnonymous() {
+		return deny(action, actor, resource, "authentication required")
+	}
+	if actor.Is(user.Id) {
+		return allow(action, actor, resource, "actor is the user")
+	}
+	if actor.IsAdmin() {
+		return allow(action, actor, resource, "actor is an admin")
+	}
+	return deny(action, actor, resource, "only the user themself and admins can "+action.V)
+}
+
+func (p AuthorizationPolicy) CanCreatePromoCode(actor Actor) Decision {
+	if actor.IsAnonymous() {
+		return deny(ACTION_PROMO_CODE_CREATE, actor, "promo code", "authentication required")
+	}
+	if !actor.IsAdmin() {
+		return deny(ACTION_PROMO_CODE_CREATE, actor, "promo code", "only admins can "+ACTION_PROMO_CODE_CREATE.V)
+	}
+	return allow(ACTION_PROMO_CODE_CREATE, actor, "promo code", "actor is an admin")
+}
+
+func (p AuthorizationPolicy) CanCreateCircle(actor Actor) Decision {
+	if actor.IsAnonymous() {
+		return deny(ACTION_CIRCLE_CREATE, actor, "circle", "authentication required")
+	}
+	return allow(ACTION_CIRCLE_CREATE, actor, "circle", "actor is authenticated")
+}
+
+// public circles can be viewed by everyone, private ones by their members only
+func (p AuthorizationPolicy) CanViewCircle(actor Actor, circle *Circle) Decision {
+	resource := "circle:" + circle.Id().V
+	if !circle.IsPrivate() {
+		return allow(ACTION_CIRCLE_VIEW, actor, resource, "circle is public")
+	}
+	if actor.IsAnonymous() {
+		return deny(ACTION_CIRCLE_VIEW, actor, resource, "authentication required")
+	}
+	if !circle.IsMember(actor.UserId) {
+		return deny(ACTION_CIRCLE_VIEW, actor, resource, "circle is private")
+	}
+	return allow(ACTION_CIRCLE_VIEW, actor, resource, "actor is a member")
+}
+
+// private circles can not be joined by themselves, nor can any circle by users who are not active
+func (p AuthorizationPolicy) CanJoinCircle(actor Actor, circle *Circle, member *User) Decision {
+	resource := "circle:" + circle.Id().V
+	if actor.IsAnonymous() {
+		return deny(ACTION_CIRCLE_JOIN, actor, resource, "authentication required")
+	}
+	if !member.IsActive() {
+		return deny(ACTION_CIRCLE_JOIN, actor, resource, "user is "+member.Status.Status.V)
+	}
+	if circle.IsPrivate() {
+		return deny(ACTION_CIRCLE_JOIN, actor, resource, "circle is private")
+	}
+	return allow(ACTION_CIRCLE_JOIN, actor, resource, "circle is public")
+}
+
+// CanAppointModerator lets the owner alone appoint moderators
+func (p AuthorizationPolicy) CanAppointModerator(actor Actor, circle *Circle, member UserId) Decision {
+	resource := "circle:" + circle.Id().V + "/member:" + member.V
+	switch {
+	case actor.IsAnonymous():
+		return deny(ACTION_CIRCLE_MODERATE, actor, resource, "authentication required")
+	case actor.Is(circle.Owner()):
+		return allow(ACTION_CIRCLE_MODERATE, actor, resource, "actor is the owner")
+	}
+	return deny(ACTION_CIRCLE_MODERATE, actor, resource, "only the owner can appoint moderators")
+}
+
+// CanKickMember lets the owner remove anyone, moderators remove plain members,
+// and members leave by themselves. Nobody can remove the owner.
+func (p AuthorizationPolicy) CanKickMember(actor Actor, circle *Circle, member UserId) Decision {
+	resource := "circle:" + circle.Id().V + "/member:" + member.V
+	switch {
+	case actor.IsAnonymous():
+		return deny(ACTION_CIRCLE_KICK, actor, resource, "authentication required")
+	case circle.Owner().V == member.V:
+		return deny(ACTION_CIRCLE_KICK, actor, resource, "the owner can not be removed")
+	case actor.Is(member):
+		return allow(ACTION_CIRCLE_KICK, actor, resource, "member leaves the circle")
+	case actor.Is(circle.Owner()):
+		return allow(ACTION_CIRCLE_KICK, actor, resource, "actor is the owner")
+	case circle.IsModerator(actor.UserId) && circle.IsModerator(member):
+		return deny(ACTION_CIRCLE_KICK, actor, resource, "only the owner can remove moderators")
+	case circle.IsModerator(actor.UserId):
+		return allow(ACTION_CIRCLE_KICK, actor, resource, "actor is a moderator")
+	}
+	return deny(ACTION_CIRCLE_KICK, actor, resource, "only the owner and moderators can remove members")
+}
+
+// members who are active can post to the circle
+func (p AuthorizationPolicy) CanCreatePost(actor Actor, circle *Circle, author *User) Decision {
+	resource := "circle:" + circle.Id().V
+	switch {
+	case actor.IsAnonymous():
+		return deny(ACTION_POST_CREATE, actor, resource, "authentication required")
+	case !author.IsActive():
+		return deny(ACTION_POST_CREATE, actor, resource, "user is "+author.Status.Status.V)
+	case !circle.IsMember(author.Id):
+		return deny(ACTION_POST_CREATE, actor, resource, "only members can post to the circle")
+	}
+	return allow(ACTION_POST_CREATE, actor, resource, "actor is a member")
+}
+
+// authors can edit their posts while they are active members of the circle
+func (p AuthorizationPolicy) CanEditPost(actor Actor, circle *Circle, post *Post, editor *User) Decision {
+	resource := "circle:" + circle.Id().V + "/post:" + post.Id.V
+	switch {
+	case actor.IsAnonymous():
+		return deny(ACTION_POST_EDIT, actor, resource, "authentication required")
+	case !editor.IsActive():
+		return deny(ACTION_POST_EDIT, actor, resource, "user is "+editor.Status.Status.V)
+	case !circle.IsMember(editor.Id):
+		return deny(ACTION_POST_EDIT, actor, resource, "only members can edit posts")
+	case !post.IsAuthoredBy(editor.Id):
+		return deny(ACTION_POST_EDIT, actor, resource, "only the author can edit the post")
+	}
+	return allow(ACTION_POST_EDIT, actor, resource, "actor is the author")
+}
+
+// CanDeletePost lets active authors, owners and moderators delete the post
+func (p AuthorizationPolicy) CanDeletePost(actor Actor, circle *Circle, post *Post, deleter *User) Decision {
+	resource := "circle:" + circle.Id().V + "/post:" + post.Id.V
+	switch {
+	case actor.IsAnonymous():
+		return deny(ACTION_POST_DELETE, actor, resource, "authentication required")
+	case !deleter.IsActive():
+		return deny(ACTION_POST_DELETE, actor, resource, "user is "+deleter.Status.Status.V)
+	case !post.CanBeDeletedBy(deleter.Id, circle):
+		return deny(ACTION_POST_DELETE, actor, resource, "only the author, owner and moderators can delete the post")
+	case post.IsAuthoredBy(deleter.Id):
+		return allow(ACTION_POST_DELETE, actor, resource, "actor is the author")
+	}
+	return allow(ACTION_POST_DELETE, actor, resource, "actor moderates the circle")
+}
+
+// the owner and moderators who are active can schedule events of the circle
+func (p AuthorizationPolicy) CanScheduleEvent(actor Actor, circle *Circle, organizer *User) Decision {
+	resource := "circle:" + circle.Id().V
+	switch {
+	case actor.IsAnonymous():
+		return deny(ACTION_EVENT_SCHEDULE, actor, resource, "authentication required")
+	case !organizer.IsActive():
+		return deny(ACTION_EVENT_SCHEDULE, actor, resource, "user is "+organizer.Status.Status.V)
+	case !circle.CanModerate(organizer.Id):
+		return deny(ACTION_EVENT_SCHEDULE, actor, resource, "only the owner and moderators can schedule events")
+	}
+	return allow(ACTION_EVENT_SCHEDULE, actor, resource, "actor moderates the circle")
+}
+
+// members who are active can respond to the events of the circle
+func (p AuthorizationPolicy) CanRespondToEvent(actor Actor, circle *Circle, event *Event, member *User) Decision {
+	resource := "circle:" + circle.Id().V + "/event:" + event.Id.V
+	switch {
+	case actor.IsAnonymous():
+		return deny(ACTION_EVENT_RSVP, actor, resource, "authentication required")
+	case !member.IsActive():
+		return deny(ACTION_EVENT_RSVP, actor, resource, "user is "+member.Status.Status.V)
+	case !circle.IsMember(member.Id):
+		return deny(ACTION_EVENT_RSVP, actor, resource, "only members can respond to the event")
+	}
+	return allow(ACTION_EVENT_RSVP, actor, resource, "actor is a member")
+}
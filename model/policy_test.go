@@ -0,0 +1,316 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingAuditLog struct {
+	decisions []Decision
+}
+
+func (l *recordingAuditLog) Record(decision Decision) error {
+	l.decisions = append(l.decisions, decision)
+	return nil
+}
+
+func actorOf(id string) Actor {
+	return Actor{UserId: UserId{id}}
+}
+
+func TestAuthorizationPolicy_CanManageUser(t *testing.T) {
+	user := User{Id: UserId{"1"}, Name: UserName{"taro"}}
+	tests := []struct {
+		name  string
+		actor Actor
+		want  error
+	}{
+		{name: "self", actor: actorOf("1"), want: nil},
+		{name: "another user", actor: actorOf("2"), want: ErrPermission},
+		{name: "anonymous", actor: ANONYMOUS_ACTOR, want: ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewAuthorizationPolicy().CanManageUser(tt.actor, ACTION_USER_UPDATE, &user).Err()
+			assert.Equal(t, true, errors.Is(err, tt.want),
+				fmt.Sprintf("CanManageUser() error = %v, want %v", err, tt.want))
+		})
+	}
+}
+
+func TestAuthorizationPolicy_CanViewEmail(t *testing.T) {
+	user := User{Id: UserId{"1"}, Name: UserName{"taro"}}
+	tests := []struct {
+		name  string
+		actor Actor
+		want  bool
+	}{
+		{name: "self", actor: actorOf("1"), want: true},
+		{name: "admin", actor: Actor{UserId: UserId{"2"}, Role: USER_ROLE_ADMIN}, want: true},
+		{name: "another user", actor: actorOf("2"), want: false},
+		{name: "anonymous", actor: ANONYMOUS_ACTOR, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := NewAuthorizationPolicy().CanViewEmail(tt.actor, &user)
+			assert.Equal(t, tt.want, decision.Allowed, fmt.Sprintf("CanViewEmail() = %v", decision.Reason))
+		})
+	}
+}
+
+func TestAuthorizationPolicy_CanViewCircle(t *testing.T) {
+	tests := []struct {
+		name    string
+		private bool
+		actor   Actor
+		want    bool
+	}{
+		{name: "public to anonymous", private: false, actor: ANONYMOUS_ACTOR, want: true},
+		{name: "private to anonymous", private: true, actor: ANONYMOUS_ACTOR, want: false},
+		{name: "private to a member", private: true, actor: actorOf("2"), want: true},
+		{name: "private to the owner", private: true, actor: actorOf("1"), want: true},
+		{name: "private to an outsider", private: true, actor: actorOf("9"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			circle := newTestCircle()
+			if tt.private {
+				circle.MakePrivate()
+			}
+			got := NewAuthorizationPolicy().CanViewCircle(tt.actor, &circle)
+			assert.Equal(t, tt.want, got.Allowed,
+				fmt.Sprintf("CanViewCircle() got = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func TestAuthorizationPolicy_CanAppointModerator(t *testing.T) {
+	tests := []struct {
+		name   string
+		actor  Actor
+		want   bool
+		reason string
+	}{
+		{name: "owner", actor: actorOf("1"), want: true, reason: "actor is the owner"},
+		{name: "moderator", actor: actorOf("3"), want: false, reason: "only the owner can appoint moderators"},
+		{name: "member", actor: actorOf("2"), want: false, reason: "only the owner can appoint moderators"},
+		{name: "anonymous", actor: ANONYMOUS_ACTOR, want: false, reason: "authentication required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			circle := newTestCircle()
+			assert.Nil(t, circle.AppointModerator(&User{Id: UserId{"3"}}))
+			got := NewAuthorizationPolicy().CanAppointModerator(tt.actor, &circle, UserId{"2"})
+			assert.Equal(t, tt.want, got.Allowed,
+				fmt.Sprintf("CanAppointModerator() got = %v, want %v", got, tt.want))
+			assert.Equal(t, tt.reason, got.Reason)
+		})
+	}
+}
+
+func TestAuthorizationPolicy_CanKickMember(t *testing.T) {
+	tests := []struct {
+		name   string
+		actor  Actor
+		member UserId
+		want   bool
+		reason string
+	}{
+		{name: "owner kicks a member", actor: actorOf("1"), member: UserId{"2"}, want: true, reason: "actor is the owner"},
+		{name: "owner kicks a moderator", actor: actorOf("1"), member: UserId{"3"}, want: true, reason: "actor is the owner"},
+		{name: "moderator kicks a member", actor: actorOf("3"), member: UserId{"2"}, want: true, reason: "actor is a moderator"},
+		{name: "moderator kicks another moderator", actor: actorOf("3"), member: UserId{"4"}, want: false, reason: "only the owner can remove moderators"},
+		{name: "member leaves", actor: actorOf("2"), member: UserId{"2"}, want: true, reason: "member leaves the circle"},
+		{name: "member kicks a member", actor: actorOf("2"), member: UserId{"5"}, want: false, reason: "only the owner and moderators can remove members"},
+		{name: "nobody kicks the owner", actor: actorOf("1"), member: UserId{"1"}, want: false, reason: "the owner can not be removed"},
+		{name: "anonymous", actor: ANONYMOUS_ACTOR, member: UserId{"2"}, want: false, reason: "authentication required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			circle := newTestCircle()
+			for _, id := range []string{"3", "4"} {
+				assert.Nil(t, circle.AppointModerator(&User{Id: UserId{id}}))
+			}
+			got := NewAuthorizationPolicy().CanKickMember(tt.actor, &circle, tt.member)
+			assert.Equal(t, tt.want, got.Allowed,
+				fmt.Sprintf("CanKickMember() got = %v, want %v", got, tt.want))
+			assert.Equal(t, tt.reason, got.Reason)
+		})
+	}
+}
+
+func TestAuthorizationPolicy_CanCreatePost(t *testing.T) {
+	tests := []struct {
+		name   string
+		actor  Actor
+		status UserStatus
+		want   bool
+		reason string
+	}{
+		{name: "member", actor: actorOf("2"), status: USER_STATUS_ACTIVE, want: true, reason: "actor is a member"},
+		{name: "suspended member", actor: actorOf("2"), status: USER_STATUS_SUSPENDED, want: false, reason: "user is suspended"},
+		{name: "outsider", actor: actorOf("9"), status: USER_STATUS_ACTIVE, want: false, reason: "only members can post to the circle"},
+		{name: "anonymous", actor: ANONYMOUS_ACTOR, status: USER_STATUS_ACTIVE, want: false, reason: "authentication required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			circle := newTestCircle()
+			author := userOf(tt.actor.UserId.V, tt.status)
+			got := NewAuthorizationPolicy().CanCreatePost(tt.actor, &circle, &author)
+			assert.Equal(t, tt.want, got.Allowed,
+				fmt.Sprintf("CanCreatePost() got = %v, want %v", got, tt.want))
+			assert.Equal(t, tt.reason, got.Reason)
+		})
+	}
+}
+
+func TestAuthorizationPolicy_CanEditPost(t *testing.T) {
+	tests := []struct {
+		name   string
+		actor  Actor
+		status UserStatus
+		want   bool
+		reason string
+	}{
+		{name: "author", actor: actorOf("2"), status: USER_STATUS_ACTIVE, want: true, reason: "actor is the author"},
+		{name: "deactivated author", actor: actorOf("2"), status: USER_STATUS_DEACTIVATED, want: false, reason: "user is deactivated"},
+		{name: "owner", actor: actorOf("1"), status: USER_STATUS_ACTIVE, want: false, reason: "only the author can edit the post"},
+		{name: "outsider", actor: actorOf("9"), status: USER_STATUS_ACTIVE, want: false, reason: "only members can edit posts"},
+		{name: "anonymous", actor: ANONYMOUS_ACTOR, status: USER_STATUS_ACTIVE, want: false, reason: "authentication required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			circle := newTestCircle()
+			post := newTestPost()
+			editor := userOf(tt.actor.UserId.V, tt.status)
+			got := NewAuthorizationPolicy().CanEditPost(tt.actor, &circle, &post, &editor)
+			assert.Equal(t, tt.want, got.Allowed,
+				fmt.Sprintf("CanEditPost() got = %v, want %v", got, tt.want))
+			assert.Equal(t, tt.reason, got.Reason)
+		})
+	}
+}
+
+func TestAuthorizationPolicy_CanDeletePost(t *testing.T) {
+	tests := []struct {
+		name   string
+		actor  Actor
+		status UserStatus
+		want   bool
+		reason string
+	}{
+		{name: "author", actor: actorOf("2"), status: USER_STATUS_ACTIVE, want: true, reason: "actor is the author"},
+		{name: "owner", actor: actorOf("1"), status: USER_STATUS_ACTIVE, want: true, reason: "actor moderates the circle"},
+		{name: "moderator", actor: actorOf("3"), status: USER_STATUS_ACTIVE, want: true, reason: "actor moderates the circle"},
+		{name: "suspended moderator", actor: actorOf("3"), status: USER_STATUS_SUSPENDED, want: false, reason: "user is suspended"},
+		{name: "member", actor: actorOf("4"), status: USER_STATUS_ACTIVE, want: false, reason: "only the author, owner and moderators can delete the post"},
+		{name: "anonymous", actor: ANONYMOUS_ACTOR, status: USER_STATUS_ACTIVE, want: false, reason: "authentication required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			circle := newTestCircle()
+			assert.Nil(t, circle.AppointModerator(&User{Id: UserId{"3"}}))
+			post := newTestPost()
+			deleter := userOf(tt.actor.UserId.V, tt.status)
+			got := NewAuthorizationPolicy().CanDeletePost(tt.actor, &circle, &post, &deleter)
+			assert.Equal(t, tt.want, got.Allowed,
+				fmt.Sprintf("CanDeletePost() got = %v, want %v", got, tt.want))
+			assert.Equal(t, tt.reason, got.Reason)
+		})
+	}
+}
+
+func TestAuthorizationPolicy_CanScheduleEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		actor  Actor
+		status UserStatus
+		want   bool
+		reason string
+	}{
+		{name: "owner", actor: actorOf("1"), status: USER_STATUS_ACTIVE, want: true, reason: "actor moderates the circle"},
+		{name: "moderator", actor: actorOf("3"), status: USER_STATUS_ACTIVE, want: true, reason: "actor moderates the circle"},
+		{name: "suspended owner", actor: actorOf("1"), status: USER_STATUS_SUSPENDED, want: false, reason: "user is suspended"},
+		{name: "member", actor: actorOf("2"), status: USER_STATUS_ACTIVE, want: false, reason: "only the owner and moderators can schedule events"},
+		{name: "anonymous", actor: ANONYMOUS_ACTOR, status: USER_STATUS_ACTIVE, want: false, reason: "authentication required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			circle := newTestCircle()
+			assert.Nil(t, circle.AppointModerator(&User{Id: UserId{"3"}}))
+			organizer := userOf(tt.actor.UserId.V, tt.status)
+			got := NewAuthorizationPolicy().CanScheduleEvent(tt.actor, &circle, &organizer)
+			assert.Equal(t, tt.want, got.Allowed,
+				fmt.Sprintf("CanScheduleEvent() got = %v, want %v", got, tt.want))
+			assert.Equal(t, tt.reason, got.Reason)
+		})
+	}
+}
+
+func TestAuthorizationPolicy_CanRespondToEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		actor  Actor
+		status UserStatus
+		want   bool
+		reason string
+	}{
+		{name: "member", actor: actorOf("2"), status: USER_STATUS_ACTIVE, want: true, reason: "actor is a member"},
+		{name: "deactivated member", actor: actorOf("2"), status: USER_STATUS_DEACTIVATED, want: false, reason: "user is deactivated"},
+		{name: "outsider", actor: actorOf("9"), status: USER_STATUS_ACTIVE, want: false, reason: "only members can respond to the event"},
+		{name: "anonymous", actor: ANONYMOUS_ACTOR, status: USER_STATUS_ACTIVE, want: false, reason: "authentication required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			circle := newTestCircle()
+			event := Event{Id: EventId{"1"}, CircleId: circle.Id()}
+			member := userOf(tt.actor.UserId.V, tt.status)
+			got := NewAuthorizationPolicy().CanRespondToEvent(tt.actor, &circle, &event, &member)
+			assert.Equal(t, tt.want, got.Allowed,
+				fmt.Sprintf("CanRespondToEvent() got = %v, want %v", got, tt.want))
+			assert.Equal(t, tt.reason, got.Reason)
+		})
+	}
+}
+
+func TestEnforce(t *testing.T) {
+	auditLog := &recordingAuditLog{}
+	policy := NewAuthorizationPolicy()
+	user := User{Id: UserId{"1"}}
+
+	assert.Nil(t, Enforce(policy.CanManageUser(actorOf("1"), ACTION_USER_DELETE, &user), auditLog))
+	assert.Equal(t, 0, len(auditLog.decisions), "allowed decisions are not recorded")
+
+	err := Enforce(policy.CanManageUser(actorOf("2"), ACTION_USER_DELETE, &user), auditLog)
+	assert.Equal(t, "cannot delete user: only the user themself can delete user", err.Error())
+	assert.Equal(t, []Decision{{
+		Allowed:  false,
+		Action:   ACTION_USER_DELETE,
+		Actor:    actorOf("2"),
+		Resource: "user:1",
+		Reason:   "only the user themself can delete user",
+	}}, auditLog.decisions)
+}
+
+// post 1 of user 2 in the test circle
+func newTestPost() Post {
+	post, _ := NewPost(PostId{"1"}, CircleId{"1"}, UserId{"2"}, PostBody{"hello"}, time.Now())
+	return post
+}
+
+func userOf(id string, status UserStatus) User {
+	return User{Id: UserId{id}, Status: UserStatusRecord{Status: status}}
+}
+
+// owner 1, members 2 to 5
+func newTestCircle() Circle {
+	id := CircleId{"1"}
+	name := CircleName{"circle"}
+	owner := UserId{"1"}
+	circle, _ := NewCircle(&id, &name, &owner, []UserId{{"2"}, {"3"}, {"4"}, {"5"}})
+	return circle
+}
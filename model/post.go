@@ -0,0 +1,169 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type (
+	PostId struct {
+		V string
+	}
+
+	PostBody struct {
+		V string
+	}
+
+	// Aggregate Root
+	// refers to Circle and User only by their ids
+	Post struct {
+		Id       PostId
+		CircleId CircleId
+		Author   UserId
+		Body     PostBody
+		Created  time.Time
+		Updated  time.Time
+	}
+
+	PostRequestModel struct {
+		Body string `json:"body" form:"body"`
+	}
+
+	PostResponseModel struct {
+		Id       string `json:"id"`
+		CircleId string `json:"circleId"`
+		Author   string `json:"author"`
+		Body     string `json:"body"`
+		Created  string `json:"created"`
+		Updated  string `json:"updated"`
+	}
+
+	PostListResponseModel struct {
+		Posts []PostResponseModel `json:"posts"`
+	}
+
+	IPostRepository interface {
+		Save(post Post) error
+		FindById(id *PostId) (*Post, error)
+		FindByCircle(circleId *CircleId) ([]Post, error)
+		Delete(post Post) error
+	}
+
+	IPostFactory interface {
+		Create(circleId *CircleId, author *UserId, body *PostBody, created time.Time) (*Post, error)
+	}
+)
+
+func NewPostId(v string) (PostId, error) {
+	if len(v) == 0 {
+		return PostId{}, NewValidationError("id", "is required")
+	}
+	return PostId{V: v}, nil
+}
+
+func NewPostBody(v string) (PostBody, error) {
+	if len(strings.TrimSpace(v)) == 0 {
+		return PostBody{}, NewValidationError("body", "is required")
+	}
+	if utf8.RuneCountInString(v) > 1000 {
+		return PostBody{}, NewValidationError("body", "must be at most 1000 characters")
+	}
+	return PostBody{V: v}, nil
+}
+
+func NewPost(id PostId, circleId CircleId, author UserId, body PostBody, created time.Time) (Post, error) {
+	if len(id.V) == 0 {
+		return Post{}, NewValidationError("id", "is required")
+	}
+	if len(circleId.V) == 0 {
+		return Post{}, NewValidationError("circleId", "is required")
+	}
+	if len(author.V) == 0 {
+		return Post{}, NewValidationError("author", "is required")
+	}
+	if len(body.V) == 0 {
+		return Post{}, NewValidationError("body", "is required")
+	}
+
+	return Post{Id: id, CircleId: circleId, Author: author, Body: body, Created: created, Updated: created}, nil
+}
+
+func (p *Post) IsAuthoredBy(id UserId) bool {
+	return p.Author.V == id.V
+}
+
+// only the author can edit the post
+func (p *Post) Edit(editor UserId, body *PostBody, updated time.Time) error {
+	if body == nil {
+		return NewValidationError("body", "is required")
+	}
+	if !p.IsAuthoredBy(editor) {
+		return NewPermissionError("edit post", "only the author can edit the post")
+	}
+	p.Body = *body
+	p.Updated = updated
+	return nil
+}
+
+// the author, owner and moderators of the circle can delete the post
+func (p *Post) CanBeDeletedBy(id UserId, circle *Circle) bool {
+	if p.IsAuthoredBy(id) {
+		return true
+	}
+	if circle == nil || circle.Id().V != p.CircleId.V {
+		return false
+	}
+	return circle.CanModerate(id)
+}
+
+func NewPostResponseModel(post Post) *PostResponseModel {
+	return &PostResponseModel{
+		Id:       post.Id.V,
+		CircleId: post.CircleId.V,
+		Author:   post.Author.V,
+		Body:     post.Body.V,
+		Created:  post.Created.Format(time.RFC3339),
+		Updated:  post.Updated.Format(time.RFC3339),
+	}
+}
+
+func NewPostListResponseModel(posts []Post) *PostListResponseModel {
+	responses := []PostResponseModel{}
+	for _, post := range posts {
+		responses = append(responses, *NewPostResponseModel(post))
+	}
+	return &PostListResponseModel{Posts: responses}
+}
+
+func (m *PostResponseModel) CSVHeader() []string {
+	return []string{"id", "circleId", "author", "body", "created", "updated"}
+}
+
+func (m *PostResponseModel) CSVRecords() [][]string {
+	return [][]string{{m.Id, m.CircleId, m.Author, m.Body, m.Created, m.Updated}}
+}
+
+func (m *PostResponseModel) Text() string {
+	return m.Id + " " + m.Author + " " + m.Body
+}
+
+func (m *PostListResponseModel) CSVHeader() []string {
+	return (&PostResponseModel{}).CSVHeader()
+}
+
+func (m *PostListResponseModel) CSVRecords() [][]string {
+	records := [][]string{}
+	for _, post := range m.Posts {
+		records = append(records, post.CSVRecords()...)
+	}
+	return records
+}
+
+func (m *PostListResponseModel) Text() string {
+	var output string
+	for _, post := range m.Posts {
+		output += post.Text() + "\n"
+	}
+	return output
+}
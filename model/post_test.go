@@ -0,0 +1,149 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPostBody(t *testing.T) {
+	type wants struct {
+		body PostBody
+		err  error
+	}
+	tests := []struct {
+		name  string
+		v     string
+		wants wants
+	}{
+		{
+			name:  "normal",
+			v:     "hello circle",
+			wants: wants{body: PostBody{V: "hello circle"}, err: nil},
+		},
+		{
+			name:  "empty",
+			v:     "",
+			wants: wants{body: PostBody{}, err: ErrValidation},
+		},
+		{
+			name:  "whitespace only",
+			v:     " \n\t",
+			wants: wants{body: PostBody{}, err: ErrValidation},
+		},
+		{
+			name:  "1000 characters",
+			v:     strings.Repeat("あ", 1000),
+			wants: wants{body: PostBody{V: strings.Repeat("あ", 1000)}, err: nil},
+		},
+		{
+			name:  "1001 characters",
+			v:     strings.Repeat("あ", 1001),
+			wants: wants{body: PostBody{}, err: ErrValidation},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := NewPostBody(tt.v)
+			assert.Equal(t, true, reflect.DeepEqual(body, tt.wants.body),
+				fmt.Sprintf("NewPostBody() got = %v, want %v", body, tt.wants.body))
+
+			assert.Equal(t, true, errors.Is(err, tt.wants.err),
+				fmt.Sprintf("NewPostBody() error = %v, want %v", err, tt.wants.err))
+		})
+	}
+}
+
+func TestPost_Edit(t *testing.T) {
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := created.Add(time.Hour)
+	type args struct {
+		editor UserId
+		body   *PostBody
+	}
+	type wants struct {
+		err  error
+		post Post
+	}
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name: "edited by author",
+			args: args{editor: UserId{"1"}, body: &PostBody{"edited"}},
+			wants: wants{
+				err:  nil,
+				post: Post{Id: PostId{"1"}, CircleId: CircleId{"1"}, Author: UserId{"1"}, Body: PostBody{"edited"}, Created: created, Updated: updated},
+			},
+		},
+		{
+			name: "edited by other user",
+			args: args{editor: UserId{"2"}, body: &PostBody{"edited"}},
+			wants: wants{
+				err:  ErrPermission,
+				post: Post{Id: PostId{"1"}, CircleId: CircleId{"1"}, Author: UserId{"1"}, Body: PostBody{"original"}, Created: created, Updated: created},
+			},
+		},
+		{
+			name: "body is nil",
+			args: args{editor: UserId{"1"}, body: nil},
+			wants: wants{
+				err:  ErrValidation,
+				post: Post{Id: PostId{"1"}, CircleId: CircleId{"1"}, Author: UserId{"1"}, Body: PostBody{"original"}, Created: created, Updated: created},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, _ := NewPost(PostId{"1"}, CircleId{"1"}, UserId{"1"}, PostBody{"original"}, created)
+			err := post.Edit(tt.args.editor, tt.args.body, updated)
+
+			assert.Equal(t, true, errors.Is(err, tt.wants.err),
+				fmt.Sprintf("Post.Edit() error = %v, want %v", err, tt.wants.err))
+
+			assert.Equal(t, true, reflect.DeepEqual(post, tt.wants.post),
+				fmt.Sprintf("Post.Edit() got = %v, want %v", post, tt.wants.post))
+		})
+	}
+}
+
+func TestPost_CanBeDeletedBy(t *testing.T) {
+	circleId := CircleId{"1"}
+	circleName := CircleName{"circle"}
+	owner := UserId{"1"}
+	circle, _ := NewCircle(&circleId, &circleName, &owner, []UserId{{"2"}, {"3"}, {"4"}})
+	circle.AppointModerator(&User{Id: UserId{"2"}})
+
+	otherCircleId := CircleId{"2"}
+	otherCircle, _ := NewCircle(&otherCircleId, &circleName, &UserId{"4"}, []UserId{})
+
+	post, _ := NewPost(PostId{"1"}, circleId, UserId{"3"}, PostBody{"hello"}, time.Now())
+
+	tests := []struct {
+		name   string
+		id     UserId
+		circle *Circle
+		want   bool
+	}{
+		{name: "author", id: UserId{"3"}, circle: &circle, want: true},
+		{name: "owner", id: UserId{"1"}, circle: &circle, want: true},
+		{name: "moderator", id: UserId{"2"}, circle: &circle, want: true},
+		{name: "member", id: UserId{"4"}, circle: &circle, want: false},
+		{name: "outsider", id: UserId{"5"}, circle: &circle, want: false},
+		{name: "owner of another circle", id: UserId{"4"}, circle: &otherCircle, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := post.CanBeDeletedBy(tt.id, tt.circle)
+			assert.Equal(t, tt.want, got,
+				fmt.Sprintf("Post.CanBeDeletedBy() = %v, want %v", got, tt.want))
+		})
+	}
+}
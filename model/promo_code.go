@@ -0,0 +1,142 @@
+package model
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// codes are compared in upper case
+	PromoCodeValue struct {
+		V string
+	}
+
+	// Aggregate Root
+	// PromoCode grants a trial of Plan for TrialDays to at most MaxRedemptions users until ExpiresAt
+	PromoCode struct {
+		Code           PromoCodeValue
+		Plan           UserType
+		TrialDays      int
+		MaxRedemptions int
+		ExpiresAt      time.Time
+		RedeemedBy     []UserId
+	}
+
+	IPromoCodeRepository interface {
+		Save(promoCode PromoCode) error
+		FindByCode(code *PromoCodeValue) (*PromoCode, error)
+	}
+
+	PromoCodeRequestModel struct {
+		Code           string `json:"code" form:"code"`
+		Plan           string `json:"plan" form:"plan"`
+		TrialDays      int    `json:"trialDays" form:"trial_days"`
+		MaxRedemptions int    `json:"maxRedemptions" form:"max_redemptions"`
+		ExpiresAt      string `json:"expiresAt" form:"expires_at"`
+	}
+
+	PromoCodeRedeemRequestModel struct {
+		Code string `json:"code" form:"code"`
+	}
+
+	PromoCodeResponseModel struct {
+		Code           string `json:"code"`
+		Plan           string `json:"plan"`
+		TrialDays      int    `json:"trialDays"`
+		MaxRedemptions int    `json:"maxRedemptions"`
+		Redemptions    int    `json:"redemptions"`
+		ExpiresAt      string `json:"expiresAt"`
+	}
+)
+
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9-]{4,32}$`)
+
+func NewPromoCodeValue(v string) (PromoCodeValue, error) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	if len(v) == 0 {
+		return PromoCodeValue{}, NewValidationError("code", "is required")
+	}
+	if !promoCodePattern.MatchString(v) {
+		return PromoCodeValue{}, NewValidationError("code", "must be 4 to 32 letters, digits or -")
+	}
+	return PromoCodeValue{V: v}, nil
+}
+
+func NewPromoCode(code PromoCodeValue, plan UserType, trialDays int, maxRedemptions int, expiresAt time.Time) (PromoCode, error) {
+	errs := NewValidationErrors()
+	if len(code.V) == 0 {
+		errs.Add("code", NewValidationError("code", "is required"))
+	}
+	if !DefaultPlanRegistry.IsPremium(plan) {
+		errs.Add("plan", NewValidationError("plan", "must be a premium plan"))
+	}
+	if trialDays < 1 || trialDays > 365 {
+		errs.Add("trialDays", NewValidationError("trialDays", "must be between 1 and 365"))
+	}
+	if maxRedemptions < 1 {
+		errs.Add("maxRedemptions", NewValidationError("maxRedemptions", "must be at least 1"))
+	}
+	if expiresAt.IsZero() {
+		errs.Add("expiresAt", NewValidationError("expiresAt", "is required"))
+	}
+	if err := errs.Err(); err != nil {
+		return PromoCode{}, err
+	}
+	return PromoCode{
+		Code:           code,
+		Plan:           plan,
+		TrialDays:      trialDays,
+		MaxRedemptions: maxRedemptions,
+		ExpiresAt:      expiresAt,
+		RedeemedBy:     []UserId{},
+	}, nil
+}
+
+// Redeem records that the user used the code. A user can redeem a code once.
+func (p *PromoCode) Redeem(userId UserId, at time.Time) error {
+	if !at.Before(p.ExpiresAt) {
+		return NewConflictError("promo code", "has expired")
+	}
+	for _, id := range p.RedeemedBy {
+		if id.V == userId.V {
+			return NewConflictError("promo code", "has already been redeemed by the user")
+		}
+	}
+	if len(p.RedeemedBy) >= p.MaxRedemptions {
+		return NewCapacityError("promo code", p.MaxRedemptions)
+	}
+	p.RedeemedBy = append(p.RedeemedBy, userId)
+	return nil
+}
+
+// Clone returns a copy which shares no redemptions with the promo code
+func (p *PromoCode) Clone() PromoCode {
+	clone := *p
+	clone.RedeemedBy = append([]UserId{}, p.RedeemedBy...)
+	return clone
+}
+
+func NewPromoCodeResponseModel(promoCode PromoCode) *PromoCodeResponseModel {
+	return &PromoCodeResponseModel{
+		Code:           promoCode.Code.V,
+		Plan:           promoCode.Plan.V,
+		TrialDays:      promoCode.TrialDays,
+		MaxRedemptions: promoCode.MaxRedemptions,
+		Redemptions:    len(promoCode.RedeemedBy),
+		ExpiresAt:      promoCode.ExpiresAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func (m *PromoCodeResponseModel) CSVHeader() []string {
+	return []string{"code", "plan", "trialDays", "maxRedemptions", "redemptions", "expiresAt"}
+}
+
+func (m *PromoCodeResponseModel) CSVRecords() [][]string {
+	return [][]string{{m.Code, m.Plan, strconv.Itoa(m.TrialDays), strconv.Itoa(m.MaxRedemptions), strconv.Itoa(m.Redemptions), m.ExpiresAt}}
+}
+
+func (m *PromoCodeResponseModel) Text() string {
+	return m.Code + " " + m.Plan + " for " + strconv.Itoa(m.TrialDays) + " days"
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPromoCodeValue(t *testing.T) {
+	tests := []struct {
+		name string
+		v    string
+		want PromoCodeValue
+		err  error
+	}{
+		{name: "upper cased", v: " welcome-2023 ", want: PromoCodeValue{"WELCOME-2023"}, err: nil},
+		{name: "empty", v: "", err: ErrValidation},
+		{name: "too short", v: "abc", err: ErrValidation},
+		{name: "space", v: "wel come", err: ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewPromoCodeValue(tt.v)
+			assert.Equal(t, true, errors.Is(err, tt.err),
+				fmt.Sprintf("NewPromoCodeValue() error = %v, want %v", err, tt.err))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewPromoCode(t *testing.T) {
+	expiresAt := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		plan           UserType
+		trialDays      int
+		maxRedemptions int
+		want           error
+	}{
+		{name: "premium", plan: USER_TYPE_PREMIUM, trialDays: 14, maxRedemptions: 100, want: nil},
+		{name: "normal", plan: USER_TYPE_NORMAL, trialDays: 14, maxRedemptions: 100, want: ErrValidation},
+		{name: "no trial days", plan: USER_TYPE_PREMIUM, trialDays: 0, maxRedemptions: 100, want: ErrValidation},
+		{name: "no redemptions", plan: USER_TYPE_PREMIUM, trialDays: 14, maxRedemptions: 0, want: ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPromoCode(PromoCodeValue{"WELCOME"}, tt.plan, tt.trialDays, tt.maxRedemptions, expiresAt)
+			assert.Equal(t, true, errors.Is(err, tt.want),
+				fmt.Sprintf("NewPromoCode() error = %v, want %v", err, tt.want))
+		})
+	}
+}
+
+func TestPromoCode_Redeem(t *testing.T) {
+	expiresAt := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	now := expiresAt.Add(-time.Hour)
+	promoCode, err := NewPromoCode(PromoCodeValue{"WELCOME"}, USER_TYPE_PREMIUM, 14, 2, expiresAt)
+	assert.Nil(t, err)
+
+	assert.Nil(t, promoCode.Redeem(UserId{"1"}, now))
+	assert.Equal(t, true, errors.Is(promoCode.Redeem(UserId{"1"}, now), ErrConflict))
+	assert.Nil(t, promoCode.Redeem(UserId{"2"}, now))
+	assert.Equal(t, true, errors.Is(promoCode.Redeem(UserId{"3"}, now), ErrCapacity))
+	assert.Equal(t, []UserId{{"1"}, {"2"}}, promoCode.RedeemedBy)
+
+	promoCode.MaxRedemptions = 10
+	assert.Equal(t, true, errors.Is(promoCode.Redeem(UserId{"3"}, expiresAt), ErrConflict))
+}
+
+func TestTrial(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	promoCode := PromoCode{Code: PromoCodeValue{"WELCOME"}, Plan: USER_TYPE_PREMIUM, TrialDays: 14}
+	trial, err := NewTrial(SubscriptionId{"1"}, UserId{"1"}, promoCode, start)
+	assert.Nil(t, err)
+	assert.Equal(t, true, trial.IsTrial())
+	assert.Equal(t, start.AddDate(0, 0, 14), trial.End)
+	assert.Equal(t, true, errors.Is(trial.Renew(1), ErrConflict))
+
+	assert.Nil(t, trial.EndTrial(start.Add(time.Hour)))
+	assert.Equal(t, SUBSCRIPTION_STATUS_EXPIRED, trial.Status(start.Add(time.Hour)))
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"time"
+)
+
+type (
+	// Aggregate Root
+	// Token keeps only the hash of the token handed to the user
+	Session struct {
+		Token     string
+		UserId    UserId
+		Created   time.Time
+		ExpiresAt time.Time
+	}
+
+	ISessionRepository interface {
+		Save(session Session) error
+		FindByToken(tokenHash string) (*Session, error)
+		Delete(session Session) error
+		DeleteByUserId(id *UserId) error
+	}
+
+	LoginRequestModel struct {
+		Name     string `json:"name" form:"name"`
+		Password string `json:"password" form:"password"`
+	}
+
+	SessionResponseModel struct {
+		Token     string `json:"token"`
+		UserId    string `json:"userId"`
+		ExpiresAt string `json:"expiresAt"`
+	}
+)
+
+func NewSession(tokenHash string, userId UserId, created time.Time, expiresAt time.Time) (Session, error) {
+	if len(tokenHash) == 0 {
+		return Session{}, NewValidationError("token", "is required")
+	}
+	if len(userId.V) == 0 {
+		return Session{}, NewValidationError("userId", "is required")
+	}
+	if !expiresAt.After(created) {
+		return Session{}, NewValidationError("expiresAt", "must be after created")
+	}
+	return Session{Token: tokenHash, UserId: userId, Created: created, ExpiresAt: expiresAt}, nil
+}
+
+func (s *Session) IsExpired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+func NewSessionResponseModel(token string, userId string, expiresAt time.Time) *SessionResponseModel {
+	return &SessionResponseModel{Token: token, UserId: userId, ExpiresAt: expiresAt.UTC().Format(time.RFC3339)}
+}
+
+func (m *SessionResponseModel) CSVHeader() []string {
+	return []string{"token", "userId", "expiresAt"}
+}
+
+func (m *SessionResponseModel) CSVRecords() [][]string {
+	return [][]string{{m.Token, m.UserId, m.ExpiresAt}}
+}
+
+func (m *SessionResponseModel) Text() string {
+	return m.Token
+}
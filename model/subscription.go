@@ -0,0 +1,318 @@
+package model
+
+import (
+	"strconv"
+	"time"
+)
+
+var (
+	SUBSCRIPTION_STATUS_ACTIVE    = SubscriptionStatus{V: "active"}
+	SUBSCRIPTION_STATUS_CANCELLED = SubscriptionStatus{V: "cancelled"}
+	SUBSCRIPTION_STATUS_EXPIRED   = SubscriptionStatus{V: "expired"}
+)
+
+type (
+	SubscriptionId struct {
+		V string
+	}
+
+	// cancelled subscriptions stay active until their end but are not renewed
+	SubscriptionStatus struct {
+		V string
+	}
+
+	// Aggregate Root
+	// a plan of a user for the period from Start to End.
+	// Months is the length of a renewal. Trials are granted by a promo code and are never renewed.
+	Subscription struct {
+		Id        SubscriptionId
+		UserId    UserId
+		Plan      UserType
+		Months    int
+		Start     time.Time
+		End       time.Time
+		AutoRenew bool
+		Cancelled time.Time
+		Expired   time.Time
+		PromoCode PromoCodeValue
+	}
+
+	ISubscriptionRepository interface {
+		Save(subscription Subscription) error
+		FindById(id *SubscriptionId) (*Subscription, error)
+		// the latest subscription of the user
+		FindByUserId(id *UserId) (*Subscription, error)
+		// subscriptions which are not expired yet but ended at or before t
+		FindDue(t time.Time) ([]Subscription, error)
+	}
+
+	ISubscriptionFactory interface {
+		Create(userId *UserId, plan *UserType, months int, start time.Time, autoRenew bool) (*Subscription, error)
+		CreateTrial(userId *UserId, promoCode *PromoCode, start time.Time) (*Subscription, error)
+	}
+
+	// PlanChange records a change of the plan of a user
+	PlanChange struct {
+		UserId  UserId
+		From    UserType
+		To      UserType
+		Reason  string
+		Changed time.Time
+	}
+
+	IPlanChangeRepository interface {
+		Save(change PlanChange) error
+		// changes of the user in the order they were made
+		FindByUserId(id *UserId) ([]PlanChange, error)
+	}
+
+	SubscriptionRequestModel struct {
+		Plan      string `json:"plan" form:"plan"`
+		Months    int    `json:"months" form:"months"`
+		AutoRenew bool   `json:"autoRenew" form:"auto_renew"`
+	}
+
+	SubscriptionResponseModel struct {
+		Id        string `json:"id"`
+		UserId    string `json:"userId"`
+		Plan      string `json:"plan"`
+		Status    string `json:"status"`
+		Start     string `json:"start"`
+		End       string `json:"end"`
+		AutoRenew bool   `json:"autoRenew"`
+		Trial     bool   `json:"trial"`
+	}
+
+	PlanChangeRequestModel struct {
+		Plan   string `json:"plan" form:"plan"`
+		Reason string `json:"reason" form:"reason"`
+	}
+
+	PlanChangeResponseModel struct {
+		UserId  string `json:"userId"`
+		From    string `json:"from"`
+		To      string `json:"to"`
+		Reason  string `json:"reason"`
+		Changed string `json:"changed"`
+	}
+
+	PlanChangeListResponseModel struct {
+		Changes []PlanChangeResponseModel `json:"changes"`
+	}
+)
+
+func NewSubscriptionId(v string) (SubscriptionId, error) {
+	if len(v) == 0 {
+		return SubscriptionId{}, NewValidationError("id", "is required")
+	}
+	return SubscriptionId{V: v}, nil
+}
+
+func NewSubscriptionMonths(v int) (int, error) {
+	if v < 1 || v > 36 {
+		return 0, NewValidationError("months", "must be between 1 and 36")
+	}
+	return v, nil
+}
+
+func NewSubscription(id SubscriptionId, userId UserId, plan UserType, months int, start time.Time, autoRenew bool) (Subscription, error) {
+	if len(id.V) == 0 {
+		return Subscription{}, NewValidationError("id", "is required")
+	}
+	if len(userId.V) == 0 {
+		return Subscription{}, NewValidationError("userId", "is required")
+	}
+	if len(plan.V) == 0 {
+		return Subscription{}, NewValidationError("plan", "is required")
+	}
+	if _, err := NewSubscriptionMonths(months); err != nil {
+		return Subscription{}, err
+	}
+	return Subscription{
+		Id:        id,
+		UserId:    userId,
+		Plan:      plan,
+		Months:    months,
+		Start:     start,
+		End:       start.AddDate(0, months, 0),
+		AutoRenew: autoRenew,
+	}, nil
+}
+
+// NewTrial starts a trial of the plan the promo code grants
+func NewTrial(id SubscriptionId, userId UserId, promoCode PromoCode, start time.Time) (Subscription, error) {
+	if len(id.V) == 0 {
+		return Subscription{}, NewValidationError("id", "is required")
+	}
+	if len(userId.V) == 0 {
+		return Subscription{}, NewValidationError("userId", "is required")
+	}
+	return Subscription{
+		Id:        id,
+		UserId:    userId,
+		Plan:      promoCode.Plan,
+		Start:     start,
+		End:       start.AddDate(0, 0, promoCode.TrialDays),
+		PromoCode: promoCode.Code,
+	}, nil
+}
+
+func (s *Subscription) IsTrial() bool {
+	return len(s.PromoCode.V) != 0
+}
+
+func (s *Subscription) IsActive(now time.Time) bool {
+	return s.Expired.IsZero() && !now.Before(s.Start) && now.Before(s.End)
+}
+
+func (s *Subscription) IsCancelled() bool {
+	return !s.Cancelled.IsZero()
+}
+
+func (s *Subscription) Status(now time.Time) SubscriptionStatus {
+	switch {
+	case !s.IsActive(now):
+		return SUBSCRIPTION_STATUS_EXPIRED
+	case s.IsCancelled():
+		return SUBSCRIPTION_STATUS_CANCELLED
+	}
+	return SUBSCRIPTION_STATUS_ACTIVE
+}
+
+// Renew extends the end by months. Cancelled or expired subscriptions can not be renewed.
+func (s *Subscription) Renew(months int) error {
+	months, err := NewSubscriptionMonths(months)
+	if err != nil {
+		return err
+	}
+	if !s.Expired.IsZero() {
+		return NewConflictError("subscription", "has expired")
+	}
+	if s.IsCancelled() {
+		return NewConflictError("subscription", "is cancelled")
+	}
+	if s.IsTrial() {
+		return NewConflictError("subscription", "is a trial")
+	}
+	s.End = s.End.AddDate(0, months, 0)
+	return nil
+}
+
+// Cancel stops renewals. The plan is kept until the end of the period.
+func (s *Subscription) Cancel(at time.Time) error {
+	if !s.IsActive(at) {
+		return NewConflictError("subscription", "is not active")
+	}
+	if s.IsCancelled() {
+		return NewConflictError("subscription", "is already cancelled")
+	}
+	s.AutoRenew = false
+	s.Cancelled = at
+	return nil
+}
+
+// StopRenewing turns auto-renew off, so that the subscription expires at its end
+func (s *Subscription) StopRenewing() {
+	s.AutoRenew = false
+}
+
+// IsDue tells whether the period is over and the subscription has to be renewed or expired
+func (s *Subscription) IsDue(now time.Time) bool {
+	return s.Expired.IsZero() && !now.Before(s.End)
+}
+
+// EndTrial ends an active trial early, when a paid subscription takes over
+func (s *Subscription) EndTrial(at time.Time) error {
+	if !s.IsTrial() {
+		return NewConflictError("subscription", "is not a trial")
+	}
+	if !s.IsActive(at) {
+		return NewConflictError("subscription", "is not active")
+	}
+	s.End = at
+	s.Expired = at
+	return nil
+}
+
+func (s *Subscription) Expire(at time.Time) error {
+	if !s.IsDue(at) {
+		return NewConflictError("subscription", "is not due until "+s.End.UTC().Format(time.RFC3339))
+	}
+	s.Expired = at
+	return nil
+}
+
+func NewSubscriptionResponseModel(subscription Subscription, now time.Time) *SubscriptionResponseModel {
+	return &SubscriptionResponseModel{
+		Id:        subscription.Id.V,
+		UserId:    subscription.UserId.V,
+		Plan:      subscription.Plan.V,
+		Status:    subscription.Status(now).V,
+		Start:     subscription.Start.UTC().Format(time.RFC3339),
+		End:       subscription.End.UTC().Format(time.RFC3339),
+		AutoRenew: subscription.AutoRenew,
+		Trial:     subscription.IsTrial(),
+	}
+}
+
+func NewPlanChangeResponseModel(change PlanChange) *PlanChangeResponseModel {
+	return &PlanChangeResponseModel{
+		UserId:  change.UserId.V,
+		From:    change.From.V,
+		To:      change.To.V,
+		Reason:  change.Reason,
+		Changed: change.Changed.UTC().Format(time.RFC3339),
+	}
+}
+
+func NewPlanChangeListResponseModel(changes []PlanChange) *PlanChangeListResponseModel {
+	responses := []PlanChangeResponseModel{}
+	for _, change := range changes {
+		responses = append(responses, *NewPlanChangeResponseModel(change))
+	}
+	return &PlanChangeListResponseModel{Changes: responses}
+}
+
+func (m *SubscriptionResponseModel) CSVHeader() []string {
+	return []string{"id", "userId", "plan", "status", "start", "end", "autoRenew", "trial"}
+}
+
+func (m *SubscriptionResponseModel) CSVRecords() [][]string {
+	return [][]string{{m.Id, m.UserId, m.Plan, m.Status, m.Start, m.End, strconv.FormatBool(m.AutoRenew), strconv.FormatBool(m.Trial)}}
+}
+
+func (m *SubscriptionResponseModel) Text() string {
+	return m.Id + " " + m.Plan + " " + m.Status + " until " + m.End
+}
+
+func (m *PlanChangeResponseModel) CSVHeader() []string {
+	return []string{"userId", "from", "to", "reason", "changed"}
+}
+
+func (m *PlanChangeResponseModel) CSVRecords() [][]string {
+	return [][]string{{m.UserId, m.From, m.To, m.Reason, m.Changed}}
+}
+
+func (m *PlanChangeResponseModel) Text() string {
+	return m.Changed + " " + m.From + " -> " + m.To + " " + m.Reason
+}
+
+func (m *PlanChangeListResponseModel) CSVHeader() []string {
+	return (&PlanChangeResponseModel{}).CSVHeader()
+}
+
+func (m *PlanChangeListResponseModel) CSVRecords() [][]string {
+	records := [][]string{}
+	for _, change := range m.Changes {
+		records = append(records, change.CSVRecords()...)
+	}
+	return records
+}
+
+func (m *PlanChangeListResponseModel) Text() string {
+	var output string
+	for _, change := range m.Changes {
+		output += change.Text() + "\n"
+	}
+	return output
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscription_Lifecycle(t *testing.T) {
+	start := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+	subscription, err := NewSubscription(SubscriptionId{"1"}, UserId{"1"}, USER_TYPE_PREMIUM, 1, start, false)
+	assert.Nil(t, err)
+	assert.Equal(t, start.AddDate(0, 1, 0), subscription.End)
+	assert.Equal(t, SUBSCRIPTION_STATUS_ACTIVE, subscription.Status(start))
+
+	assert.Nil(t, subscription.Renew(2))
+	assert.Equal(t, start.AddDate(0, 1, 0).AddDate(0, 2, 0), subscription.End)
+
+	// cancelled subscriptions stay active until their end
+	assert.Nil(t, subscription.Cancel(start.Add(time.Hour)))
+	assert.Equal(t, SUBSCRIPTION_STATUS_CANCELLED, subscription.Status(start.Add(time.Hour)))
+	assert.Equal(t, true, errors.Is(subscription.Renew(1), ErrConflict))
+	assert.Equal(t, true, errors.Is(subscription.Cancel(start.Add(time.Hour)), ErrConflict))
+
+	// it can not expire before the end
+	assert.Equal(t, true, errors.Is(subscription.Expire(subscription.End.Add(-time.Second)), ErrConflict))
+	assert.Equal(t, true, subscription.IsDue(subscription.End))
+	assert.Nil(t, subscription.Expire(subscription.End))
+	assert.Equal(t, false, subscription.IsDue(subscription.End))
+	assert.Equal(t, SUBSCRIPTION_STATUS_EXPIRED, subscription.Status(subscription.End))
+}
+
+func TestNewSubscription(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		userId UserId
+		months int
+		want   error
+	}{
+		{name: "normal", userId: UserId{"1"}, months: 12, want: nil},
+		{name: "no user", userId: UserId{}, months: 1, want: ErrValidation},
+		{name: "no months", userId: UserId{"1"}, months: 0, want: ErrValidation},
+		{name: "too long", userId: UserId{"1"}, months: 37, want: ErrValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSubscription(SubscriptionId{"1"}, tt.userId, USER_TYPE_PREMIUM, tt.months, start, true)
+			assert.Equal(t, true, errors.Is(err, tt.want),
+				fmt.Sprintf("NewSubscription() error = %v, want %v", err, tt.want))
+		})
+	}
+}
+
+func TestUser_Upgrade(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := User{Id: UserId{"1"}, Name: UserName{"taro"}, UType: USER_TYPE_NORMAL}
+
+	change, err := user.Upgrade(USER_TYPE_PREMIUM, "subscription 1 started", now)
+	assert.Nil(t, err)
+	assert.Equal(t, &PlanChange{UserId: UserId{"1"}, From: USER_TYPE_NORMAL, To: USER_TYPE_PREMIUM, Reason: "subscription 1 started", Changed: now}, change)
+	assert.Equal(t, true, user.IsPremium())
+	_, err = user.Upgrade(USER_TYPE_PREMIUM, "again", now)
+	assert.Equal(t, true, errors.Is(err, ErrConflict))
+	_, err = user.Upgrade(USER_TYPE_NORMAL, "not premium", now)
+	assert.Equal(t, true, errors.Is(err, ErrValidation))
+
+	change, err = user.DownGrade("subscription 1 expired", now)
+	assert.Nil(t, err)
+	assert.Equal(t, USER_TYPE_NORMAL, change.To)
+	assert.Equal(t, false, user.IsPremium())
+	_, err = user.DownGrade("again", now)
+	assert.Equal(t, true, errors.Is(err, ErrConflict))
+}
@@ -0,0 +1,13 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashToken is used to keep only hashes of tokens handed to users,
+// such as email verification tokens and session tokens
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
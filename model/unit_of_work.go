@@ -0,0 +1,9 @@
+package model
+
+type (
+	// port to run the writes of a use case as one transaction.
+	// Do commits what work saved when it returns nil, and rolls all of it back when it fails.
+	IUnitOfWork interface {
+		Do(work func() error) error
+	}
+)
@@ -1,8 +1,24 @@
 package model
 
+import (
+	"strconv"
+	"time"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
 var (
 	USER_TYPE_PREMIUM = UserType{V: "premium"}
 	USER_TYPE_NORMAL  = UserType{V: "normal"}
+
+	USER_SORT_KEY_ID   = UserSortKey{V: "id"}
+	USER_SORT_KEY_NAME = UserSortKey{V: "name"}
+
+	USER_NAME_COMPARISON_EXACT            = UserNameComparison{V: "exact"}
+	USER_NAME_COMPARISON_CASE_INSENSITIVE = UserNameComparison{V: "case-insensitive"}
+	// NFKC normalisation and case folding, e.g. "Ｔａｒｏ" and "taro" are the same name
+	USER_NAME_COMPARISON_NORMALIZED = UserNameComparison{V: "normalized"}
 )
 
 type (
@@ -19,91 +35,182 @@ type (
 	}
 
 	// Aggregate Root
+	// Version counts the saves of the user. Repositories reject saves of a version which is not the latest.
 	User struct {
-		Id    UserId
-		Name  UserName
-		UType UserType
+		Id                UserId
+		Name              UserName
+		UType             UserType
+		Email             Email
+		EmailVerification EmailVerification
+		Role              UserRole
+		Status            UserStatusRecord
+		Version           int
 	}
 
 	IUserRepository interface {
 		Save(user User) error
 		FindById(id *UserId) (*User, error)
 		FindByName(name *UserName) (*User, error)
+		FindByEmail(email *Email) (*User, error)
 		FindAll() (*[]User, error)
+		FindByQuery(query UserQuery) (*UserPage, error)
 		Exists(user User) bool
 		Delete(user User) error
 	}
 
+	UserSortKey struct {
+		V string
+	}
+
+	// conditions to list users. Limit 0 means no limit and a nil UType or Status matches every user
+	UserQuery struct {
+		Limit      int
+		Offset     int
+		SortKey    UserSortKey
+		Descending bool
+		UType      *UserType
+		Status     *UserStatus
+		NamePrefix string
+	}
+
+	// Total is the number of users matching the query regardless of Limit and Offset
+	UserPage struct {
+		Users []User
+		Total int
+	}
+
 	IUserFactory interface {
 		Create(name *UserName) (*User, error)
 	}
 
 	UserResponseModel struct {
-		Id   string
-		Name string
+		Id            string `json:"id"`
+		Name          string `json:"name"`
+		Type          string `json:"type"`
+		Email         string `json:"email,omitempty"`
+		EmailVerified *bool  `json:"emailVerified,omitempty"`
+	}
+
+	UserListResponseModel struct {
+		Users []UserResponseModel `json:"users"`
+		Total int                 `json:"total"`
+		Next  string              `json:"next,omitempty"`
 	}
 
 	UserPostRequestModel struct {
-		Name string
+		Name     string `json:"name" form:"name"`
+		Password string `json:"password" form:"password"`
 	}
 
-	UserService struct {
-		userRepository IUserRepository
+	UserPutRequestModel struct {
+		Name string `json:"name" form:"name"`
 	}
-)
 
-func NewUserName(v string) (UserName, bool) {
-	if len(v) == 0 {
-		return UserName{}, false
+	UserEmailPutRequestModel struct {
+		Email string `json:"email" form:"email"`
+	}
+
+	UserEmailVerifyRequestModel struct {
+		Token string `json:"token" form:"token"`
 	}
-	if len(v) < 3 {
-		return UserName{}, false
+
+	UserPasswordPutRequestModel struct {
+		CurrentPassword string `json:"currentPassword" form:"current_password"`
+		Password        string `json:"password" form:"password"`
+	}
+
+	// how user names are compared to detect duplicates
+	UserNameComparison struct {
+		V string
+	}
+
+	UserService struct {
+		userRepository IUserRepository
+		nameComparison UserNameComparison
+		namePolicy     UserNamePolicy
 	}
+)
 
-	return UserName{V: v}, true
+// NewUserName accepts names following the default policy. Services follow the policy they are given instead.
+func NewUserName(v string) (UserName, error) {
+	return NewDefaultUserNamePolicy().NewUserName(v)
 }
 
-func NewUserId(v string) (UserId, bool) {
+func NewUserId(v string) (UserId, error) {
 	if len(v) == 0 {
-		return UserId{}, false
+		return UserId{}, NewValidationError("id", "is required")
 	}
 
-	return UserId{V: v}, true
+	return UserId{V: v}, nil
+}
+
+// NewUserType accepts the plans of DefaultPlanRegistry only
+func NewUserType(v string) (UserType, error) {
+	return DefaultPlanRegistry.NewUserType(v)
 }
 
-func NewUserType(v string) (UserType, bool) {
-	return UserType{V: v}, true
+func NewUserSortKey(v string) (UserSortKey, error) {
+	switch v {
+	case USER_SORT_KEY_ID.V, USER_SORT_KEY_NAME.V:
+		return UserSortKey{V: v}, nil
+	}
+	return UserSortKey{}, NewValidationError("sort", "must be id or name")
 }
 
-func NewUser(id UserId, name UserName, uType UserType) (User, bool) {
+func NewUser(id UserId, name UserName, uType UserType) (User, error) {
 	if len(id.V) == 0 {
-		return User{}, false
+		return User{}, NewValidationError("id", "is required")
 	}
 	if len(name.V) == 0 {
-		return User{}, false
+		return User{}, NewValidationError("name", "is required")
 	}
 
-	return User{Id: id, Name: name, UType: uType}, true
+	return User{Id: id, Name: name, UType: uType, Role: USER_ROLE_MEMBER, Status: UserStatusRecord{Status: USER_STATUS_ACTIVE}}, nil
+}
+
+// RequireVersion fails unless the user is at the version the client read. A nil version matches any version.
+func (u *User) RequireVersion(version *int) error {
+	if version != nil && *version != u.Version {
+		return NewPreconditionFailedError("user", u.Id.V, *version, u.Version)
+	}
+	return nil
 }
 
-func (u *User) ChangeName(name *UserName) bool {
+func (u *User) ChangeName(name *UserName) error {
 	if name == nil {
-		return false
+		return NewValidationError("name", "is required")
 	}
 	u.Name = *name
-	return true
+	return nil
 }
 
-func (u *User) Upgrade() {
-	u.UType = USER_TYPE_PREMIUM
+// Upgrade puts the user on a premium plan and returns the change to keep in the history
+func (u *User) Upgrade(plan UserType, reason string, at time.Time) (*PlanChange, error) {
+	if !DefaultPlanRegistry.IsPremium(plan) {
+		return nil, NewValidationError("plan", "must be a premium plan")
+	}
+	if u.IsPremium() {
+		return nil, NewConflictError("user", "is already premium")
+	}
+	return u.changePlan(plan, reason, at), nil
+}
+
+// DownGrade makes the user normal and returns the change to keep in the history
+func (u *User) DownGrade(reason string, at time.Time) (*PlanChange, error) {
+	if !u.IsPremium() {
+		return nil, NewConflictError("user", "is not premium")
+	}
+	return u.changePlan(USER_TYPE_NORMAL, reason, at), nil
 }
 
-func (u *User) DownGrade() {
-	u.UType = USER_TYPE_NORMAL
+func (u *User) changePlan(to UserType, reason string, at time.Time) *PlanChange {
+	change := &PlanChange{UserId: u.Id, From: u.UType, To: to, Reason: reason, Changed: at}
+	u.UType = to
+	return change
 }
 
 func (u *User) IsPremium() bool {
-	return u.UType.V == "premium"
+	return DefaultPlanRegistry.IsPremium(u.UType)
 }
 
 func (u *User) ToString() string {
@@ -111,14 +218,130 @@ func (u *User) ToString() string {
 }
 
 func NewUserResponseModel(user User) *UserResponseModel {
-	return &UserResponseModel{Id: user.Id.V, Name: user.Name.V}
+	verified := user.IsEmailVerified()
+	return &UserResponseModel{
+		Id:            user.Id.V,
+		Name:          user.Name.V,
+		Type:          user.UType.V,
+		Email:         user.Email.V,
+		EmailVerified: &verified,
+	}
+}
+
+// HideEmail leaves out the email and whether it is verified, for viewers who can not see them
+func (m *UserResponseModel) HideEmail() {
+	m.Email = ""
+	m.EmailVerified = nil
+}
+
+func NewUserListResponseModel(users []User) *UserListResponseModel {
+	responses := []UserResponseModel{}
+	for _, user := range users {
+		responses = append(responses, *NewUserResponseModel(user))
+	}
+	return &UserListResponseModel{Users: responses, Total: len(users)}
+}
+
+func (m *UserResponseModel) CSVHeader() []string {
+	return []string{"id", "name", "type", "email", "emailVerified"}
+}
+
+func (m *UserResponseModel) CSVRecords() [][]string {
+	verified := ""
+	if m.EmailVerified != nil {
+		verified = strconv.FormatBool(*m.EmailVerified)
+	}
+	return [][]string{{m.Id, m.Name, m.Type, m.Email, verified}}
+}
+
+func (m *UserResponseModel) Text() string {
+	return m.Id + " " + m.Name
+}
+
+func (m *UserListResponseModel) CSVHeader() []string {
+	return (&UserResponseModel{}).CSVHeader()
 }
 
-func NewUserService(userRepository IUserRepository) UserService {
-	return UserService{userRepository: userRepository}
+func (m *UserListResponseModel) CSVRecords() [][]string {
+	records := [][]string{}
+	for _, user := range m.Users {
+		records = append(records, user.CSVRecords()...)
+	}
+	return records
 }
 
-func (us *UserService) Exists(user *User) bool {
-	duplicatedUser, _ := us.userRepository.FindByName(&user.Name)
-	return duplicatedUser != nil
+func (m *UserListResponseModel) Text() string {
+	var output string
+	for _, user := range m.Users {
+		output += user.Id + " " + user.Name + " " + user.Type + "\n"
+	}
+	return output
+}
+
+func NewUserNameComparison(v string) (UserNameComparison, error) {
+	switch v {
+	case USER_NAME_COMPARISON_EXACT.V, USER_NAME_COMPARISON_CASE_INSENSITIVE.V, USER_NAME_COMPARISON_NORMALIZED.V:
+		return UserNameComparison{V: v}, nil
+	}
+	return UserNameComparison{}, NewValidationError("nameComparison", "must be exact, case-insensitive or normalized")
+}
+
+// Key returns the form of the name which is equal for every duplicated name
+func (c UserNameComparison) Key(name UserName) string {
+	switch c {
+	case USER_NAME_COMPARISON_CASE_INSENSITIVE:
+		return cases.Fold().String(name.V)
+	case USER_NAME_COMPARISON_NORMALIZED:
+		return norm.NFKC.String(cases.Fold().String(norm.NFKC.String(name.V)))
+	}
+	return name.V
+}
+
+func NewUserService(userRepository IUserRepository, nameComparison UserNameComparison, namePolicy UserNamePolicy) UserService {
+	return UserService{userRepository: userRepository, nameComparison: nameComparison, namePolicy: namePolicy}
+}
+
+// NewUserName accepts names following the policy of the service
+func (us *UserService) NewUserName(v string) (UserName, error) {
+	return us.namePolicy.NewUserName(v)
+}
+
+// EmailExists reports whether another user already has the email address of user
+func (us *UserService) EmailExists(user *User) (bool, error) {
+	if len(user.Email.V) == 0 {
+		return false, nil
+	}
+	duplicatedUser, err := us.userRepository.FindByEmail(&user.Email)
+	if err != nil {
+		return false, err
+	}
+	return duplicatedUser != nil && duplicatedUser.Id.V != user.Id.V, nil
+}
+
+// Exists reports whether another user already has the name of user.
+// Users which could not be read are reported as the error, not as a unique name.
+func (us *UserService) Exists(user *User) (bool, error) {
+	// fast path for names which are exactly the same
+	duplicatedUser, err := us.userRepository.FindByName(&user.Name)
+	if err != nil {
+		return false, err
+	}
+	if duplicatedUser != nil && duplicatedUser.Id.V != user.Id.V {
+		return true, nil
+	}
+	if us.nameComparison == USER_NAME_COMPARISON_EXACT {
+		return false, nil
+	}
+
+	users, err := us.userRepository.FindAll()
+	if err != nil {
+		return false, err
+	}
+	key := us.nameComparison.Key(user.Name)
+	for _, u := range *users {
+		if u.Id.V != user.Id.V && us.nameComparison.Key(u.Name) == key {
+			return true, nil
+		}
+	}
+	return false, nil
 }
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+type (
+	// rules to accept a user name. Lengths are counted in characters (runes), not bytes
+	UserNamePolicy struct {
+		MinLength int
+		MaxLength int
+		// characters in AllowedRanges or AllowedSymbols are accepted
+		AllowedRanges  []*unicode.RangeTable
+		AllowedSymbols string
+		TrimSpace      bool
+		NormalizeNFC   bool
+		// compared ignoring case and width
+		Reserved []string
+	}
+)
+
+// NewDefaultUserNamePolicy returns the policy used by NewUserName.
+// Every call returns a new policy, so that changing one configures only the services it is given to.
+func NewDefaultUserNamePolicy() UserNamePolicy {
+	return UserNamePolicy{
+		MinLength:      3,
+		MaxLength:      20,
+		AllowedRanges:  []*unicode.RangeTable{unicode.Letter, unicode.Digit, unicode.Mark},
+		AllowedSymbols: "_-. ",
+		TrimSpace:      true,
+		NormalizeNFC:   true,
+		Reserved:       []string{"admin", "administrator", "root", "system", "support"},
+	}
+}
+
+func (p UserNamePolicy) NewUserName(v string) (UserName, error) {
+	if p.NormalizeNFC {
+		v = norm.NFC.String(v)
+	}
+	if len(v) == 0 {
+		return UserName{}, NewValidationError("name", "is required")
+	}
+	if strings.TrimSpace(v) == "" {
+		return UserName{}, NewValidationError("name", "must not be blank")
+	}
+	if p.TrimSpace {
+		v = strings.TrimSpace(v)
+	}
+
+	for _, r := range v {
+		if !p.allows(r) {
+			return UserName{}, NewValidationError("name", "must not contain "+strconv.QuoteRune(r))
+		}
+	}
+
+	length := utf8.RuneCountInString(v)
+	if length < p.MinLength {
+		return UserName{}, NewValidationError("name", "must be at least "+strconv.Itoa(p.MinLength)+" characters")
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return UserName{}, NewValidationError("name", "must be at most "+strconv.Itoa(p.MaxLength)+" characters")
+	}
+
+	for _, reserved := range p.Reserved {
+		if USER_NAME_COMPARISON_NORMALIZED.Key(UserName{V: reserved}) == USER_NAME_COMPARISON_NORMALIZED.Key(UserName{V: v}) {
+			return UserName{}, NewValidationError("name", "is reserved")
+		}
+	}
+
+	return UserName{V: v}, nil
+}
+
+func (p UserNamePolicy) allows(r rune) bool {
+	if unicode.IsControl(r) {
+		return false
+	}
+	if strings.ContainsRune(p.AllowedSymbols, r) {
+		return true
+	}
+	return unicode.IsOneOf(p.AllowedRanges, r)
+}
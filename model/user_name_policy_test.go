@@ -0,0 +1,112 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserNamePolicy_NewUserName(t *testing.T) {
+	type wants struct {
+		userName UserName
+		err      *ValidationError
+	}
+	tests := []struct {
+		name   string
+		policy UserNamePolicy
+		v      string
+		wants  wants
+	}{
+		{
+			name:   "normal",
+			policy: NewDefaultUserNamePolicy(),
+			v:      "user_name-1",
+			wants:  wants{userName: UserName{V: "user_name-1"}},
+		},
+		{
+			name:   "japanese name is counted in characters",
+			policy: NewDefaultUserNamePolicy(),
+			v:      "山田太郎",
+			wants:  wants{userName: UserName{V: "山田太郎"}},
+		},
+		{
+			name:   "too short in characters",
+			policy: NewDefaultUserNamePolicy(),
+			v:      "山田",
+			wants:  wants{err: &ValidationError{Field: "name", Reason: "must be at least 3 characters"}},
+		},
+		{
+			name:   "too long",
+			policy: NewDefaultUserNamePolicy(),
+			v:      strings.Repeat("あ", 21),
+			wants:  wants{err: &ValidationError{Field: "name", Reason: "must be at most 20 characters"}},
+		},
+		{
+			name:   "surrounding spaces are trimmed",
+			policy: NewDefaultUserNamePolicy(),
+			v:      "  taro  ",
+			wants:  wants{userName: UserName{V: "taro"}},
+		},
+		{
+			name:   "whitespace only",
+			policy: NewDefaultUserNamePolicy(),
+			v:      " \t ",
+			wants:  wants{err: &ValidationError{Field: "name", Reason: "must not be blank"}},
+		},
+		{
+			name:   "control character",
+			policy: NewDefaultUserNamePolicy(),
+			v:      "taro\a",
+			wants:  wants{err: &ValidationError{Field: "name", Reason: `must not contain '\a'`}},
+		},
+		{
+			name:   "disallowed symbol",
+			policy: NewDefaultUserNamePolicy(),
+			v:      "taro!",
+			wants:  wants{err: &ValidationError{Field: "name", Reason: `must not contain '!'`}},
+		},
+		{
+			name:   "normalized to NFC",
+			policy: NewDefaultUserNamePolicy(),
+			v:      "\u30AB\u3099ンダム",
+			wants:  wants{userName: UserName{V: "\u30ACンダム"}},
+		},
+		{
+			name:   "reserved",
+			policy: NewDefaultUserNamePolicy(),
+			v:      "Ａｄｍｉｎ",
+			wants:  wants{err: &ValidationError{Field: "name", Reason: "is reserved"}},
+		},
+		{
+			name:   "custom policy",
+			policy: UserNamePolicy{MinLength: 1, MaxLength: 4, AllowedRanges: []*unicode.RangeTable{unicode.Digit}},
+			v:      "1234",
+			wants:  wants{userName: UserName{V: "1234"}},
+		},
+		{
+			name:   "custom policy - letters are not allowed",
+			policy: UserNamePolicy{MinLength: 1, MaxLength: 4, AllowedRanges: []*unicode.RangeTable{unicode.Digit}},
+			v:      "12a",
+			wants:  wants{err: &ValidationError{Field: "name", Reason: `must not contain 'a'`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userName, err := tt.policy.NewUserName(tt.v)
+			assert.Equal(t, tt.wants.userName, userName,
+				fmt.Sprintf("UserNamePolicy.NewUserName() got = %v, want %v", userName, tt.wants.userName))
+
+			if tt.wants.err == nil {
+				assert.Nil(t, err)
+				return
+			}
+			var validationError *ValidationError
+			assert.Equal(t, true, errors.As(err, &validationError))
+			assert.Equal(t, tt.wants.err, validationError)
+		})
+	}
+}
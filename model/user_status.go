@@ -0,0 +1,185 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+var (
+	USER_ROLE_MEMBER = UserRole{V: "member"}
+	USER_ROLE_ADMIN  = UserRole{V: "admin"}
+
+	USER_STATUS_ACTIVE      = UserStatus{V: "active"}
+	USER_STATUS_SUSPENDED   = UserStatus{V: "suspended"}
+	USER_STATUS_DEACTIVATED = UserStatus{V: "deactivated"}
+)
+
+type (
+	UserRole struct {
+		V string
+	}
+
+	UserStatus struct {
+		V string
+	}
+
+	// Reason and Changed describe the latest change of the status
+	UserStatusRecord struct {
+		Status  UserStatus
+		Reason  string
+		Changed time.Time
+	}
+
+	UserStatusRequestModel struct {
+		Reason string `json:"reason" form:"reason"`
+	}
+
+	UserStatusResponseModel struct {
+		Id      string `json:"id"`
+		Name    string `json:"name"`
+		Status  string `json:"status"`
+		Reason  string `json:"reason,omitempty"`
+		Changed string `json:"changed,omitempty"`
+	}
+
+	UserStatusListResponseModel struct {
+		Users []UserStatusResponseModel `json:"users"`
+		Total int                       `json:"total"`
+		Next  string                    `json:"next,omitempty"`
+	}
+)
+
+func NewUserStatus(v string) (UserStatus, error) {
+	switch v {
+	case USER_STATUS_ACTIVE.V, USER_STATUS_SUSPENDED.V, USER_STATUS_DEACTIVATED.V:
+		return UserStatus{V: v}, nil
+	}
+	return UserStatus{}, NewValidationError("status", "must be active, suspended or deactivated")
+}
+
+func newStatusReason(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	if len(v) == 0 {
+		return "", NewValidationError("reason", "is required")
+	}
+	if len(v) > 500 {
+		return "", NewValidationError("reason", "must be at most 500 characters")
+	}
+	return v, nil
+}
+
+func (u *User) IsAdmin() bool {
+	return u.Role == USER_ROLE_ADMIN
+}
+
+func (u *User) GrantAdmin() {
+	u.Role = USER_ROLE_ADMIN
+}
+
+func (u *User) RevokeAdmin() {
+	u.Role = USER_ROLE_MEMBER
+}
+
+func (u *User) IsActive() bool {
+	return u.Status.Status == USER_STATUS_ACTIVE
+}
+
+func (u *User) IsSuspended() bool {
+	return u.Status.Status == USER_STATUS_SUSPENDED
+}
+
+func (u *User) IsDeactivated() bool {
+	return u.Status.Status == USER_STATUS_DEACTIVATED
+}
+
+// only active users can be suspended
+func (u *User) Suspend(reason string, at time.Time) error {
+	reason, err := newStatusReason(reason)
+	if err != nil {
+		return err
+	}
+	if !u.IsActive() {
+		return NewConflictError("user", "is "+u.Status.Status.V)
+	}
+	u.Status = UserStatusRecord{Status: USER_STATUS_SUSPENDED, Reason: reason, Changed: at}
+	return nil
+}
+
+// Reinstate makes a suspended user active again
+func (u *User) Reinstate(reason string, at time.Time) error {
+	reason, err := newStatusReason(reason)
+	if err != nil {
+		return err
+	}
+	if !u.IsSuspended() {
+		return NewConflictError("user", "is not suspended")
+	}
+	u.Status = UserStatusRecord{Status: USER_STATUS_ACTIVE, Reason: reason, Changed: at}
+	return nil
+}
+
+// deactivated users can not be reinstated
+func (u *User) Deactivate(reason string, at time.Time) error {
+	reason, err := newStatusReason(reason)
+	if err != nil {
+		return err
+	}
+	if u.IsDeactivated() {
+		return NewConflictError("user", "is already deactivated")
+	}
+	u.Status = UserStatusRecord{Status: USER_STATUS_DEACTIVATED, Reason: reason, Changed: at}
+	return nil
+}
+
+func NewUserStatusResponseModel(user User) *UserStatusResponseModel {
+	response := &UserStatusResponseModel{
+		Id:     user.Id.V,
+		Name:   user.Name.V,
+		Status: user.Status.Status.V,
+		Reason: user.Status.Reason,
+	}
+	if !user.Status.Changed.IsZero() {
+		response.Changed = user.Status.Changed.UTC().Format(time.RFC3339)
+	}
+	return response
+}
+
+func NewUserStatusListResponseModel(users []User) *UserStatusListResponseModel {
+	responses := []UserStatusResponseModel{}
+	for _, user := range users {
+		responses = append(responses, *NewUserStatusResponseModel(user))
+	}
+	return &UserStatusListResponseModel{Users: responses, Total: len(responses)}
+}
+
+func (m *UserStatusResponseModel) CSVHeader() []string {
+	return []string{"id", "name", "status", "reason", "changed"}
+}
+
+func (m *UserStatusResponseModel) CSVRecords() [][]string {
+	return [][]string{{m.Id, m.Name, m.Status, m.Reason, m.Changed}}
+}
+
+func (m *UserStatusResponseModel) Text() string {
+	return m.Id + " " + m.Name + " " + m.Status
+}
+
+func (m *UserStatusListResponseModel) CSVHeader() []string {
+	return (&UserStatusResponseModel{}).CSVHeader()
+}
+
+func (m *UserStatusListResponseModel) CSVRecords() [][]string {
+	records := [][]string{}
+	for _, user := range m.Users {
+		records = append(records, user.CSVRecords()...)
+	}
+	return records
+}
+
+func (m *UserStatusListResponseModel) Text() string {
+	var output string
+	for _, user := range m.Users {
+		output += user.Text() + "\n"
+	}
+	return output
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUser_StatusLifecycle(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		from       UserStatus
+		change     func(u *User) error
+		want       error
+		wantStatus UserStatus
+	}{
+		{name: "suspend an active user", from: USER_STATUS_ACTIVE, change: func(u *User) error { return u.Suspend("spam", now) }, want: nil, wantStatus: USER_STATUS_SUSPENDED},
+		{name: "suspend without a reason", from: USER_STATUS_ACTIVE, change: func(u *User) error { return u.Suspend(" ", now) }, want: ErrValidation, wantStatus: USER_STATUS_ACTIVE},
+		{name: "suspend a suspended user", from: USER_STATUS_SUSPENDED, change: func(u *User) error { return u.Suspend("spam", now) }, want: ErrConflict, wantStatus: USER_STATUS_SUSPENDED},
+		{name: "reinstate a suspended user", from: USER_STATUS_SUSPENDED, change: func(u *User) error { return u.Reinstate("appeal", now) }, want: nil, wantStatus: USER_STATUS_ACTIVE},
+		{name: "reinstate an active user", from: USER_STATUS_ACTIVE, change: func(u *User) error { return u.Reinstate("appeal", now) }, want: ErrConflict, wantStatus: USER_STATUS_ACTIVE},
+		{name: "reinstate a deactivated user", from: USER_STATUS_DEACTIVATED, change: func(u *User) error { return u.Reinstate("appeal", now) }, want: ErrConflict, wantStatus: USER_STATUS_DEACTIVATED},
+		{name: "deactivate a suspended user", from: USER_STATUS_SUSPENDED, change: func(u *User) error { return u.Deactivate("requested", now) }, want: nil, wantStatus: USER_STATUS_DEACTIVATED},
+		{name: "deactivate twice", from: USER_STATUS_DEACTIVATED, change: func(u *User) error { return u.Deactivate("requested", now) }, want: ErrConflict, wantStatus: USER_STATUS_DEACTIVATED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := User{Id: UserId{"1"}, Name: UserName{"taro"}, Status: UserStatusRecord{Status: tt.from}}
+			err := tt.change(&user)
+			assert.Equal(t, true, errors.Is(err, tt.want),
+				fmt.Sprintf("error = %v, want %v", err, tt.want))
+			assert.Equal(t, tt.wantStatus, user.Status.Status)
+			if tt.want == nil {
+				assert.Equal(t, now, user.Status.Changed)
+			}
+		})
+	}
+}
+
+func TestAuthorizationPolicy_CanAdministerUser(t *testing.T) {
+	user := User{Id: UserId{"2"}}
+	admin := Actor{UserId: UserId{"1"}, Role: USER_ROLE_ADMIN}
+	tests := []struct {
+		name  string
+		actor Actor
+		user  User
+		want  error
+	}{
+		{name: "admin", actor: admin, user: user, want: nil},
+		{name: "admin on themselves", actor: admin, user: User{Id: UserId{"1"}}, want: ErrPermission},
+		{name: "member", actor: actorOf("3"), user: user, want: ErrPermission},
+		{name: "anonymous", actor: ANONYMOUS_ACTOR, user: user, want: ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewAuthorizationPolicy().CanAdministerUser(tt.actor, ACTION_USER_SUSPEND, &tt.user).Err()
+			assert.Equal(t, true, errors.Is(err, tt.want),
+				fmt.Sprintf("CanAdministerUser() error = %v, want %v", err, tt.want))
+		})
+	}
+}
+
+func TestAuthorizationPolicy_CanJoinCircle(t *testing.T) {
+	tests := []struct {
+		name   string
+		status UserStatus
+		want   bool
+	}{
+		{name: "active", status: USER_STATUS_ACTIVE, want: true},
+		{name: "suspended", status: USER_STATUS_SUSPENDED, want: false},
+		{name: "deactivated", status: USER_STATUS_DEACTIVATED, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			circle := newTestCircle()
+			member := User{Id: UserId{"9"}, Status: UserStatusRecord{Status: tt.status}}
+			got := NewAuthorizationPolicy().CanJoinCircle(actorOf("9"), &circle, &member)
+			assert.Equal(t, tt.want, got.Allowed,
+				fmt.Sprintf("CanJoinCircle() got = %v, want %v", got, tt.want))
+		})
+	}
+}
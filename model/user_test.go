@@ -1,8 +1,10 @@
 package model
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,7 +16,7 @@ func TestNewUserName(t *testing.T) {
 	}
 	type expected struct {
 		userName UserName
-		ok       bool
+		err      error
 	}
 	tests := []struct {
 		name     string
@@ -26,7 +28,7 @@ func TestNewUserName(t *testing.T) {
 			args: args{v: "username"},
 			expected: expected{
 				userName: UserName{V: "username"},
-				ok:       true,
+				err:      nil,
 			},
 		},
 		{
@@ -34,7 +36,7 @@ func TestNewUserName(t *testing.T) {
 			args: args{v: ""},
 			expected: expected{
 				userName: UserName{V: ""},
-				ok:       false,
+				err:      ErrValidation,
 			},
 		},
 		{
@@ -42,7 +44,7 @@ func TestNewUserName(t *testing.T) {
 			args: args{v: "aiu"},
 			expected: expected{
 				userName: UserName{V: "aiu"},
-				ok:       true,
+				err:      nil,
 			},
 		},
 		{
@@ -50,18 +52,18 @@ func TestNewUserName(t *testing.T) {
 			args: args{v: "ai"},
 			expected: expected{
 				userName: UserName{V: ""},
-				ok:       false,
+				err:      ErrValidation,
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, ok := NewUserName(tt.args.v)
+			got, err := NewUserName(tt.args.v)
 			assert.Equal(t, true, reflect.DeepEqual(got, tt.expected.userName),
 				fmt.Sprintf("NewUserName() got = %v, want %v", got, tt.expected.userName))
 
-			assert.Equal(t, tt.expected.ok, ok,
-				fmt.Sprintf("NewUserName() got1 = %v, want %v", ok, tt.expected.ok))
+			assert.Equal(t, true, errors.Is(err, tt.expected.err),
+				fmt.Sprintf("NewUserName() error = %v, want %v", err, tt.expected.err))
 		})
 	}
 }
@@ -72,7 +74,7 @@ func TestNewUserId(t *testing.T) {
 	}
 	type wants struct {
 		userId UserId
-		ok     bool
+		err    error
 	}
 	tests := []struct {
 		name  string
@@ -82,22 +84,22 @@ func TestNewUserId(t *testing.T) {
 		{
 			name:  "normal",
 			args:  args{v: "1"},
-			wants: wants{userId: UserId{V: "1"}, ok: true},
+			wants: wants{userId: UserId{V: "1"}, err: nil},
 		},
 		{
 			name:  "empty",
 			args:  args{v: ""},
-			wants: wants{userId: UserId{V: ""}, ok: false},
+			wants: wants{userId: UserId{V: ""}, err: ErrValidation},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			userId, ok := NewUserId(tt.args.v)
+			userId, err := NewUserId(tt.args.v)
 			assert.Equal(t, true, reflect.DeepEqual(userId, tt.wants.userId),
 				fmt.Sprintf("NewUserId() got = %v, want %v", userId, tt.wants.userId))
 
-			assert.Equal(t, tt.wants.ok, ok,
-				fmt.Sprintf("NewUserId() got1 = %v, want %v", ok, tt.wants.ok))
+			assert.Equal(t, true, errors.Is(err, tt.wants.err),
+				fmt.Sprintf("NewUserId() error = %v, want %v", err, tt.wants.err))
 		})
 	}
 }
@@ -108,7 +110,7 @@ func TestNewUserType(t *testing.T) {
 	}
 	type wants struct {
 		userType UserType
-		ok       bool
+		err      error
 	}
 	tests := []struct {
 		name  string
@@ -117,17 +119,29 @@ func TestNewUserType(t *testing.T) {
 	}{
 		{
 			name:  "normal",
-			args:  args{v: "test_type"},
-			wants: wants{userType: UserType{V: "test_type"}, ok: true}},
+			args:  args{v: "normal"},
+			wants: wants{userType: USER_TYPE_NORMAL, err: nil}},
+		{
+			name:  "premium",
+			args:  args{v: "premium"},
+			wants: wants{userType: USER_TYPE_PREMIUM, err: nil}},
+		{
+			name:  "unknown plan",
+			args:  args{v: "premum"},
+			wants: wants{userType: UserType{}, err: ErrValidation}},
+		{
+			name:  "empty",
+			args:  args{v: ""},
+			wants: wants{userType: UserType{}, err: ErrValidation}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			userType, ok := NewUserType(tt.args.v)
+			userType, err := NewUserType(tt.args.v)
 			assert.Equal(t, true, reflect.DeepEqual(userType, tt.wants.userType),
 				fmt.Sprintf("NewUserType() got = %v, want %v", userType, tt.wants.userType))
 
-			assert.Equal(t, tt.wants.ok, ok,
-				fmt.Sprintf("NewUserType() got1 = %v, want %v", ok, tt.wants.ok))
+			assert.Equal(t, true, errors.Is(err, tt.wants.err),
+				fmt.Sprintf("NewUserType() error = %v, want %v", err, tt.wants.err))
 		})
 	}
 }
@@ -140,7 +154,7 @@ func TestNewUser(t *testing.T) {
 	}
 	type wants struct {
 		user User
-		ok   bool
+		err  error
 	}
 	tests := []struct {
 		name  string
@@ -155,9 +169,11 @@ func TestNewUser(t *testing.T) {
 				uType: UserType{V: "test_type"}},
 			wants: wants{
 				user: User{Id: UserId{V: "1"},
-					Name:  UserName{V: "test_name"},
-					UType: UserType{V: "test_type"}},
-				ok: true},
+					Name:   UserName{V: "test_name"},
+					UType:  UserType{V: "test_type"},
+					Role:   USER_ROLE_MEMBER,
+					Status: UserStatusRecord{Status: USER_STATUS_ACTIVE}},
+				err: nil},
 		},
 		{
 			name: "empty UserId",
@@ -169,7 +185,7 @@ func TestNewUser(t *testing.T) {
 				user: User{Id: UserId{V: ""},
 					Name:  UserName{V: ""},
 					UType: UserType{V: ""}},
-				ok: false},
+				err: ErrValidation},
 		},
 		{
 			name: "empty UserName",
@@ -181,17 +197,17 @@ func TestNewUser(t *testing.T) {
 				user: User{Id: UserId{V: ""},
 					Name:  UserName{V: ""},
 					UType: UserType{V: ""}},
-				ok: false},
+				err: ErrValidation},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			user, ok := NewUser(tt.args.id, tt.args.name, tt.args.uType)
+			user, err := NewUser(tt.args.id, tt.args.name, tt.args.uType)
 			assert.Equal(t, true, reflect.DeepEqual(user, tt.wants.user),
 				fmt.Sprintf("NewUser() got = %v, want %v", user, tt.wants.user))
 
-			assert.Equal(t, tt.wants.ok, ok,
-				fmt.Sprintf("NewUser() got1 = %v, want %v", ok, tt.wants.ok))
+			assert.Equal(t, true, errors.Is(err, tt.wants.err),
+				fmt.Sprintf("NewUser() error = %v, want %v", err, tt.wants.err))
 		})
 	}
 }
@@ -206,7 +222,7 @@ func TestUser_ChangeName(t *testing.T) {
 		name *UserName
 	}
 	type wants struct {
-		ok          bool
+		err         error
 		updatedName UserName
 	}
 	tests := []struct {
@@ -226,7 +242,7 @@ func TestUser_ChangeName(t *testing.T) {
 				name: &UserName{"updated_name"},
 			},
 			wants: wants{
-				ok:          true,
+				err:         nil,
 				updatedName: UserName{"updated_name"},
 			},
 		},
@@ -241,7 +257,7 @@ func TestUser_ChangeName(t *testing.T) {
 				name: nil,
 			},
 			wants: wants{
-				ok:          false,
+				err:         ErrValidation,
 				updatedName: UserName{"test_name"},
 			},
 		},
@@ -253,13 +269,123 @@ func TestUser_ChangeName(t *testing.T) {
 				Name:  tt.fields.Name,
 				UType: tt.fields.UType,
 			}
-			ok := u.ChangeName(tt.args.name)
+			err := u.ChangeName(tt.args.name)
 
-			assert.Equal(t, tt.wants.ok, ok,
-				fmt.Sprintf("User.ChangeName() = %v, want %v", ok, tt.wants.ok))
+			assert.Equal(t, true, errors.Is(err, tt.wants.err),
+				fmt.Sprintf("User.ChangeName() error = %v, want %v", err, tt.wants.err))
 
 			assert.Equal(t, true, reflect.DeepEqual(u.Name, tt.wants.updatedName),
 				fmt.Sprintf("NewUser() got = %v, want %v", u.Name, tt.wants.updatedName))
 		})
 	}
 }
+
+type stubUserRepository struct {
+	users []User
+	// err fails the reads of the repository
+	err error
+}
+
+func (r *stubUserRepository) Save(user User) error                     { return nil }
+func (r *stubUserRepository) FindByQuery(UserQuery) (*UserPage, error) { return nil, nil }
+func (r *stubUserRepository) Exists(user User) bool                    { return false }
+func (r *stubUserRepository) Delete(user User) error                   { return nil }
+func (r *stubUserRepository) FindByEmail(*Email) (*User, error)        { return nil, r.err }
+func (r *stubUserRepository) FindAll() (*[]User, error)                { return &r.users, r.err }
+func (r *stubUserRepository) FindById(id *UserId) (*User, error) {
+	for _, user := range r.users {
+		if user.Id.V == id.V {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+func (r *stubUserRepository) FindByName(name *UserName) (*User, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	for _, user := range r.users {
+		if user.Name.V == name.V {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestUserNameComparison_Key(t *testing.T) {
+	tests := []struct {
+		name       string
+		comparison UserNameComparison
+		a          string
+		b          string
+		want       bool
+	}{
+		{name: "exact - same", comparison: USER_NAME_COMPARISON_EXACT, a: "taro", b: "taro", want: true},
+		{name: "exact - case", comparison: USER_NAME_COMPARISON_EXACT, a: "Taro", b: "taro", want: false},
+		{name: "case-insensitive - case", comparison: USER_NAME_COMPARISON_CASE_INSENSITIVE, a: "Taro", b: "tARO", want: true},
+		{name: "case-insensitive - full width", comparison: USER_NAME_COMPARISON_CASE_INSENSITIVE, a: "Ｔａｒｏ", b: "taro", want: false},
+		{name: "normalized - full width", comparison: USER_NAME_COMPARISON_NORMALIZED, a: "Ｔａｒｏ", b: "taro", want: true},
+		{name: "normalized - combining mark", comparison: USER_NAME_COMPARISON_NORMALIZED, a: "\u30AC", b: "\u30AB\u3099", want: true},
+		{name: "normalized - different", comparison: USER_NAME_COMPARISON_NORMALIZED, a: "taro", b: "jiro", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.comparison.Key(UserName{tt.a}) == tt.comparison.Key(UserName{tt.b})
+			assert.Equal(t, tt.want, got,
+				fmt.Sprintf("Key(%q) == Key(%q) = %v, want %v", tt.a, tt.b, got, tt.want))
+		})
+	}
+}
+
+func TestUserService_Exists(t *testing.T) {
+	repo := &stubUserRepository{users: []User{
+		{Id: UserId{"1"}, Name: UserName{"Taro"}, UType: USER_TYPE_NORMAL},
+	}}
+	tests := []struct {
+		name       string
+		comparison UserNameComparison
+		user       User
+		want       bool
+	}{
+		{name: "exact duplicate", comparison: USER_NAME_COMPARISON_EXACT, user: User{Id: UserId{"2"}, Name: UserName{"Taro"}}, want: true},
+		{name: "exact - other case", comparison: USER_NAME_COMPARISON_EXACT, user: User{Id: UserId{"2"}, Name: UserName{"taro"}}, want: false},
+		{name: "case-insensitive duplicate", comparison: USER_NAME_COMPARISON_CASE_INSENSITIVE, user: User{Id: UserId{"2"}, Name: UserName{"taro"}}, want: true},
+		{name: "normalized duplicate", comparison: USER_NAME_COMPARISON_NORMALIZED, user: User{Id: UserId{"2"}, Name: UserName{"ｔａｒｏ"}}, want: true},
+		{name: "the user itself", comparison: USER_NAME_COMPARISON_NORMALIZED, user: User{Id: UserId{"1"}, Name: UserName{"TARO"}}, want: false},
+		{name: "unique", comparison: USER_NAME_COMPARISON_NORMALIZED, user: User{Id: UserId{"2"}, Name: UserName{"jiro"}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			us := NewUserService(repo, tt.comparison, NewDefaultUserNamePolicy())
+			got, err := us.Exists(&tt.user)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got,
+				fmt.Sprintf("UserService.Exists() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func TestUserService_ExistsFailure(t *testing.T) {
+	failure := errors.New("repository is down")
+	repo := &stubUserRepository{err: failure}
+	for _, comparison := range []UserNameComparison{USER_NAME_COMPARISON_EXACT, USER_NAME_COMPARISON_NORMALIZED} {
+		us := NewUserService(repo, comparison, NewDefaultUserNamePolicy())
+		user := User{Id: UserId{"2"}, Name: UserName{"taro"}, Email: Email{"taro@example.com"}}
+		_, err := us.Exists(&user)
+		assert.Equal(t, failure, err, fmt.Sprintf("Exists() with %s comparison", comparison.V))
+		_, err = us.EmailExists(&user)
+		assert.Equal(t, failure, err, fmt.Sprintf("EmailExists() with %s comparison", comparison.V))
+	}
+}
+
+func TestUserService_NewUserName(t *testing.T) {
+	policy := NewDefaultUserNamePolicy()
+	policy.MaxLength = 30
+	us := NewUserService(&stubUserRepository{}, USER_NAME_COMPARISON_NORMALIZED, policy)
+	long := strings.Repeat("a", 25)
+
+	_, err := us.NewUserName(long)
+	assert.Nil(t, err, "the policy of the service was not followed")
+	_, err = NewUserName(long)
+	assert.ErrorIs(t, err, ErrValidation, "the policy of the service changed the default policy")
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+type (
+	// RFC 7807 problem details with a machine-readable code
+	problemDetails struct {
+		Type   string         `json:"type"`
+		Title  string         `json:"title"`
+		Status int            `json:"status"`
+		Detail string         `json:"detail"`
+		Code   string         `json:"code"`
+		Errors []fieldProblem `json:"errors,omitempty"`
+	}
+
+	fieldProblem struct {
+		Field  string `json:"field"`
+		Reason string `json:"reason"`
+	}
+)
+
+// newProblemDetails maps err to a status code driven by the domain error types
+func newProblemDetails(err error) problemDetails {
+	var httpError *echo.HTTPError
+	switch {
+	case errors.Is(err, model.ErrValidation):
+		return problemDetails{
+			Status: http.StatusUnprocessableEntity,
+			Code:   "validation_failed",
+			Detail: err.Error(),
+			Errors: fieldProblemsOf(err),
+		}
+	case errors.Is(err, model.ErrNotFound):
+		return problemDetails{Status: http.StatusNotFound, Code: "not_found", Detail: err.Error()}
+	case errors.Is(err, model.ErrStale):
+		return problemDetails{Status: http.StatusConflict, Code: "stale", Detail: err.Error()}
+	case errors.Is(err, model.ErrConflict):
+		return problemDetails{Status: http.StatusConflict, Code: "conflict", Detail: err.Error()}
+	case errors.Is(err, model.ErrPreconditionFailed):
+		return problemDetails{Status: http.StatusPreconditionFailed, Code: "precondition_failed", Detail: err.Error()}
+	case errors.Is(err, model.ErrCapacity):
+		return problemDetails{Status: http.StatusConflict, Code: "capacity_exceeded", Detail: err.Error()}
+	case errors.Is(err, model.ErrUnauthenticated):
+		return problemDetails{Status: http.StatusUnauthorized, Code: "unauthenticated", Detail: err.Error()}
+	case errors.Is(err, model.ErrLocked):
+		return problemDetails{Status: http.StatusLocked, Code: "locked", Detail: err.Error()}
+	case errors.Is(err, model.ErrPaymentDeclined):
+		return problemDetails{Status: http.StatusPaymentRequired, Code: "payment_declined", Detail: err.Error()}
+	case errors.Is(err, model.ErrPermission):
+		return problemDetails{Status: http.StatusForbidden, Code: "permission_denied", Detail: err.Error()}
+	case errors.As(err, &httpError):
+		problem := problemDetails{Status: httpError.Code, Code: "http_error", Detail: http.StatusText(httpError.Code)}
+		switch httpError.Code {
+		case http.StatusBadRequest:
+			problem.Code = "bad_request"
+		case http.StatusNotFound:
+			problem.Code = "not_found"
+		case http.StatusMethodNotAllowed:
+			problem.Code = "method_not_allowed"
+		case http.StatusNotAcceptable:
+			problem.Code = "not_acceptable"
+		}
+		if message, ok := httpError.Message.(string); ok {
+			problem.Detail = message
+		}
+		return problem
+	}
+	// details of unexpected errors are not exposed to clients
+	return problemDetails{Status: http.StatusInternalServerError, Code: "internal_error", Detail: "internal server error"}
+}
+
+func fieldProblemsOf(err error) []fieldProblem {
+	problems := []fieldProblem{}
+	var errs *model.ValidationErrors
+	var validationError *model.ValidationError
+	if errors.As(err, &errs) {
+		for _, e := range errs.Errors {
+			problems = append(problems, fieldProblem{Field: e.Field, Reason: e.Reason})
+		}
+	} else if errors.As(err, &validationError) {
+		problems = append(problems, fieldProblem{Field: validationError.Field, Reason: validationError.Reason})
+	}
+	return problems
+}
+
+func errorResponse(c echo.Context, err error) error {
+	problem := newProblemDetails(err)
+	if problem.Status == http.StatusInternalServerError {
+		c.Logger().Error(err)
+	}
+	if problem.Status == http.StatusUnauthorized {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="ddd-bottom-up"`)
+	}
+	var lockedError *model.LockedError
+	if errors.As(err, &lockedError) {
+		retryAfter := int(math.Ceil(time.Until(lockedError.Until).Seconds()))
+		if retryAfter > 0 {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		}
+	}
+	problem.Type = "about:blank"
+	problem.Title = http.StatusText(problem.Status)
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+	return c.Blob(problem.Status, MIMEApplicationProblemJSON, body)
+}
+
+// errors returned from handlers and raised by echo itself (e.g. unknown routes)
+// are rendered as problem details as well
+func problemErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	if err := errorResponse(c, err); err != nil {
+		c.Logger().Error(err)
+	}
+}
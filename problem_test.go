@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestErrorResponse(t *testing.T) {
+	validationErrors := model.NewValidationErrors()
+	validationErrors.Add("name", model.NewValidationError("name", "is required"))
+	validationErrors.Add("id", model.NewValidationError("id", "is required"))
+
+	tests := []struct {
+		name  string
+		err   error
+		wants problemDetails
+	}{
+		{
+			name: "validation",
+			err:  validationErrors,
+			wants: problemDetails{
+				Type: "about:blank", Title: "Unprocessable Entity", Status: 422, Code: "validation_failed",
+				Detail: "invalid name: is required; invalid id: is required",
+				Errors: []fieldProblem{{Field: "name", Reason: "is required"}, {Field: "id", Reason: "is required"}},
+			},
+		},
+		{
+			name:  "not found",
+			err:   model.NewNotFoundError("user", "1"),
+			wants: problemDetails{Type: "about:blank", Title: "Not Found", Status: 404, Code: "not_found", Detail: "user not found"},
+		},
+		{
+			name:  "conflict",
+			err:   fmt.Errorf("register: %w", model.NewConflictError("user", "already exists")),
+			wants: problemDetails{Type: "about:blank", Title: "Conflict", Status: 409, Code: "conflict", Detail: "register: user already exists"},
+		},
+		{
+			name:  "stale",
+			err:   model.NewStaleError("user", "1", 3),
+			wants: problemDetails{Type: "about:blank", Title: "Conflict", Status: 409, Code: "stale", Detail: "user 1 was changed by someone else (version 3)"},
+		},
+		{
+			name:  "precondition failed",
+			err:   model.NewPreconditionFailedError("user", "1", 2, 3),
+			wants: problemDetails{Type: "about:blank", Title: "Precondition Failed", Status: 412, Code: "precondition_failed", Detail: "user 1 is at version 3, not 2"},
+		},
+		{
+			name:  "capacity",
+			err:   model.NewCapacityError("circle", 30),
+			wants: problemDetails{Type: "about:blank", Title: "Conflict", Status: 409, Code: "capacity_exceeded", Detail: "circle is full (limit 30)"},
+		},
+		{
+			name:  "permission",
+			err:   model.NewPermissionError("delete post", "not the author"),
+			wants: problemDetails{Type: "about:blank", Title: "Forbidden", Status: 403, Code: "permission_denied", Detail: "cannot delete post: not the author"},
+		},
+		{
+			name:  "unauthenticated",
+			err:   model.NewAuthenticationError("invalid name or password"),
+			wants: problemDetails{Type: "about:blank", Title: "Unauthorized", Status: 401, Code: "unauthenticated", Detail: "authentication failed: invalid name or password"},
+		},
+		{
+			name:  "locked",
+			err:   model.NewLockedError("account", time.Date(2023, 1, 1, 0, 15, 0, 0, time.UTC)),
+			wants: problemDetails{Type: "about:blank", Title: "Locked", Status: 423, Code: "locked", Detail: "account is locked until 2023-01-01T00:15:00Z"},
+		},
+		{
+			name:  "payment declined",
+			err:   model.NewPaymentDeclinedError("insufficient funds"),
+			wants: problemDetails{Type: "about:blank", Title: "Payment Required", Status: 402, Code: "payment_declined", Detail: "payment declined: insufficient funds"},
+		},
+		{
+			name:  "malformed request",
+			err:   echo.NewHTTPError(http.StatusBadRequest, "invalid body"),
+			wants: problemDetails{Type: "about:blank", Title: "Bad Request", Status: 400, Code: "bad_request", Detail: "invalid body"},
+		},
+		{
+			name:  "unexpected",
+			err:   errors.New("disk is on fire"),
+			wants: problemDetails{Type: "about:blank", Title: "Internal Server Error", Status: 500, Code: "internal_error", Detail: "internal server error"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+			assert.Nil(t, errorResponse(c, tt.err))
+			assert.Equal(t, tt.wants.Status, rec.Code)
+			assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+
+			var got problemDetails
+			assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			assert.Equal(t, tt.wants, got)
+		})
+	}
+}
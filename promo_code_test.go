@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	inMemoryInfrastructure "uyutaka.com/ddd-bottom-up/InMemoryInfrastructure"
+	"uyutaka.com/ddd-bottom-up/application"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func setUpPromoCodeApplicationService() {
+	setUpSubscriptionApplicationService()
+	admin, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "2"})
+	admin.GrantAdmin()
+	_ = userApplicationService.UserRepository.Save(*admin)
+	adminApplicationService = application.NewAdminApplicationService(userApplicationService.UserRepository, userApplicationService.PromoCodeRepository, testUnitOfWork, inMemoryInfrastructure.NewWriterAuditLog(io.Discard))
+}
+
+func postForm(handler echo.HandlerFunc, actorId string, id string, form url.Values) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	token, _ := testJWT.Sign(model.UserId{V: actorId}, time.Now(), time.Now().Add(time.Hour))
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if len(id) > 0 {
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+	}
+	_ = authenticate(handler)(c)
+	return rec
+}
+
+func promoCodeForm(code string, trialDays string, maxRedemptions string, expiresAt string) url.Values {
+	return url.Values{"code": {code}, "plan": {"premium"}, "trial_days": {trialDays}, "max_redemptions": {maxRedemptions}, "expires_at": {expiresAt}}
+}
+
+func TestCreatePromoCode(t *testing.T) {
+	expiresAt := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	tests := []struct {
+		name       string
+		actorId    string
+		form       url.Values
+		wantStatus int
+	}{
+		{name: "by an admin", actorId: "2", form: promoCodeForm("welcome-2023", "14", "100", expiresAt), wantStatus: http.StatusCreated},
+		{name: "by a member", actorId: "1", form: promoCodeForm("welcome-2023", "14", "100", expiresAt), wantStatus: http.StatusForbidden},
+		{name: "invalid expiresAt", actorId: "2", form: promoCodeForm("welcome-2023", "14", "100", "tomorrow"), wantStatus: http.StatusUnprocessableEntity},
+		{name: "no trial days", actorId: "2", form: promoCodeForm("welcome-2023", "0", "100", expiresAt), wantStatus: http.StatusUnprocessableEntity},
+		{name: "invalid code", actorId: "2", form: promoCodeForm("a b", "14", "100", expiresAt), wantStatus: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpPromoCodeApplicationService()
+			rec := postForm(createPromoCode, tt.actorId, "", tt.form)
+			assert.Equal(t, tt.wantStatus, rec.Code, fmt.Sprintf("createPromoCode() = %v", rec.Body.String()))
+		})
+	}
+
+	t.Run("duplicated", func(t *testing.T) {
+		setUpPromoCodeApplicationService()
+		postForm(createPromoCode, "2", "", promoCodeForm("welcome-2023", "14", "100", expiresAt))
+		rec := postForm(createPromoCode, "2", "", promoCodeForm("WELCOME-2023", "7", "1", expiresAt))
+		assert.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	})
+}
+
+func TestRedeemPromoCode(t *testing.T) {
+	expiresAt := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	tests := []struct {
+		name       string
+		actorId    string
+		id         string
+		code       string
+		wantStatus int
+	}{
+		{name: "redeemed", actorId: "1", id: "1", code: "welcome-2023", wantStatus: http.StatusCreated},
+		{name: "for another user", actorId: "2", id: "1", code: "welcome-2023", wantStatus: http.StatusForbidden},
+		{name: "unknown code", actorId: "1", id: "1", code: "UNKNOWN", wantStatus: http.StatusNotFound},
+		{name: "already premium", actorId: "2", id: "2", code: "welcome-2023", wantStatus: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpPromoCodeApplicationService()
+			rec := postForm(createPromoCode, "2", "", promoCodeForm("welcome-2023", "14", "1", expiresAt))
+			assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+			rec = postForm(redeemPromoCode, tt.actorId, tt.id, url.Values{"code": {tt.code}})
+			assert.Equal(t, tt.wantStatus, rec.Code, fmt.Sprintf("redeemPromoCode() = %v", rec.Body.String()))
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+			var response model.SubscriptionResponseModel
+			assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.True(t, response.Trial)
+			assert.Equal(t, "premium", response.Plan)
+		})
+	}
+}
+
+func TestTrialReverts(t *testing.T) {
+	setUpPromoCodeApplicationService()
+	rec := postForm(createPromoCode, "2", "", promoCodeForm("welcome-2023", "14", "10", time.Now().Add(24*time.Hour).Format(time.RFC3339)))
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = postForm(redeemPromoCode, "1", "1", url.Values{"code": {"welcome-2023"}})
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	// a trial cannot be renewed, only replaced by a paid subscription
+	rec = postForm(renewSubscription, "1", "1", url.Values{"months": {"1"}})
+	assert.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+
+	ticks := make(chan time.Time, 1)
+	ticks <- time.Now().AddDate(0, 0, 15)
+	close(ticks)
+	expireSubscriptions(ticks)
+
+	user, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "1"})
+	assert.Equal(t, model.USER_TYPE_NORMAL, user.UType)
+	changes, _ := userApplicationService.PlanChangeRepository.FindByUserId(&model.UserId{V: "1"})
+	to := []string{}
+	for _, change := range changes {
+		to = append(to, change.To.V)
+	}
+	assert.Equal(t, []string{"premium", "normal"}, to)
+}
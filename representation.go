@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+	formatText = "text"
+
+	MIMETextCSVCharsetUTF8 = "text/csv; charset=UTF-8"
+)
+
+type (
+	// response models which can be rendered in every supported format
+	representation interface {
+		CSVHeader() []string
+		CSVRecords() [][]string
+		Text() string
+	}
+
+	mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+)
+
+// offered formats in the order of server preference
+var offeredFormats = []struct {
+	mediaType string
+	format    string
+}{
+	{mediaType: echo.MIMEApplicationJSON, format: formatJSON},
+	{mediaType: "text/csv", format: formatCSV},
+	{mediaType: echo.MIMETextPlain, format: formatText},
+}
+
+// negotiateFormat honours the ?format= override first and the Accept header next.
+// JSON is chosen when the client has no preference.
+func negotiateFormat(c echo.Context) (string, error) {
+	if format := c.QueryParam("format"); len(format) != 0 {
+		for _, offered := range offeredFormats {
+			if offered.format == format {
+				return format, nil
+			}
+		}
+		return "", echo.NewHTTPError(http.StatusBadRequest, "format must be json, csv or text")
+	}
+
+	accept := c.Request().Header.Get(echo.HeaderAccept)
+	if len(strings.TrimSpace(accept)) == 0 {
+		return formatJSON, nil
+	}
+
+	ranges := parseAccept(accept)
+	best := ""
+	bestQuality := 0.0
+	bestSpecificity := -1
+	for _, offered := range offeredFormats {
+		quality, specificity := matchMediaRanges(ranges, offered.mediaType)
+		if quality <= 0 {
+			continue
+		}
+		if quality > bestQuality || (quality == bestQuality && specificity > bestSpecificity) {
+			best, bestQuality, bestSpecificity = offered.format, quality, specificity
+		}
+	}
+	if len(best) == 0 {
+		return "", echo.NewHTTPError(http.StatusNotAcceptable, "supported media types are application/json, text/csv and text/plain")
+	}
+	return best, nil
+}
+
+func parseAccept(accept string) []mediaRange {
+	ranges := []mediaRange{}
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if len(mediaType) == 0 {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+	}
+	return ranges
+}
+
+// matchMediaRanges returns the quality of the most specific range matching mediaType
+func matchMediaRanges(ranges []mediaRange, mediaType string) (float64, int) {
+	quality := 0.0
+	specificity := -1
+	mainType := strings.SplitN(mediaType, "/", 2)[0]
+	for _, r := range ranges {
+		s := -1
+		switch r.mediaType {
+		case mediaType:
+			s = 2
+		case mainType + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		}
+		if s > specificity {
+			quality, specificity = r.quality, s
+		}
+	}
+	return quality, specificity
+}
+
+func render(c echo.Context, status int, response representation) error {
+	return renderMessage(c, status, response, response.Text())
+}
+
+// renderMessage serves message instead of the text representation of the response
+func renderMessage(c echo.Context, status int, response representation, message string) error {
+	format, err := negotiateFormat(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+
+	switch format {
+	case formatCSV:
+		body, err := encodeCSV(response)
+		if err != nil {
+			return errorResponse(c, err)
+		}
+		return c.Blob(status, MIMETextCSVCharsetUTF8, body)
+	case formatText:
+		return c.String(status, message)
+	}
+	return c.JSON(status, response)
+}
+
+func encodeCSV(response representation) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(response.CSVHeader()); err != nil {
+		return nil, err
+	}
+	if err := w.WriteAll(response.CSVRecords()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		accept     string
+		want       string
+		wantStatus int
+	}{
+		{name: "no preference", target: "/", want: formatJSON},
+		{name: "any", target: "/", accept: "*/*", want: formatJSON},
+		{name: "csv", target: "/", accept: "text/csv", want: formatCSV},
+		{name: "text", target: "/", accept: "text/plain", want: formatText},
+		{name: "explicit type beats wildcard", target: "/", accept: "*/*, text/plain", want: formatText},
+		{name: "quality", target: "/", accept: "application/json;q=0.5, text/csv;q=0.9", want: formatCSV},
+		{name: "type wildcard", target: "/", accept: "text/*", want: formatCSV},
+		{name: "excluded by zero quality", target: "/", accept: "text/*, text/csv;q=0", want: formatText},
+		{name: "format overrides accept", target: "/?format=text", accept: "application/json", want: formatText},
+		{name: "unknown format", target: "/?format=xml", wantStatus: http.StatusBadRequest},
+		{name: "not acceptable", target: "/", accept: "application/xml", wantStatus: http.StatusNotAcceptable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set(echo.HeaderAccept, tt.accept)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			got, err := negotiateFormat(c)
+			if tt.wantStatus != 0 {
+				var httpError *echo.HTTPError
+				assert.Equal(t, true, errors.As(err, &httpError), fmt.Sprintf("negotiateFormat() error = %v", err))
+				assert.Equal(t, tt.wantStatus, httpError.Code)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got, fmt.Sprintf("negotiateFormat() = %v, want %v", got, tt.want))
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	response := model.NewUserListResponseModel([]model.User{
+		{Id: model.UserId{V: "1"}, Name: model.UserName{V: "user1"}, UType: model.USER_TYPE_NORMAL},
+		{Id: model.UserId{V: "2"}, Name: model.UserName{V: "user, 2"}, UType: model.USER_TYPE_PREMIUM},
+	})
+	tests := []struct {
+		name            string
+		target          string
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "json",
+			target:          "/",
+			wantContentType: echo.MIMEApplicationJSONCharsetUTF8,
+			wantBody:        `{"users":[{"id":"1","name":"user1","type":"normal","emailVerified":false},{"id":"2","name":"user, 2","type":"premium","emailVerified":false}],"total":2}` + "\n",
+		},
+		{
+			name:            "csv",
+			target:          "/?format=csv",
+			wantContentType: MIMETextCSVCharsetUTF8,
+			wantBody:        "id,name,type,email,emailVerified\n1,user1,normal,,false\n2,\"user, 2\",premium,,false\n",
+		},
+		{
+			name:            "text",
+			target:          "/?format=text",
+			wantContentType: echo.MIMETextPlainCharsetUTF8,
+			wantBody:        "1 user1 normal\n2 user, 2 premium\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, tt.target, nil), rec)
+
+			assert.Nil(t, render(c, http.StatusOK, response))
+			assert.Equal(t, tt.wantContentType, rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, echo.HeaderAccept, rec.Header().Get(echo.HeaderVary))
+			assert.Equal(t, tt.wantBody, rec.Body.String())
+		})
+	}
+}
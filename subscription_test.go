@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	inMemoryInfrastructure "uyutaka.com/ddd-bottom-up/InMemoryInfrastructure"
+	"uyutaka.com/ddd-bottom-up/application"
+	"uyutaka.com/ddd-bottom-up/model"
+)
+
+var testPaymentGateway *inMemoryInfrastructure.FakePaymentGateway
+
+func setUpSubscriptionApplicationService() {
+	setUpAuthApplicationService()
+	invoiceRepository := inMemoryInfrastructure.NewSliceInvoiceRepository()
+	invoiceFactory := inMemoryInfrastructure.NewInvoiceFactory(invoiceRepository.Storage)
+	ledgerRepository := inMemoryInfrastructure.NewSliceLedgerRepository()
+	testUnitOfWork.Register(invoiceRepository.Storage, ledgerRepository.Storage)
+	testPaymentGateway = inMemoryInfrastructure.NewFakePaymentGateway()
+	auditLog := inMemoryInfrastructure.NewWriterAuditLog(io.Discard)
+	// shares the subscriptions with the user service, which starts trials
+	subscriptionApplicationService = application.NewSubscriptionApplicationService(userApplicationService.UserRepository, userApplicationService.SubscriptionRepository, userApplicationService.SubscriptionFactory, userApplicationService.PlanChangeRepository, &invoiceRepository, &invoiceFactory, &ledgerRepository, testPaymentGateway, testUnitOfWork, auditLog)
+	billingApplicationService = application.NewBillingApplicationService(userApplicationService.UserRepository, &invoiceRepository, &ledgerRepository, auditLog)
+}
+
+func TestExpireSubscriptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		autoRenew bool
+		wantType  model.UserType
+		wantTo    []string
+	}{
+		{name: "expired", autoRenew: false, wantType: model.USER_TYPE_NORMAL, wantTo: []string{"premium", "normal"}},
+		{name: "renewed", autoRenew: true, wantType: model.USER_TYPE_PREMIUM, wantTo: []string{"premium"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpSubscriptionApplicationService()
+			actor, _ := model.NewActor(model.UserId{V: "1"}, model.USER_ROLE_MEMBER)
+			_, err := subscriptionApplicationService.Start(application.SubscriptionStartCommand{Actor: actor, UserId: "1", Months: 1, AutoRenew: tt.autoRenew})
+			assert.Nil(t, err)
+
+			ticks := make(chan time.Time, 1)
+			ticks <- time.Now().AddDate(0, 2, 0)
+			close(ticks)
+			expireSubscriptions(ticks)
+
+			user, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "1"})
+			assert.Equal(t, tt.wantType, user.UType)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			token, _ := testJWT.Sign(model.UserId{V: "1"}, time.Now(), time.Now().Add(time.Hour))
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			assert.Nil(t, authenticate(getPlanHistory)(c))
+			assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			var response model.PlanChangeListResponseModel
+			assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			to := []string{}
+			for _, change := range response.Changes {
+				to = append(to, change.To)
+			}
+			assert.Equal(t, tt.wantTo, to)
+		})
+	}
+}
+
+func TestPlanHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		actorId    string
+		handler    echo.HandlerFunc
+		id         string
+		wantStatus int
+		wantTo     string
+	}{
+		{name: "upgrade by an admin", actorId: "2", handler: upgradeUser, id: "1", wantStatus: http.StatusOK, wantTo: "premium"},
+		{name: "downgrade a normal user", actorId: "2", handler: downgradeUser, id: "1", wantStatus: http.StatusConflict},
+		{name: "upgrade by a member", actorId: "1", handler: upgradeUser, id: "1", wantStatus: http.StatusForbidden},
+		{name: "admin's own account", actorId: "2", handler: downgradeUser, id: "2", wantStatus: http.StatusForbidden},
+		{name: "unknown user", actorId: "2", handler: upgradeUser, id: "99", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setUpAuthApplicationService()
+			admin, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "2"})
+			admin.GrantAdmin()
+			assert.Nil(t, userApplicationService.UserRepository.Save(*admin))
+			changed := []model.PlanChange{}
+			userApplicationService.OnPlanChanged(application.PlanChangedHandlerFunc(func(change model.PlanChange) error {
+				changed = append(changed, change)
+				return nil
+			}))
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			token, _ := testJWT.Sign(model.UserId{V: tt.actorId}, time.Now(), time.Now().Add(time.Hour))
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+
+			assert.Nil(t, authenticate(tt.handler)(c))
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				assert.Empty(t, changed)
+				return
+			}
+			var response model.PlanChangeResponseModel
+			assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, tt.wantTo, response.To)
+			assert.Equal(t, "upgraded by user "+tt.actorId, response.Reason)
+			assert.Equal(t, 1, len(changed), "handlers are told about the change")
+		})
+	}
+}
+
+func TestPlanHandlerFailureKeepsChange(t *testing.T) {
+	setUpAuthApplicationService()
+	admin, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "2"})
+	admin.GrantAdmin()
+	assert.Nil(t, userApplicationService.UserRepository.Save(*admin))
+	userApplicationService.OnPlanChanged(application.PlanChangedHandlerFunc(func(change model.PlanChange) error {
+		return errors.New("handler failed")
+	}))
+	told := []model.PlanChange{}
+	userApplicationService.OnPlanChanged(application.PlanChangedHandlerFunc(func(change model.PlanChange) error {
+		told = append(told, change)
+		return nil
+	}))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	token, _ := testJWT.Sign(model.UserId{V: "2"}, time.Now(), time.Now().Add(time.Hour))
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	assert.Nil(t, authenticate(upgradeUser)(c))
+	assert.Equal(t, http.StatusOK, rec.Code, "the upgrade is answered although a handler failed")
+
+	// handlers are told after the commit, so that they can not undo the change
+	user, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "1"})
+	assert.Equal(t, model.USER_TYPE_PREMIUM, user.UType, "the upgrade is kept")
+	changes, _ := userApplicationService.PlanChangeRepository.FindByUserId(&model.UserId{V: "1"})
+	assert.Equal(t, 1, len(changes), "the plan change is kept")
+	assert.Equal(t, changes, told, "the other handler is told as well")
+}
+
+func TestExpireDueAfterPlanHandlerFailure(t *testing.T) {
+	setUpSubscriptionApplicationService()
+	for _, id := range []string{"1", "2"} {
+		actor, _ := model.NewActor(model.UserId{V: id}, model.USER_ROLE_MEMBER)
+		_, err := subscriptionApplicationService.Start(application.SubscriptionStartCommand{Actor: actor, UserId: id, Months: 1})
+		assert.Nil(t, err)
+	}
+	subscriptionApplicationService.OnPlanChanged(application.PlanChangedHandlerFunc(func(change model.PlanChange) error {
+		return errors.New("handler failed")
+	}))
+
+	result, err := subscriptionApplicationService.ExpireDue(application.SubscriptionExpireCommand{Now: time.Now().AddDate(0, 2, 0)})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result.Downgraded), "a failing handler stopped the job")
+}
+
+func TestDowngradeStopsRenewal(t *testing.T) {
+	setUpSubscriptionApplicationService()
+	admin, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "2"})
+	admin.GrantAdmin()
+	assert.Nil(t, userApplicationService.UserRepository.Save(*admin))
+	actor, _ := model.NewActor(model.UserId{V: "1"}, model.USER_ROLE_MEMBER)
+	_, err := subscriptionApplicationService.Start(application.SubscriptionStartCommand{Actor: actor, UserId: "1", Months: 1, AutoRenew: true})
+	assert.Nil(t, err)
+
+	adminActor, _ := model.NewActor(model.UserId{V: "2"}, model.USER_ROLE_ADMIN)
+	_, err = userApplicationService.Downgrade(application.UserDowngradeCommand{Actor: adminActor, Id: "1"})
+	assert.Nil(t, err)
+	subscription, _ := subscriptionApplicationService.SubscriptionRepository.FindByUserId(&model.UserId{V: "1"})
+	assert.True(t, subscription.IsCancelled(), "the subscription is not cancelled by the downgrade")
+
+	result, err := subscriptionApplicationService.ExpireDue(application.SubscriptionExpireCommand{Now: time.Now().AddDate(0, 2, 0)})
+	assert.Nil(t, err)
+	assert.Empty(t, result.Renewed, "the subscription was renewed after the downgrade")
+	user, _ := userApplicationService.UserRepository.FindById(&model.UserId{V: "1"})
+	assert.Equal(t, model.USER_TYPE_NORMAL, user.UType)
+}
+
+type failingLedgerRepository struct {
+	model.ILedgerRepository
+	userId model.UserId
+}
+
+func (r failingLedgerRepository) Save(entry model.LedgerEntry) error {
+	if entry.UserId == r.userId {
+		return errors.New("disk full")
+	}
+	return r.ILedgerRepository.Save(entry)
+}
+
+func TestExpireDueAfterFailure(t *testing.T) {
+	setUpSubscriptionApplicationService()
+	for _, id := range []string{"1", "2"} {
+		actor, _ := model.NewActor(model.UserId{V: id}, model.USER_ROLE_MEMBER)
+		_, err := subscriptionApplicationService.Start(application.SubscriptionStartCommand{Actor: actor, UserId: id, Months: 1, AutoRenew: true})
+		assert.Nil(t, err)
+	}
+	subscriptionApplicationService.LedgerRepository = failingLedgerRepository{subscriptionApplicationService.LedgerRepository, model.UserId{V: "1"}}
+
+	result, err := subscriptionApplicationService.ExpireDue(application.SubscriptionExpireCommand{Now: time.Now().AddDate(0, 1, 1)})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, len(result.Renewed), "a failing subscription stopped the job")
+	assert.Equal(t, model.UserId{V: "2"}, result.Renewed[0].UserId)
+	assert.True(t, testPaymentGateway.Refunded("pay_3"), "the payment of the failed renewal was not refunded")
+	assert.False(t, testPaymentGateway.Refunded("pay_4"))
+	subscription, _ := subscriptionApplicationService.SubscriptionRepository.FindByUserId(&model.UserId{V: "1"})
+	assert.True(t, subscription.IsDue(time.Now().AddDate(0, 1, 1)), "the failed renewal was kept")
+}